@@ -34,7 +34,9 @@ func initCleanup() {
 	ctx.Actions[types.CleanupAction] = cleanupAction{retentionDays}
 }
 
-func (a cleanupAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) {
+func (a cleanupAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	report := types.NewActionReport(types.CleanupAction)
+	reportLock := sync.Mutex{}
 	wg := sync.WaitGroup{}
 	wg.Add(len(ctx.CloudProviders))
 	for t, p := range ctx.CloudProviders {
@@ -42,35 +44,41 @@ func (a cleanupAction) Execute(op types.OpType, filters []types.FilterType, item
 			defer wg.Done()
 
 			var cloudItems []*types.CloudItem
+			var reportItems []types.CloudItem
 			for _, item := range items {
 				if item.GetCloudType() == cType {
 					i := item
 					cloudItems = append(cloudItems, &i)
+					reportItems = append(reportItems, item)
 				}
 			}
+			defer recoverFailedReport(types.CleanupAction, "CLEANUP", reportItems, func(cloudReport *types.ActionReport) {
+				reportLock.Lock()
+				report.Merge(cloudReport)
+				reportLock.Unlock()
+			})
 
 			if len(cloudItems) > 0 {
 				log.Infof("[CLEANUP] Cleaning up %d items on %s: %s", len(cloudItems), cType, items)
-				var errors []error
+				var cloudReport *types.ActionReport
 
 				item := *cloudItems[0]
 				switch t := item.GetItem().(type) {
 				case types.Storage:
-					errors = a.cleanupStorages(provider, cloudItems)
+					cloudReport = newReport(types.CleanupAction, reportItems, a.cleanupStorages(provider, cloudItems))
 				default:
-					panic(fmt.Sprintf("[CLEANUP] Operation on type %T is not allowed", t))
+					cloudReport = newFailedReport(types.CleanupAction, reportItems, fmt.Errorf("operation on type %T is not allowed", t))
 				}
+				logFailures("CLEANUP", cloudReport)
 
-				if len(errors) != 0 {
-					for _, err := range errors {
-						log.Errorf("[CLEANUP] Failed to clean up %ss on %s, err: %s", item.GetType(), cType, err.Error())
-					}
-					panic(fmt.Sprintf("[CLEANUP] Failed to clean up %ss on %s", item.GetType(), cType))
-				}
+				reportLock.Lock()
+				report.Merge(cloudReport)
+				reportLock.Unlock()
 			}
 		}(t, p())
 	}
 	wg.Wait()
+	return report
 }
 
 func (a cleanupAction) cleanupStorages(provider types.CloudProvider, items []*types.CloudItem) []error {
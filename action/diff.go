@@ -0,0 +1,127 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// the characters of the account names that are not safe in a file name
+var unsafeSnapshotNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func init() {
+	ctx.Actions[types.DiffAction] = diffAction{snapshotDir: os.Getenv("DIFF_SNAPSHOT_DIR")}
+}
+
+// diffAction keeps a snapshot of the items per operation, filters and cloud accounts in the format of the json action. The first
+// execution only creates the snapshot, the later ones print the items that were added, removed or changed since then and send them
+// to the dispatchers. The snapshot is not updated on dry run.
+type diffAction struct {
+	snapshotDir string
+}
+
+func (a diffAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	return a.ExecuteOnClouds(utils.GetCloudTypes(items), op, filters, items)
+}
+
+// ExecuteOnClouds compares the items with the snapshot of the same operation and filters executed on the same cloud accounts
+func (a diffAction) ExecuteOnClouds(clouds []types.CloudType, op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	if len(a.snapshotDir) == 0 {
+		panic("[DIFF] DIFF_SNAPSHOT_DIR environment variable is missing")
+	}
+	accounts := map[types.CloudType]string{}
+	for _, cloud := range clouds {
+		if provider, ok := ctx.CloudProviders[cloud]; ok {
+			accounts[cloud] = provider().GetAccountName()
+		}
+	}
+	location := a.getSnapshotLocation(op, filters, accounts, clouds)
+	raw, err := os.ReadFile(location)
+	switch {
+	case os.IsNotExist(err):
+		log.Infof("[DIFF] No previous snapshot found at %s, the current %d items are the baseline", location, len(items))
+	case err != nil:
+		panic(fmt.Sprintf("[DIFF] Failed to read snapshot %s, err: %s", location, err))
+	default:
+		previous, err := types.UnmarshalItems(raw)
+		if err != nil {
+			panic(fmt.Sprintf("[DIFF] Failed to parse snapshot %s, err: %s", location, err))
+		}
+		diff := types.NewDiff(previous, items)
+		log.Infof("[DIFF] Changes of operation %s and filters %s since the previous snapshot, added: %d, removed: %d, changed: %d",
+			op, utils.GetFilterNames(filters), len(diff.Added), len(diff.Removed), len(diff.Changed))
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			panic("[DIFF] Failed to marshal diff, err: " + err.Error())
+		}
+		fmt.Println(string(out))
+		if !diff.IsEmpty() && !dispatchDiff(op, filters, diff) {
+			log.Warnf("[DIFF] Snapshot %s is not updated, so the same changes are sent again next time", location)
+			return nil
+		}
+	}
+	if ctx.DryRun {
+		log.Infof("[DIFF] Snapshot %s is not updated on dry run, so the same changes are sent again by the next execution", location)
+		return nil
+	}
+	if err := writeSnapshot(location, items); err != nil {
+		panic(fmt.Sprintf("[DIFF] Failed to write snapshot %s, err: %s", location, err))
+	}
+	return nil
+}
+
+// The snapshots of the same operation and filters are kept apart per cloud accounts, e.g. getInstances-ownerless-AWS_123_GCP_project.json
+func (a diffAction) getSnapshotLocation(op types.OpType, filters []types.FilterType, accounts map[types.CloudType]string, clouds []types.CloudType) string {
+	var names []string
+	for _, cloud := range clouds {
+		names = append(names, unsafeSnapshotNameChars.ReplaceAllString(fmt.Sprintf("%s_%s", cloud, accounts[cloud]), "-"))
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		names = append(names, "noCloud")
+	}
+	return filepath.Join(a.snapshotDir, fmt.Sprintf("%s-%s-%s.json", op, utils.GetFilterNames(filters), strings.Join(names, "_")))
+}
+
+func dispatchDiff(op types.OpType, filters []types.FilterType, diff *types.Diff) bool {
+	succeeded := true
+	for name, d := range ctx.Dispatchers {
+		if dispatcher, ok := d.(types.DiffDispatcher); ok {
+			if err := dispatcher.SendDiff(op, filters, diff); err != nil {
+				log.Errorf("[%s] Failed to send diff, err: %s", name, err.Error())
+				succeeded = false
+			}
+		}
+	}
+	return succeeded
+}
+
+// The snapshot is replaced atomically, so an interrupted execution does not leave a partial snapshot behind
+func writeSnapshot(location string, items []types.CloudItem) error {
+	out, err := types.MarshalItems(items)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(location), filepath.Base(location)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(out); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), location)
+}
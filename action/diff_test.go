@@ -0,0 +1,182 @@
+package action
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type mockDiffDispatcher struct {
+	mockDispatcher
+	diffs []*types.Diff
+	err   error
+}
+
+func (d *mockDiffDispatcher) SendDiff(op types.OpType, filters []types.FilterType, diff *types.Diff) error {
+	d.diffs = append(d.diffs, diff)
+	return d.err
+}
+
+type diffSuite struct {
+	suite.Suite
+	dispatchers map[string]types.Dispatcher
+	providers   map[types.CloudType]func() types.CloudProvider
+	dispatcher  *mockDiffDispatcher
+	action      diffAction
+}
+
+func (s *diffSuite) SetupSuite() {
+	s.dispatchers = ctx.Dispatchers
+	s.providers = ctx.CloudProviders
+}
+
+func (s *diffSuite) SetupTest() {
+	s.dispatcher = &mockDiffDispatcher{}
+	ctx.Dispatchers = map[string]types.Dispatcher{"mock": s.dispatcher}
+	ctx.CloudProviders = map[types.CloudType]func() types.CloudProvider{
+		types.AWS: func() types.CloudProvider {
+			return &mockProvider{}
+		}}
+	s.action = diffAction{snapshotDir: s.T().TempDir()}
+}
+
+func (s *diffSuite) TearDownSuite() {
+	ctx.Dispatchers = s.dispatchers
+	ctx.CloudProviders = s.providers
+}
+
+func (s *diffSuite) execute(items ...types.CloudItem) {
+	s.action.ExecuteOnClouds([]types.CloudType{types.AWS}, types.Instances, []types.FilterType{types.OwnerlessFilter}, items)
+}
+
+func (s *diffSuite) getSnapshotLocation() string {
+	return s.action.getSnapshotLocation(types.Instances, []types.FilterType{types.OwnerlessFilter}, map[types.CloudType]string{types.AWS: "mock"},
+		[]types.CloudType{types.AWS})
+}
+
+func (s *diffSuite) TestFirstExecutionCreatesBaseline() {
+	s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS, State: types.Running})
+
+	s.Empty(s.dispatcher.diffs)
+	_, err := os.Stat(s.getSnapshotLocation())
+	s.Nil(err)
+}
+
+func (s *diffSuite) TestFirstExecutionWithoutItemsCreatesBaseline() {
+	s.execute()
+	s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+
+	s.Equal(1, len(s.dispatcher.diffs))
+	s.Equal("i-1", s.dispatcher.diffs[0].Added[0].GetID())
+}
+
+func (s *diffSuite) TestSnapshotLocationPerCloudAccount() {
+	accounts := map[types.CloudType]string{types.AWS: "123", types.GCP: "my project/1"}
+
+	s.Equal(filepath.Join(s.action.snapshotDir, "getInstances-ownerless-AWS_123.json"),
+		s.action.getSnapshotLocation(types.Instances, []types.FilterType{types.OwnerlessFilter}, accounts, []types.CloudType{types.AWS}))
+	s.Equal(filepath.Join(s.action.snapshotDir, "getInstances-ownerless-AWS_123_GCP_my-project-1.json"),
+		s.action.getSnapshotLocation(types.Instances, []types.FilterType{types.OwnerlessFilter}, accounts, []types.CloudType{types.GCP, types.AWS}))
+}
+
+func (s *diffSuite) TestMissingSnapshotDir() {
+	s.action = diffAction{}
+
+	s.Panics(func() {
+		s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	})
+}
+
+func (s *diffSuite) TestDiff() {
+	s.execute(
+		&types.Instance{ID: "i-1", CloudType: types.AWS, State: types.Running},
+		&types.Instance{ID: "i-2", CloudType: types.AWS, State: types.Running, Owner: "alice"},
+		&types.Disk{ID: "i-3", CloudType: types.AWS, State: types.Unused},
+	)
+	s.execute(
+		&types.Instance{ID: "i-4", CloudType: types.AWS, State: types.Running},
+		&types.Instance{ID: "i-2", CloudType: types.AWS, State: types.Stopped, Owner: "bob"},
+		&types.Instance{ID: "i-3", CloudType: types.AWS, State: types.Running},
+		&types.Disk{ID: "i-3", CloudType: types.AWS, State: types.Unused},
+	)
+
+	s.Equal(1, len(s.dispatcher.diffs))
+	diff := s.dispatcher.diffs[0]
+	s.Equal([]string{"i-3", "i-4"}, []string{diff.Added[0].GetID(), diff.Added[1].GetID()})
+	s.Equal(1, len(diff.Removed))
+	s.Equal("i-1", diff.Removed[0].GetID())
+	s.Equal(1, len(diff.Changed))
+	s.Equal("i-2", diff.Changed[0].Item.GetID())
+	s.Equal([]types.FieldChange{
+		{Field: "State", Previous: "running", Current: "stopped"},
+		{Field: "Owner", Previous: "alice", Current: "bob"},
+	}, diff.Changed[0].Changes)
+
+	// the snapshot is updated, so nothing is sent if nothing has changed since then
+	s.execute(
+		&types.Instance{ID: "i-4", CloudType: types.AWS, State: types.Running},
+		&types.Instance{ID: "i-2", CloudType: types.AWS, State: types.Stopped, Owner: "bob"},
+		&types.Instance{ID: "i-3", CloudType: types.AWS, State: types.Running},
+		&types.Disk{ID: "i-3", CloudType: types.AWS, State: types.Unused},
+	)
+	s.Equal(1, len(s.dispatcher.diffs))
+}
+
+func (s *diffSuite) TestSnapshotIsKeptIfDispatchFails() {
+	s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	s.dispatcher.err = errors.New("failed")
+
+	s.execute(&types.Instance{ID: "i-2", CloudType: types.AWS})
+	s.dispatcher.err = nil
+	s.execute(&types.Instance{ID: "i-2", CloudType: types.AWS})
+
+	s.Equal(2, len(s.dispatcher.diffs))
+	s.Equal("i-2", s.dispatcher.diffs[1].Added[0].GetID())
+	s.Equal("i-1", s.dispatcher.diffs[1].Removed[0].GetID())
+}
+
+func (s *diffSuite) TestSnapshotIsKeptOnDryRun() {
+	s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+
+	s.execute(&types.Instance{ID: "i-2", CloudType: types.AWS})
+	ctx.DryRun = false
+	s.execute(&types.Instance{ID: "i-2", CloudType: types.AWS})
+
+	s.Equal(2, len(s.dispatcher.diffs))
+	s.Equal("i-2", s.dispatcher.diffs[1].Added[0].GetID())
+	s.Equal("i-1", s.dispatcher.diffs[1].Removed[0].GetID())
+}
+
+func (s *diffSuite) TestDryRunDoesNotCreateBaseline() {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+
+	s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+
+	_, err := os.Stat(s.getSnapshotLocation())
+	s.True(os.IsNotExist(err))
+}
+
+func (s *diffSuite) TestInvalidSnapshot() {
+	location := s.getSnapshotLocation()
+	s.Nil(os.WriteFile(location, []byte(`[{"Id": "i-1"}]`), 0600))
+
+	s.Panics(func() {
+		s.execute(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	})
+}
+
+func TestDiffSuite(t *testing.T) {
+	suite.Run(t, new(diffSuite))
+}
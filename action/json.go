@@ -1,7 +1,6 @@
 package action
 
 import (
-	"encoding/json"
 	"fmt"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
@@ -17,8 +16,14 @@ func init() {
 type jsonAction struct {
 }
 
-func (a jsonAction) Execute(op types.OpType, filter []types.FilterType, items []types.CloudItem) {
+func (a jsonAction) Execute(op types.OpType, filter []types.FilterType, items []types.CloudItem) *types.ActionReport {
 	log.Infof("[JSON] Number of items generated by operation %s and filters %s on accounts %s: %d", op.String(), filter, utils.GetCloudAccountNames(), len(items))
-	out, _ := json.MarshalIndent(items, "", "  ")
+	out, err := types.MarshalItems(items)
+	if err != nil {
+		log.Errorf("[JSON] Failed to marshal items, err: %s", err)
+		return nil
+	}
 	fmt.Println(string(out))
+	logCosts("JSON", items)
+	return nil
 }
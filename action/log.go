@@ -16,10 +16,12 @@ func init() {
 type logAction struct {
 }
 
-func (a logAction) Execute(op types.OpType, filter []types.FilterType, items []types.CloudItem) {
+func (a logAction) Execute(op types.OpType, filter []types.FilterType, items []types.CloudItem) *types.ActionReport {
 	log.Infof("[LOG] Number of items generated by operation %s and filters %s on accounts %s: %d", op.String(), filter, utils.GetCloudAccountNames(), len(items))
 	for _, item := range items {
 		out, _ := json.Marshal(item.GetItem())
 		log.Infof("[%s] %s", item.GetCloudType(), string(out))
 	}
+	logCosts("LOG", items)
+	return nil
 }
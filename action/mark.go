@@ -0,0 +1,180 @@
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// GCP label values can be at most 63 characters long
+const maxMarkReasonLength = 63
+
+func init() {
+	ctx.Actions[types.MarkAction] = new(markAction)
+	ctx.Actions[types.UnmarkAction] = new(unmarkAction)
+}
+
+type markAction struct {
+}
+
+type unmarkAction struct {
+}
+
+func (a markAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	return a.ExecuteOnClouds(utils.GetCloudTypes(items), op, filters, items)
+}
+
+// ExecuteOnClouds marks the items and removes the marks of the items of the clouds that do not match the filters anymore
+func (a markAction) ExecuteOnClouds(clouds []types.CloudType, op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	reason := getMarkReason(filters)
+	var toMark []types.CloudItem
+	for _, item := range items {
+		if !isMarkable(item) {
+			log.Debugf("[MARK] Ignoring cloud item: %s, because it's not a markable resource: %s", item.GetName(), item.GetType())
+		} else if _, ok := item.GetTags()[ctx.MarkedAtLabel]; ok {
+			log.Debugf("[MARK] %s is already marked: %s", item.GetType(), item.GetName())
+		} else {
+			toMark = append(toMark, item)
+		}
+	}
+
+	log.Infof("[MARK] Mark %d items with reason: %s", len(toMark), reason)
+	tags := types.Tags{
+		ctx.MarkedAtLabel:   strconv.FormatInt(time.Now().Unix(), 10),
+		ctx.MarkReasonLabel: reason,
+	}
+	report := updateTagsPerCloud(types.MarkAction, toMark, func(provider types.CloudProvider, cloudItems []types.CloudItem) []error {
+		return provider.AddTags(cloudItems, tags)
+	})
+	logFailures("MARK", report)
+
+	report.Merge(unmarkNotMatching(clouds, op, reason, items))
+	return report
+}
+
+func (a unmarkAction) Execute(_ types.OpType, _ []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	var toUnmark []types.CloudItem
+	for _, item := range items {
+		if _, ok := item.GetTags()[ctx.MarkedAtLabel]; ok && isMarkable(item) {
+			toUnmark = append(toUnmark, item)
+		}
+	}
+	log.Infof("[UNMARK] Remove the mark of %d items", len(toUnmark))
+	report := unmark(toUnmark)
+	logFailures("UNMARK", report)
+	return report
+}
+
+// The operation is executed again on the same clouds to find the items that were marked with the same reason, but do not match
+// the filters anymore
+func unmarkNotMatching(clouds []types.CloudType, op types.OpType, reason string, matching []types.CloudItem) *types.ActionReport {
+	if len(clouds) == 0 {
+		return nil
+	}
+
+	operation, ok := ctx.Operations[op]
+	if !ok {
+		log.Warnf("[MARK] Operation is not found: %s, marks are not removed", op)
+		return nil
+	}
+
+	matchingIDs := map[types.CloudType]map[string]bool{}
+	for _, item := range matching {
+		if _, ok := matchingIDs[item.GetCloudType()]; !ok {
+			matchingIDs[item.GetCloudType()] = map[string]bool{}
+		}
+		matchingIDs[item.GetCloudType()][item.GetID()] = true
+	}
+
+	var toUnmark []types.CloudItem
+	for _, item := range operation.Execute(clouds) {
+		if _, ok := item.GetTags()[ctx.MarkedAtLabel]; !ok || !isMarkable(item) {
+			continue
+		}
+		if item.GetTags()[ctx.MarkReasonLabel] != reason || matchingIDs[item.GetCloudType()][item.GetID()] {
+			continue
+		}
+		log.Infof("[MARK] %s does not match anymore, removing its mark: %s", item.GetType(), item.GetName())
+		toUnmark = append(toUnmark, item)
+	}
+	report := unmark(toUnmark)
+	logFailures("UNMARK", report)
+	return report
+}
+
+func unmark(items []types.CloudItem) *types.ActionReport {
+	return updateTagsPerCloud(types.UnmarkAction, items, func(provider types.CloudProvider, cloudItems []types.CloudItem) []error {
+		return provider.RemoveTags(cloudItems, []string{ctx.MarkedAtLabel, ctx.MarkReasonLabel})
+	})
+}
+
+func updateTagsPerCloud(action types.ActionType, items []types.CloudItem, update func(types.CloudProvider, []types.CloudItem) []error) *types.ActionReport {
+	itemsPerCloud := map[types.CloudType][]types.CloudItem{}
+	for _, item := range items {
+		itemsPerCloud[item.GetCloudType()] = append(itemsPerCloud[item.GetCloudType()], item)
+	}
+
+	report := types.NewActionReport(action)
+	reportLock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(len(itemsPerCloud))
+	for c, i := range itemsPerCloud {
+		go func(cloud types.CloudType, cloudItems []types.CloudItem) {
+			defer wg.Done()
+			defer recoverFailedReport(action, strings.ToUpper(string(action)), cloudItems, func(cloudReport *types.ActionReport) {
+				reportLock.Lock()
+				report.Merge(cloudReport)
+				reportLock.Unlock()
+			})
+
+			var cloudReport *types.ActionReport
+			if provider, ok := ctx.CloudProviders[cloud]; ok {
+				cloudReport = newReport(action, cloudItems, update(provider(), cloudItems))
+			} else {
+				cloudReport = newFailedReport(action, cloudItems, fmt.Errorf("cloud provider not found: %s", cloud))
+			}
+
+			reportLock.Lock()
+			report.Merge(cloudReport)
+			reportLock.Unlock()
+		}(c, i)
+	}
+	wg.Wait()
+	return report
+}
+
+func getMarkReason(filters []types.FilterType) string {
+	var names []string
+	for _, f := range filters {
+		if f != types.MarkedFilter {
+			names = append(names, f.String())
+		}
+	}
+	// the order of the filters given on the command line is not deterministic
+	sort.Strings(names)
+	reason := strings.Join(names, "_")
+	if len(reason) == 0 {
+		reason = "nofilter"
+	}
+	if len(reason) > maxMarkReasonLength {
+		reason = reason[:maxMarkReasonLength]
+	}
+	return reason
+}
+
+func isMarkable(item types.CloudItem) bool {
+	switch item.GetItem().(type) {
+	case types.Instance, types.Disk, types.Stack, types.Database:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/suite"
+)
+
+const markTestOperation = types.OpType("marktest")
+
+type markTestOp struct {
+	items  []types.CloudItem
+	clouds *[]types.CloudType
+}
+
+func (o markTestOp) Execute(clouds []types.CloudType) []types.CloudItem {
+	if o.clouds != nil {
+		*o.clouds = clouds
+	}
+	return o.items
+}
+
+type markSuite struct {
+	suite.Suite
+	providers    map[types.CloudType]func() types.CloudProvider
+	mockProvider *mockProvider
+}
+
+func (s *markSuite) SetupSuite() {
+	s.providers = ctx.CloudProviders
+}
+
+func (s *markSuite) SetupTest() {
+	s.mockProvider = &mockProvider{addedTags: map[string]types.Tags{}, removedTags: map[string][]string{}}
+	ctx.CloudProviders = map[types.CloudType]func() types.CloudProvider{
+		types.AWS: func() types.CloudProvider {
+			return s.mockProvider
+		}}
+	ctx.Operations[markTestOperation] = markTestOp{}
+}
+
+func (s *markSuite) TearDownSuite() {
+	ctx.CloudProviders = s.providers
+	delete(ctx.Operations, markTestOperation)
+}
+
+func (s *markSuite) TestMark() {
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", CloudType: types.AWS},
+		&types.Instance{ID: "i-2", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000"}},
+		&types.Access{Name: "key", CloudType: types.AWS},
+	}
+
+	report := markAction{}.Execute(markTestOperation, []types.FilterType{types.OwnerlessFilter, types.LongRunningFilter, types.MarkedFilter}, items)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Equal(1, len(s.mockProvider.addedTags))
+	s.Equal("longrunning_ownerless", s.mockProvider.addedTags["i-1"][ctx.MarkReasonLabel])
+	s.NotEmpty(s.mockProvider.addedTags["i-1"][ctx.MarkedAtLabel])
+	s.Empty(s.mockProvider.removedTags)
+}
+
+func (s *markSuite) TestMarkRecoversFromPanic() {
+	s.mockProvider.panics = true
+	items := []types.CloudItem{&types.Instance{ID: "i-1", CloudType: types.AWS}}
+
+	report := markAction{}.Execute(markTestOperation, []types.FilterType{types.OwnerlessFilter}, items)
+
+	s.Equal(1, report.Count(types.OutcomeFailed))
+	s.Equal("panic: provider failed", report.GetFailed()[0].Error)
+}
+
+func (s *markSuite) TestMarkRemovesNotMatching() {
+	ctx.Operations[markTestOperation] = markTestOp{items: []types.CloudItem{
+		&types.Instance{ID: "i-1", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000", ctx.MarkReasonLabel: "ownerless"}},
+		&types.Instance{ID: "i-2", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000", ctx.MarkReasonLabel: "ownerless"}},
+		&types.Instance{ID: "i-3", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000", ctx.MarkReasonLabel: "longrunning"}},
+		&types.Instance{ID: "i-4", CloudType: types.AWS},
+	}}
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000", ctx.MarkReasonLabel: "ownerless"}},
+	}
+
+	report := markAction{}.Execute(markTestOperation, []types.FilterType{types.OwnerlessFilter}, items)
+
+	s.Equal(1, len(s.mockProvider.removedTags))
+	s.Equal([]string{ctx.MarkedAtLabel, ctx.MarkReasonLabel}, s.mockProvider.removedTags["i-2"])
+	s.Equal(1, len(report.Results))
+	s.Equal(types.UnmarkAction, report.Results[0].Action)
+}
+
+func (s *markSuite) TestMarkRemovesNotMatchingOfJobClouds() {
+	var executedClouds []types.CloudType
+	ctx.Operations[markTestOperation] = markTestOp{clouds: &executedClouds}
+	ctx.CloudProviders[types.GCP] = func() types.CloudProvider {
+		return s.mockProvider
+	}
+
+	markAction{}.ExecuteOnClouds([]types.CloudType{types.AWS}, markTestOperation, []types.FilterType{types.OwnerlessFilter}, nil)
+
+	s.Equal([]types.CloudType{types.AWS}, executedClouds)
+}
+
+func (s *markSuite) TestMarkWithoutItemsDoesNotExecuteOperation() {
+	var executedClouds []types.CloudType
+	ctx.Operations[markTestOperation] = markTestOp{clouds: &executedClouds}
+
+	markAction{}.Execute(markTestOperation, []types.FilterType{types.OwnerlessFilter}, nil)
+
+	s.Nil(executedClouds)
+}
+
+func (s *markSuite) TestUnmark() {
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", CloudType: types.AWS, Tags: types.Tags{ctx.MarkedAtLabel: "1500000000"}},
+		&types.Instance{ID: "i-2", CloudType: types.AWS},
+	}
+
+	report := unmarkAction{}.Execute(markTestOperation, []types.FilterType{}, items)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Contains(s.mockProvider.removedTags, "i-1")
+}
+
+func TestMarkSuite(t *testing.T) {
+	suite.Run(t, new(markSuite))
+}
@@ -0,0 +1,23 @@
+package action
+
+import (
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/metrics"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	ctx.Actions[types.MetricsAction] = new(metricsAction)
+}
+
+type metricsAction struct {
+}
+
+func (a metricsAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	log.Infof("[METRICS] Number of items generated by operation %s and filters %s: %d", op, filters, len(items))
+	metrics.SetItems(op, filters, items, utils.GetCloudAccountNames())
+	metrics.WriteTextfile()
+	return nil
+}
@@ -1,6 +1,7 @@
 package action
 
 import (
+	"fmt"
 	"sync"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
@@ -15,10 +16,12 @@ func init() {
 type notificationAction struct {
 }
 
-func (a notificationAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) {
+func (a notificationAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
 	log.Infof("[NOTIFICATION] Sending %d items for %d dispatchers", len(items), len(ctx.Dispatchers))
 	log.Debugf("[NOTIFICATION] Sending notifications (%d) for items: [%s]", len(items), items)
+	var errs []error
 	if len(items) > 0 {
+		errLock := sync.Mutex{}
 		wg := sync.WaitGroup{}
 		wg.Add(len(ctx.Dispatchers))
 		for n, d := range ctx.Dispatchers {
@@ -28,9 +31,13 @@ func (a notificationAction) Execute(op types.OpType, filters []types.FilterType,
 
 				if err := dispatcher.Send(op, filters, items); err != nil {
 					log.Errorf("[%s] Failed to send message, err: %s", name, err.Error())
+					errLock.Lock()
+					errs = append(errs, fmt.Errorf("%s: %s", name, err.Error()))
+					errLock.Unlock()
 				}
 			}(n, d)
 		}
 		wg.Wait()
 	}
+	return newReport(types.NotificationAction, items, errs)
 }
@@ -0,0 +1,98 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/pricing"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// newReport creates the result of the action per cloud item from the errors returned by the cloud provider.
+// Errors that cannot be attributed to a resource fail every item, as their success cannot be confirmed.
+func newReport(action types.ActionType, items []types.CloudItem, errs []error) *types.ActionReport {
+	var resourceErrors []types.ResourceError
+	var generalErrors []string
+	for _, err := range errs {
+		var resourceErr types.ResourceError
+		if errors.As(err, &resourceErr) {
+			resourceErrors = append(resourceErrors, resourceErr)
+		} else {
+			generalErrors = append(generalErrors, err.Error())
+		}
+	}
+
+	report := types.NewActionReport(action)
+	for _, item := range items {
+		var itemErrors []string
+		for _, resourceErr := range resourceErrors {
+			if resourceErr.Affects(item) {
+				itemErrors = append(itemErrors, resourceErr.Err.Error())
+			}
+		}
+		if len(itemErrors) == 0 {
+			itemErrors = generalErrors
+		}
+
+		if len(itemErrors) != 0 {
+			report.Add(item, types.OutcomeFailed, errors.New(strings.Join(itemErrors, "; ")))
+		} else if ctx.DryRun {
+			report.Add(item, types.OutcomeDryRun, nil)
+		} else {
+			report.Add(item, types.OutcomeSucceeded, nil)
+		}
+		if cost := types.GetCost(item); cost != nil && isSaving(action) {
+			report.Results[len(report.Results)-1].MonthlySavings = cost.GetMonthly()
+		}
+	}
+	return report
+}
+
+// Stopped and terminated resources do not generate costs anymore
+func isSaving(action types.ActionType) bool {
+	return action == types.StopAction || action == types.TerminationAction
+}
+
+// newFailedReport creates a report where the action failed on all the cloud items with the same error
+func newFailedReport(action types.ActionType, items []types.CloudItem, err error) *types.ActionReport {
+	report := types.NewActionReport(action)
+	for _, item := range items {
+		report.Add(item, types.OutcomeFailed, err)
+	}
+	return report
+}
+
+// recoverFailedReport must be deferred in the goroutines calling the cloud providers. A panic of a goroutine cannot be recovered by
+// the caller of the action, so it is turned into a report where the action failed on all the cloud items of the goroutine.
+func recoverFailedReport(action types.ActionType, prefix string, items []types.CloudItem, send func(*types.ActionReport)) {
+	if r := recover(); r != nil {
+		log.Errorf("[%s] Failed to %s %d items, cloud provider panicked: %v", prefix, action, len(items), r)
+		send(newFailedReport(action, items, fmt.Errorf("panic: %v", r)))
+	}
+}
+
+func logFailures(prefix string, report *types.ActionReport) {
+	for _, result := range report.GetFailed() {
+		log.Errorf("[%s] Failed to %s %s %s:%s on %s, err: %s", prefix, result.Action, result.ItemType, result.ID, result.Name, result.CloudType, result.Error)
+	}
+}
+
+func logCosts(prefix string, items []types.CloudItem) {
+	costs := pricing.SumPerOwner(items)
+	if len(costs) == 0 {
+		return
+	}
+	owners := make([]string, 0, len(costs))
+	for owner := range costs {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		log.Infof("[%s] Estimated cost of owner %s: %s", prefix, owner, costs[owner])
+	}
+	log.Infof("[%s] Estimated total cost: %s", prefix, pricing.Sum(items))
+}
@@ -0,0 +1,73 @@
+package action
+
+import (
+	"errors"
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReportWithoutErrors(t *testing.T) {
+	items := []types.CloudItem{&types.Instance{ID: "i-1", Region: "eu-west-1"}, &types.Instance{ID: "i-2", Region: "eu-west-1"}}
+
+	report := newReport(types.StopAction, items, nil)
+
+	assert.Equal(t, 2, report.Count(types.OutcomeSucceeded))
+	assert.False(t, report.HasFailure())
+}
+
+func TestNewReportDryRun(t *testing.T) {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+	items := []types.CloudItem{&types.Instance{ID: "i-1", Region: "eu-west-1"}}
+
+	report := newReport(types.StopAction, items, nil)
+
+	assert.Equal(t, 1, report.Count(types.OutcomeDryRun))
+}
+
+func TestNewReportResourceErrors(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", Region: "eu-west-1"},
+		&types.Instance{ID: "i-2", Region: "eu-west-1"},
+		&types.Instance{ID: "i-3", Region: "us-east-1"},
+	}
+	errs := []error{types.NewResourceError("i-2", "eu-west-1", errors.New("denied")), types.NewResourceError("", "us-east-1", errors.New("throttled"))}
+
+	report := newReport(types.TerminationAction, items, errs)
+
+	assert.Equal(t, types.OutcomeSucceeded, report.Results[0].Outcome)
+	assert.Equal(t, types.OutcomeFailed, report.Results[1].Outcome)
+	assert.Equal(t, "denied", report.Results[1].Error)
+	assert.Equal(t, types.OutcomeFailed, report.Results[2].Outcome)
+	assert.Equal(t, "throttled", report.Results[2].Error)
+	assert.Equal(t, types.TerminationAction, report.Results[2].Action)
+}
+
+func TestNewReportGeneralErrors(t *testing.T) {
+	items := []types.CloudItem{&types.Disk{ID: "d-1"}, &types.Disk{ID: "d-2"}}
+
+	report := newReport(types.TerminationAction, items, []error{errors.New("not supported")})
+
+	assert.Equal(t, 2, report.Count(types.OutcomeFailed))
+	assert.Equal(t, "not supported", report.Results[1].Error)
+}
+
+func TestNewReportMonthlySavings(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", Region: "eu-west-1", Cost: &types.Cost{Hourly: 0.1}},
+		&types.Instance{ID: "i-2", Region: "eu-west-1", Cost: &types.Cost{Hourly: 0.2}},
+		&types.Instance{ID: "i-3", Region: "eu-west-1"},
+	}
+	errs := []error{types.NewResourceError("i-2", "eu-west-1", errors.New("denied"))}
+
+	report := newReport(types.StopAction, items, errs)
+
+	assert.InDelta(t, 73, report.Results[0].MonthlySavings, 0.001)
+	assert.InDelta(t, 73, report.GetMonthlySavings(), 0.001)
+	assert.Equal(t, 0.0, newReport(types.NotificationAction, items, nil).GetMonthlySavings())
+}
@@ -17,7 +17,7 @@ func init() {
 type stopAction struct {
 }
 
-func (s stopAction) Execute(_ types.OpType, _ []types.FilterType, items []types.CloudItem) {
+func (s stopAction) Execute(_ types.OpType, _ []types.FilterType, items []types.CloudItem) *types.ActionReport {
 	instancesPerCloud := map[types.CloudType][]*types.Instance{}
 	databasesPerCloud := map[types.CloudType][]*types.Database{}
 	for _, item := range items {
@@ -31,45 +31,65 @@ func (s stopAction) Execute(_ types.OpType, _ []types.FilterType, items []types.
 		}
 	}
 
+	reports := make(chan *types.ActionReport)
 	wg := sync.WaitGroup{}
 	if len(instancesPerCloud) > 0 {
 		wg.Add(len(instancesPerCloud))
-		stopInstances(instancesPerCloud, &wg)
+		stopInstances(instancesPerCloud, &wg, reports)
 	}
 	if len(databasesPerCloud) > 0 {
 		wg.Add(len(databasesPerCloud))
-		stopDatabases(databasesPerCloud, &wg)
+		stopDatabases(databasesPerCloud, &wg, reports)
 	}
 
-	wg.Wait()
+	go func() {
+		wg.Wait()
+		close(reports)
+	}()
+
+	report := types.NewActionReport(types.StopAction)
+	for r := range reports {
+		report.Merge(r)
+	}
+	return report
 }
 
-func stopInstances(instancesPerCloud map[types.CloudType][]*types.Instance, wg *sync.WaitGroup) {
+func stopInstances(instancesPerCloud map[types.CloudType][]*types.Instance, wg *sync.WaitGroup, reports chan *types.ActionReport) {
 	for cloud, instances := range instancesPerCloud {
 		go func(cloud types.CloudType, instances []*types.Instance) {
 			defer wg.Done()
-			log.Infof("[STOP] Stop %d instances on %s: %s", len(instances), cloud, strings.Join(getInstanceNames(instances), ","))
-			if errors := ctx.CloudProviders[cloud]().StopInstances(types.NewInstanceContainer(instances)); len(errors) != 0 {
-				for _, err := range errors {
-					log.Errorf("[STOP] Failed to stop instances on cloud: %s, err: %s", cloud, err.Error())
-				}
-				panic(fmt.Sprintf("[STOP] Failed to stop instances on cloud: %s", cloud))
+			var items []types.CloudItem
+			for _, instance := range instances {
+				items = append(items, instance)
 			}
+			defer recoverFailedReport(types.StopAction, "STOP", items, func(report *types.ActionReport) {
+				reports <- report
+			})
+			log.Infof("[STOP] Stop %d instances on %s: %s", len(instances), cloud, strings.Join(getInstanceNames(instances), ","))
+			errors := ctx.CloudProviders[cloud]().StopInstances(types.NewInstanceContainer(instances))
+			report := newReport(types.StopAction, items, errors)
+			logFailures("STOP", report)
+			reports <- report
 		}(cloud, instances)
 	}
 }
 
-func stopDatabases(databasesPerCloud map[types.CloudType][]*types.Database, wg *sync.WaitGroup) {
+func stopDatabases(databasesPerCloud map[types.CloudType][]*types.Database, wg *sync.WaitGroup, reports chan *types.ActionReport) {
 	for cloud, databases := range databasesPerCloud {
 		go func(cloud types.CloudType, databases []*types.Database) {
 			defer wg.Done()
-			log.Infof("[STOP] Stop %d databases on %s: %s", len(databases), cloud, strings.Join(getDatabaseNames(databases), ","))
-			if errors := ctx.CloudProviders[cloud]().StopDatabases(types.NewDatabaseContainer(databases)); len(errors) != 0 {
-				for _, err := range errors {
-					log.Errorf("[STOP] Failed to stop databases on cloud: %s, err: %s", cloud, err.Error())
-				}
-				panic(fmt.Sprintf("[STOP] Failed to stop databases on cloud: %s", cloud))
+			var items []types.CloudItem
+			for _, database := range databases {
+				items = append(items, database)
 			}
+			defer recoverFailedReport(types.StopAction, "STOP", items, func(report *types.ActionReport) {
+				reports <- report
+			})
+			log.Infof("[STOP] Stop %d databases on %s: %s", len(databases), cloud, strings.Join(getDatabaseNames(databases), ","))
+			errors := ctx.CloudProviders[cloud]().StopDatabases(types.NewDatabaseContainer(databases))
+			report := newReport(types.StopAction, items, errors)
+			logFailures("STOP", report)
+			reports <- report
 		}(cloud, databases)
 	}
 }
@@ -17,7 +17,9 @@ func init() {
 type terminationAction struct {
 }
 
-func (a terminationAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) {
+func (a terminationAction) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	report := types.NewActionReport(types.TerminationAction)
+	reportLock := sync.Mutex{}
 	wg := sync.WaitGroup{}
 	wg.Add(len(ctx.CloudProviders))
 	for t, p := range ctx.CloudProviders {
@@ -25,16 +27,24 @@ func (a terminationAction) Execute(op types.OpType, filters []types.FilterType,
 			defer wg.Done()
 
 			var cloudItems []*types.CloudItem
+			var reportItems []types.CloudItem
 			for _, item := range items {
 				if item.GetCloudType() == cType {
 					i := item
 					cloudItems = append(cloudItems, &i)
+					reportItems = append(reportItems, item)
 				}
 			}
+			defer recoverFailedReport(types.TerminationAction, "TERMINATION", reportItems, func(cloudReport *types.ActionReport) {
+				reportLock.Lock()
+				report.Merge(cloudReport)
+				reportLock.Unlock()
+			})
 
 			if len(cloudItems) > 0 {
 				log.Infof("[TERMINATION] Terminating %d items on %s: %s", len(cloudItems), cType, items)
 				var errors []error
+				var cloudReport *types.ActionReport
 
 				item := *cloudItems[0]
 				switch t := item.GetItem().(type) {
@@ -49,19 +59,22 @@ func (a terminationAction) Execute(op types.OpType, filters []types.FilterType,
 				case types.Alert:
 					errors = deleteAlerts(provider, cloudItems)
 				default:
-					panic(fmt.Sprintf("[TERMINATION] Operation on type %T is not allowed", t))
+					cloudReport = newFailedReport(types.TerminationAction, reportItems, fmt.Errorf("operation on type %T is not allowed", t))
 				}
 
-				if len(errors) != 0 {
-					for _, err := range errors {
-						log.Errorf("[TERMINATION] Failed to terminate %ss on %s, err: %s", item.GetType(), cType, err.Error())
-					}
-					panic(fmt.Sprintf("[TERMINATION] Failed to terminate %ss on %s", item.GetType(), cType))
+				if cloudReport == nil {
+					cloudReport = newReport(types.TerminationAction, reportItems, errors)
 				}
+				logFailures("TERMINATION", cloudReport)
+
+				reportLock.Lock()
+				report.Merge(cloudReport)
+				reportLock.Unlock()
 			}
 		}(t, p())
 	}
 	wg.Wait()
+	return report
 }
 
 func terminateInstances(provider types.CloudProvider, items []*types.CloudItem) []error {
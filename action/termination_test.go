@@ -1,7 +1,9 @@
 package action
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
 	"github.com/hortonworks/cloud-haunter/types"
@@ -9,7 +11,11 @@ import (
 )
 
 type mockProvider struct {
-	calls int
+	calls       int
+	errs        []error
+	addedTags   map[string]types.Tags
+	removedTags map[string][]string
+	panics      bool
 }
 
 func (p *mockProvider) GetAccountName() string {
@@ -22,7 +28,10 @@ func (p *mockProvider) GetInstances() ([]*types.Instance, error) {
 
 func (p *mockProvider) TerminateInstances(*types.InstanceContainer) []error {
 	p.calls++
-	return nil
+	if p.panics {
+		panic("provider failed")
+	}
+	return p.errs
 }
 
 func (p *mockProvider) TerminateStacks(*types.StackContainer) []error {
@@ -32,6 +41,9 @@ func (p *mockProvider) TerminateStacks(*types.StackContainer) []error {
 
 func (p *mockProvider) StopInstances(*types.InstanceContainer) []error {
 	p.calls++
+	if p.panics {
+		panic("provider failed")
+	}
 	return nil
 }
 
@@ -83,6 +95,27 @@ func (p *mockProvider) CleanupStorages(storageContainer *types.StorageContainer,
 	return nil
 }
 
+func (p *mockProvider) AddTags(items []types.CloudItem, tags types.Tags) []error {
+	if p.panics {
+		panic("provider failed")
+	}
+	for _, item := range items {
+		p.addedTags[item.GetID()] = tags
+	}
+	return p.errs
+}
+
+func (p *mockProvider) RemoveTags(items []types.CloudItem, keys []string) []error {
+	for _, item := range items {
+		p.removedTags[item.GetID()] = keys
+	}
+	return p.errs
+}
+
+func (p *mockProvider) GetUtilizations([]types.CloudItem, time.Duration) (map[string]types.Utilization, []error) {
+	return nil, nil
+}
+
 type terminationSuite struct {
 	suite.Suite
 	providers    map[types.CloudType]func() types.CloudProvider
@@ -94,7 +127,7 @@ func (s *terminationSuite) SetupSuite() {
 }
 
 func (s *terminationSuite) SetupTest() {
-	s.mockProvider = &mockProvider{0}
+	s.mockProvider = &mockProvider{addedTags: map[string]types.Tags{}, removedTags: map[string][]string{}}
 	ctx.CloudProviders = map[types.CloudType]func() types.CloudProvider{
 		types.AWS: func() types.CloudProvider {
 			return s.mockProvider
@@ -113,9 +146,61 @@ func (s *terminationSuite) TestTermination() {
 		types.Instance{CloudType: types.GCP},
 	}
 
-	action.Execute(op, []types.FilterType{}, items)
+	report := action.Execute(op, []types.FilterType{}, items)
 
 	s.Equal(1, s.mockProvider.calls)
+	s.Equal(1, len(report.Results))
+	s.Equal(types.OutcomeSucceeded, report.Results[0].Outcome)
+}
+
+func (s *terminationSuite) TestTerminationPartialFailure() {
+	s.mockProvider.errs = []error{types.NewResourceError("i-2", "eu-west-1", errors.New("denied"))}
+	action := terminationAction{}
+	items := []types.CloudItem{
+		types.Instance{ID: "i-1", Region: "eu-west-1", CloudType: types.AWS},
+		types.Instance{ID: "i-2", Region: "eu-west-1", CloudType: types.AWS},
+	}
+
+	report := action.Execute(types.Instances, []types.FilterType{}, items)
+
+	s.True(report.HasFailure())
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Equal("i-2", report.GetFailed()[0].ID)
+}
+
+func (s *terminationSuite) TestTerminationNotAllowedType() {
+	action := terminationAction{}
+	items := []types.CloudItem{types.Access{Name: "key", CloudType: types.AWS}}
+
+	report := action.Execute(types.CloudAccess, []types.FilterType{}, items)
+
+	s.Equal(0, s.mockProvider.calls)
+	s.Equal(1, report.Count(types.OutcomeFailed))
+}
+
+func (s *terminationSuite) TestTerminationRecoversFromPanic() {
+	s.mockProvider.panics = true
+	action := terminationAction{}
+	items := []types.CloudItem{
+		types.Instance{ID: "i-1", CloudType: types.AWS},
+		types.Instance{ID: "i-2", CloudType: types.AWS},
+	}
+
+	report := action.Execute(types.Instances, []types.FilterType{}, items)
+
+	s.Equal(2, report.Count(types.OutcomeFailed))
+	s.Equal("panic: provider failed", report.GetFailed()[0].Error)
+}
+
+func (s *terminationSuite) TestStopRecoversFromPanic() {
+	s.mockProvider.panics = true
+	action := stopAction{}
+	items := []types.CloudItem{&types.Instance{ID: "i-1", CloudType: types.AWS}}
+
+	report := action.Execute(types.Instances, []types.FilterType{}, items)
+
+	s.Equal(1, report.Count(types.OutcomeFailed))
+	s.Equal("panic: provider failed", report.GetFailed()[0].Error)
 }
 
 func TestTerminationSuite(t *testing.T) {
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	filter "github.com/hortonworks/cloud-haunter/filter"
+	"github.com/hortonworks/cloud-haunter/metrics"
+	"github.com/hortonworks/cloud-haunter/plan"
+	"github.com/hortonworks/cloud-haunter/schedule"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ItemSetHeader is the response header that contains the ID of the returned item set, actions can be executed on it later
+	ItemSetHeader = "X-Item-Set"
+
+	defaultItemSetTTL = 1 * time.Hour
+)
+
+// Server serves the read-only inventory endpoints per operation and a guarded endpoint that runs an action on a previously returned item set
+type Server struct {
+	token      string
+	itemSetTTL time.Duration
+	statuses   func() []schedule.JobStatus
+	now        func() time.Time
+
+	lock     sync.Mutex
+	itemSets map[string]itemSet
+}
+
+type itemSet struct {
+	op      types.OpType
+	filters []types.FilterType
+	items   []types.CloudItem
+	created time.Time
+}
+
+type errorResponse struct {
+	Error string `json:"Error"`
+}
+
+// NewServer creates the API server. The actions can be executed only if the API_TOKEN environment variable is set,
+// the item sets are kept for API_ITEM_SET_TTL (default: 1h).
+func NewServer() (*Server, error) {
+	itemSetTTL := defaultItemSetTTL
+	if ttlEnv := os.Getenv("API_ITEM_SET_TTL"); len(ttlEnv) > 0 {
+		var err error
+		if itemSetTTL, err = time.ParseDuration(ttlEnv); err != nil {
+			return nil, fmt.Errorf("[API] invalid item set TTL: %s, err: %s", ttlEnv, err)
+		}
+	}
+	token := os.Getenv("API_TOKEN")
+	if len(token) == 0 {
+		log.Warn("[API] API_TOKEN environment variable is missing, the actions cannot be executed through the API")
+	}
+	return newServer(token, itemSetTTL), nil
+}
+
+func newServer(token string, itemSetTTL time.Duration) *Server {
+	return &Server{
+		token:      token,
+		itemSetTTL: itemSetTTL,
+		now:        time.Now,
+		itemSets:   map[string]itemSet{},
+	}
+}
+
+// SetJobStatuses exposes the state of the scheduled jobs of the daemon mode
+func (s *Server) SetJobStatuses(statuses func() []schedule.JobStatus) {
+	s.statuses = statuses
+}
+
+// Handler returns the HTTP handler of the endpoints:
+//
+//	GET  /api/v1/operations
+//	GET  /api/v1/operations/{operation}?filter=longrunning,ownerless&cloud=GCP&owner=alice&longrunning.period=6h
+//	POST /api/v1/itemsets/{id}/actions/{action}
+//	GET  /api/v1/jobs
+//	GET  /metrics
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/operations", s.getOperations)
+	mux.HandleFunc("GET /api/v1/operations/{operation}", s.getItems)
+	mux.HandleFunc("POST /api/v1/itemsets/{id}/actions/{action}", s.executeAction)
+	mux.HandleFunc("GET /api/v1/jobs", s.getJobs)
+	mux.Handle("GET /metrics", metrics.Handler())
+	return mux
+}
+
+func (s *Server) getOperations(w http.ResponseWriter, _ *http.Request) {
+	operations := []string{}
+	for op := range ctx.Operations {
+		operations = append(operations, op.String())
+	}
+	sort.Strings(operations)
+	writeJSON(w, http.StatusOK, operations)
+}
+
+func (s *Server) getItems(w http.ResponseWriter, r *http.Request) {
+	op := types.OpType(r.PathValue("operation"))
+	job, filterConfigs, err := parseQuery(op, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := plan.Validate(job); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Infof("[API] Executing operation %s with filters %s on clouds %s", op, job.GetFilterTypes(), job.Clouds)
+	items, err := plan.GetItems(job)
+	if err != nil {
+		log.Errorf("[API] Failed to execute operation %s, err: %s", op, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, filterConfig := range filterConfigs {
+		items = filter.MatchFilterConfig(items, filterConfig)
+	}
+	if items == nil {
+		items = []types.CloudItem{}
+	}
+
+	id, err := s.addItemSet(itemSet{op: op, filters: job.GetFilterTypes(), items: items})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out, err := types.MarshalItems(items)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(ItemSetHeader, id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// The item set is removed once the action is executed, so the same items are not changed twice by accident
+func (s *Server) executeAction(w http.ResponseWriter, r *http.Request) {
+	if len(s.token) == 0 {
+		writeError(w, http.StatusForbidden, fmt.Errorf("actions are disabled, because API_TOKEN is not set"))
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+		return
+	}
+	actionType := types.ActionType(r.PathValue("action"))
+	if _, ok := ctx.Actions[actionType]; !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("action is not found: %s", actionType))
+		return
+	}
+	set, ok := s.removeItemSet(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("item set is not found or expired: %s", r.PathValue("id")))
+		return
+	}
+
+	log.Infof("[API] Executing action %s on %d items of operation %s", actionType, len(set.items), set.op)
+	report, err := plan.ExecuteAction(actionType, set.op, set.filters, set.items)
+	if err != nil {
+		log.Errorf("[API] Failed to execute action %s, err: %s", actionType, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if report == nil {
+		report = types.NewActionReport(actionType)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) getJobs(w http.ResponseWriter, _ *http.Request) {
+	statuses := []schedule.JobStatus{}
+	if s.statuses != nil {
+		statuses = append(statuses, s.statuses()...)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) addItemSet(set itemSet) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	set.created = s.now()
+	for k, v := range s.itemSets {
+		if set.created.Sub(v.created) > s.itemSetTTL {
+			delete(s.itemSets, k)
+		}
+	}
+	s.itemSets[id] = set
+	return id, nil
+}
+
+func (s *Server) removeItemSet(id string) (itemSet, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	set, ok := s.itemSets[id]
+	if !ok || s.now().Sub(set.created) > s.itemSetTTL {
+		return itemSet{}, false
+	}
+	delete(s.itemSets, id)
+	return set, true
+}
+
+// The owner, name and label parameters are matched like the include criteria of the filter config, the items have to match all of them.
+// The parameters of the filters are given in the <filter>.<parameter> format.
+func parseQuery(op types.OpType, query map[string][]string) (types.Job, []types.IFilterConfig, error) {
+	job := types.Job{Operation: op}
+	parameters := map[types.FilterType]map[string]string{}
+	var filterConfigs []types.IFilterConfig
+	for key, values := range query {
+		switch key {
+		case "filter":
+			for _, f := range splitValues(values) {
+				job.Filters = append(job.Filters, types.JobFilter{Type: types.FilterType(f)})
+			}
+		case "cloud":
+			for _, c := range splitValues(values) {
+				job.Clouds = append(job.Clouds, types.CloudType(c))
+			}
+		case string(types.Owner), string(types.Name), string(types.Label):
+			filterConfigs = append(filterConfigs, newFilterConfig(types.FilterConfigProperty(key), splitValues(values)))
+		default:
+			parts := strings.SplitN(key, ".", 2)
+			if len(parts) != 2 || len(parts[1]) == 0 {
+				return job, nil, fmt.Errorf("unknown query parameter: %s", key)
+			}
+			filterType := types.FilterType(parts[0])
+			if _, ok := parameters[filterType]; !ok {
+				parameters[filterType] = map[string]string{}
+			}
+			parameters[filterType][parts[1]] = values[len(values)-1]
+		}
+	}
+	for i, f := range job.Filters {
+		job.Filters[i].Parameters = parameters[f.Type]
+		delete(parameters, f.Type)
+	}
+	for f := range parameters {
+		return job, nil, fmt.Errorf("parameters are given for a filter that is not selected: %s", f)
+	}
+	return job, filterConfigs, nil
+}
+
+func newFilterConfig(property types.FilterConfigProperty, values []string) types.IFilterConfig {
+	var clouds []types.CloudType
+	for cloud := range ctx.CloudProviders {
+		clouds = append(clouds, types.CloudType(strings.ToLower(string(cloud))))
+	}
+	return types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{{
+		Types:      []types.FilterEntityType{types.IncludeInstance, types.IncludeAccess},
+		CloudTypes: clouds,
+		Properties: []types.FilterConfigProperty{property},
+		Values:     values,
+	}}}
+}
+
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(v); len(trimmed) != 0 {
+				split = append(split, trimmed)
+			}
+		}
+	}
+	return split
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	out, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		log.Errorf("[API] Failed to marshal response, err: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(out)
+}
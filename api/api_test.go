@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/hortonworks/cloud-haunter/action"
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	_ "github.com/hortonworks/cloud-haunter/operation"
+	"github.com/hortonworks/cloud-haunter/schedule"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/suite"
+)
+
+const testToken = "secret"
+
+type dummyProvider struct {
+	types.CloudProvider
+	lock    *sync.Mutex
+	stopped map[string]bool
+}
+
+func (p dummyProvider) GetAccountName() string {
+	return "dummy"
+}
+
+func (p dummyProvider) GetInstances() ([]*types.Instance, error) {
+	created := time.Now().Add(-48 * time.Hour)
+	return []*types.Instance{
+		{ID: "i-1", Name: "alice-running", Owner: "alice", State: types.Running, Created: created, CloudType: types.DUMMY},
+		{ID: "i-2", Name: "alice-stopped", Owner: "alice", State: types.Stopped, Created: created, CloudType: types.DUMMY},
+		{ID: "i-3", Name: "bob-running", Owner: "bob", State: types.Running, Created: created, CloudType: types.DUMMY},
+		{ID: "i-4", Name: "alice-new", Owner: "alice", State: types.Running, Created: time.Now(), CloudType: types.DUMMY},
+	}, nil
+}
+
+func (p dummyProvider) StopInstances(instances *types.InstanceContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, instance := range instances.Get(types.DUMMY) {
+		p.stopped[instance.ID] = true
+	}
+	return nil
+}
+
+type apiSuite struct {
+	suite.Suite
+	provider dummyProvider
+	server   *Server
+	http     *httptest.Server
+}
+
+func (s *apiSuite) SetupTest() {
+	s.provider = dummyProvider{lock: &sync.Mutex{}, stopped: map[string]bool{}}
+	ctx.CloudProviders[types.DUMMY] = func() types.CloudProvider {
+		return s.provider
+	}
+	s.server = newServer(testToken, time.Hour)
+	s.http = httptest.NewServer(s.server.Handler())
+}
+
+func (s *apiSuite) TearDownTest() {
+	s.http.Close()
+	delete(ctx.CloudProviders, types.DUMMY)
+}
+
+func (s *apiSuite) get(path string, body interface{}) *http.Response {
+	resp, err := http.Get(s.http.URL + path)
+	s.Require().Nil(err)
+	defer resp.Body.Close()
+	s.Require().Nil(json.NewDecoder(resp.Body).Decode(body))
+	return resp
+}
+
+func (s *apiSuite) post(path, token string, body interface{}) *http.Response {
+	req, _ := http.NewRequest(http.MethodPost, s.http.URL+path, nil)
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().Nil(err)
+	defer resp.Body.Close()
+	s.Require().Nil(json.NewDecoder(resp.Body).Decode(body))
+	return resp
+}
+
+func getNames(items []types.Instance) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func (s *apiSuite) TestGetOperations() {
+	var operations []string
+	resp := s.get("/api/v1/operations", &operations)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(operations, types.Instances.String())
+}
+
+func (s *apiSuite) TestGetItems() {
+	var items []types.Instance
+	resp := s.get("/api/v1/operations/getInstances?filter=running,longrunning&longrunning.period=24h&owner=alice&cloud=dummy", &items)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.NotEmpty(resp.Header.Get(ItemSetHeader))
+	s.Equal([]string{"alice-running"}, getNames(items))
+	s.Equal("i-1", items[0].ID)
+}
+
+func (s *apiSuite) TestGetItemsWithoutFilters() {
+	var items []types.Instance
+	resp := s.get("/api/v1/operations/getInstances", &items)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(4, len(items))
+}
+
+func (s *apiSuite) TestGetItemsInvalidQuery() {
+	for _, path := range []string{
+		"/api/v1/operations/unknown",
+		"/api/v1/operations/getInstances?filter=unknown",
+		"/api/v1/operations/getInstances?unknown=value",
+		"/api/v1/operations/getInstances?longrunning.period=1h",
+		"/api/v1/operations/getInstances?filter=longrunning&longrunning.period=invalid",
+		"/api/v1/operations/getInstances?cloud=unknown",
+	} {
+		var errResp errorResponse
+		resp := s.get(path, &errResp)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, path)
+		s.NotEmpty(errResp.Error, path)
+	}
+}
+
+func (s *apiSuite) TestExecuteAction() {
+	var items []types.Instance
+	resp := s.get("/api/v1/operations/getInstances?filter=running&owner=alice", &items)
+	itemSet := resp.Header.Get(ItemSetHeader)
+
+	var report types.ActionReport
+	resp = s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", testToken, &report)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(types.StopAction, report.Action)
+	s.Equal(2, report.Count(types.OutcomeSucceeded))
+	s.Equal(map[string]bool{"i-1": true, "i-4": true}, s.provider.stopped)
+
+	var errResp errorResponse
+	resp = s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", testToken, &errResp)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *apiSuite) TestExecuteActionIsGuarded() {
+	var items []types.Instance
+	itemSet := s.get("/api/v1/operations/getInstances", &items).Header.Get(ItemSetHeader)
+
+	var errResp errorResponse
+	resp := s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", "", &errResp)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+	resp = s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", "invalid", &errResp)
+	s.Equal(http.StatusUnauthorized, resp.StatusCode)
+	resp = s.post("/api/v1/itemsets/"+itemSet+"/actions/unknown", testToken, &errResp)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	s.server.token = ""
+	resp = s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", "", &errResp)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+	s.Empty(s.provider.stopped)
+}
+
+func (s *apiSuite) TestItemSetExpires() {
+	var items []types.Instance
+	itemSet := s.get("/api/v1/operations/getInstances", &items).Header.Get(ItemSetHeader)
+	s.server.now = func() time.Time {
+		return time.Now().Add(2 * time.Hour)
+	}
+
+	var errResp errorResponse
+	resp := s.post("/api/v1/itemsets/"+itemSet+"/actions/stop", testToken, &errResp)
+
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+	s.Empty(s.provider.stopped)
+}
+
+func (s *apiSuite) TestGetJobs() {
+	var statuses []schedule.JobStatus
+	resp := s.get("/api/v1/jobs", &statuses)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Empty(statuses)
+
+	s.server.SetJobStatuses(func() []schedule.JobStatus {
+		return []schedule.JobStatus{{Name: "job", Cron: "@hourly", LastResult: schedule.ResultSucceeded}}
+	})
+	s.get("/api/v1/jobs", &statuses)
+	s.Equal(1, len(statuses))
+	s.Equal("job", statuses[0].Name)
+}
+
+func TestAPISuite(t *testing.T) {
+	suite.Run(t, new(apiSuite))
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/cloudtrail"
@@ -27,7 +29,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -45,10 +49,15 @@ const (
 	METADATA_SECURITY_GROUPS = "securityGroups"
 	METADATA_ELASTIC_IPS     = "elasticIps"
 	METADATA_ALARMS          = "alarms"
+	METADATA_ARN             = "arn"
 )
 
 var provider = awsProvider{}
 
+var stateTransitionTimePattern = regexp.MustCompile(`\((\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) GMT\)`)
+
+var errShuttingDown = errors.New("skipped, because the process is shutting down")
+
 var rateLimiter = rate.NewLimiter(rate.Every(time.Duration(ctx.AwsApiOperationRateLimitIntervalInSeconds)*time.Second), ctx.AwsApiOperationRateLimit)
 
 type awsProvider struct {
@@ -59,6 +68,7 @@ type awsProvider struct {
 	rdsClients           map[string]*rds.RDS
 	elbClients           map[string]*elb.ELBV2
 	cloudWatchClients    map[string]*cloudwatch.CloudWatch
+	s3Clients            map[string]*s3.S3
 	iamClient            *iam.IAM
 	govCloud             bool
 }
@@ -112,6 +122,7 @@ func (p *awsProvider) init(getRegions func() ([]string, error), govCloud bool) e
 	p.cloudTrailClient = map[string]*cloudtrail.CloudTrail{}
 	p.cloudFormationClient = map[string]*cloudformation.CloudFormation{}
 	p.cloudWatchClients = map[string]*cloudwatch.CloudWatch{}
+	p.s3Clients = map[string]*s3.S3{}
 	p.govCloud = govCloud
 
 	for _, region := range regions {
@@ -156,6 +167,12 @@ func (p *awsProvider) init(getRegions func() ([]string, error), govCloud bool) e
 		} else {
 			p.cloudWatchClients[region] = cwClient
 		}
+
+		if s3Client, err := newS3Client(region); err != nil {
+			panic(fmt.Sprintf("[AWS] Failed to create S3 client, err: %s", err.Error()))
+		} else {
+			p.s3Clients[region] = s3Client
+		}
 	}
 	if iamClient, err := newIamClient(); err != nil {
 		panic(fmt.Sprintf("[AWS] Failed to create IAM client, err: %s", err.Error()))
@@ -266,6 +283,14 @@ func (p awsProvider) getCloudWatchClientsByRegion() map[string]cloudWatchClient
 	return cwClients
 }
 
+func (p awsProvider) getS3ClientsByRegion() map[string]s3Client {
+	s3Clients := map[string]s3Client{}
+	for k := range p.s3Clients {
+		s3Clients[k] = p.s3Clients[k]
+	}
+	return s3Clients
+}
+
 func (p awsProvider) TerminateInstances(instances *types.InstanceContainer) []error {
 	log.Debug("[AWS] Terminating instances")
 	regionInstances := map[string][]string{}
@@ -285,6 +310,11 @@ func (p awsProvider) TerminateInstances(instances *types.InstanceContainer) []er
 			ec2Client := p.ec2Clients[region]
 
 			for i := 0; i < len(instanceIds); i += ctx.AwsBulkOperationSize {
+				if ctx.IsShuttingDown() {
+					log.Warnf("[AWS] Shutting down, instances are not terminated in region %s: %v", region, instanceIds[i:])
+					sendResourceErrors(errChan, instanceIds[i:], region, errShuttingDown)
+					break
+				}
 				log.Infof("[AWS] Round %d for terminate operation in region %s", (i/ctx.AwsBulkOperationSize)+1, region)
 				arrayEnd := i + ctx.AwsBulkOperationSize
 				if ctx.AwsBulkOperationSize > len(instanceIds[i:]) {
@@ -298,7 +328,8 @@ func (p awsProvider) TerminateInstances(instances *types.InstanceContainer) []er
 				})
 				if err != nil {
 					log.Errorf("[AWS] Failed to terminate instances: %v, err: %s", instanceIdsChunk, err)
-					errChan <- err
+					sendResourceErrors(errChan, instanceIdsChunk, region, err)
+					continue
 				}
 
 				err = ec2Client.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{
@@ -306,7 +337,7 @@ func (p awsProvider) TerminateInstances(instances *types.InstanceContainer) []er
 				})
 				if err != nil {
 					log.Errorf("[AWS] Failed to wait for terminated instances: %v, err: %s", instanceIdsChunk, err)
-					errChan <- err
+					sendResourceErrors(errChan, instanceIdsChunk, region, err)
 				}
 			}
 		}(r, i)
@@ -369,6 +400,12 @@ func (p awsProvider) StopInstances(instances *types.InstanceContainer) []error {
 			defer wg.Done()
 
 			for i := 0; i < len(instances); i += ctx.AwsBulkOperationSize {
+				if ctx.IsShuttingDown() {
+					_, instanceIDs := getNameIDPairs(instances[i:])
+					log.Warnf("[AWS] Shutting down, instances are not stopped in region %s: %v", region, aws.StringValueSlice(instanceIDs))
+					sendResourceErrors(errChan, aws.StringValueSlice(instanceIDs), region, errShuttingDown)
+					break
+				}
 				log.Infof("[AWS] Round %d for stop operation", (i/ctx.AwsBulkOperationSize)+1)
 				arrayEnd := i + ctx.AwsBulkOperationSize
 				if ctx.AwsBulkOperationSize > len(instances[i:]) {
@@ -386,6 +423,7 @@ func (p awsProvider) StopInstances(instances *types.InstanceContainer) []error {
 				})
 				if err != nil {
 					log.Errorf("[AWS] Failed to fetch the ASG instances in region: %s, err: %s", region, err)
+					errChan <- types.NewResourceError("", region, err)
 					return
 				}
 
@@ -410,6 +448,7 @@ func (p awsProvider) StopInstances(instances *types.InstanceContainer) []error {
 						log.Errorf("[AWS] Failed to suspend ASG %v for instance %s, err: %s", instance.AutoScalingGroupName, compactInstanceName, err.Error())
 						// Do not stop the instance if the ASG cannot be suspended otherwise the ASG will terminate the instance
 						instanceIDs = removeInstance(instanceIDs, instanceId)
+						errChan <- types.NewResourceError(*instanceId, region, err)
 					}
 				}
 
@@ -433,7 +472,7 @@ func (p awsProvider) StopInstances(instances *types.InstanceContainer) []error {
 					}
 					log.Infof("[AWS] Sending request to stop instances in region %s (%d): %v", region, len(instanceIDs), stopInstancesNames)
 					if _, err := p.ec2Clients[region].StopInstances(&ec2.StopInstancesInput{InstanceIds: instanceIDs}); err != nil {
-						errChan <- err
+						sendResourceErrors(errChan, aws.StringValueSlice(instanceIDs), region, err)
 					}
 
 					if len(spotInstanceIDs) > 0 {
@@ -445,7 +484,7 @@ func (p awsProvider) StopInstances(instances *types.InstanceContainer) []error {
 
 						log.Infof("[AWS] Sending request to terminate spot instances in region %s (%d): %v", region, len(spotInstanceIDs), spotInstanceNames)
 						if _, err := p.ec2Clients[region].TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: spotInstanceIDs}); err != nil {
-							errChan <- err
+							sendResourceErrors(errChan, aws.StringValueSlice(spotInstanceIDs), region, err)
 						}
 					} else {
 						log.Info("[AWS] There are not spot instances to terminate")
@@ -487,7 +526,7 @@ func (p awsProvider) StopDatabases(databases *types.DatabaseContainer) []error {
 				if _, err := p.rdsClients[region].StopDBInstance(&rds.StopDBInstanceInput{
 					DBInstanceIdentifier: &db.Name,
 				}); err != nil {
-					errChan <- err
+					errChan <- types.NewResourceError(db.ID, region, err)
 				}
 			}
 		}(r, db)
@@ -530,15 +569,15 @@ func deleteStacks(cfClients map[string]cfClient, rdsClients map[string]rdsClient
 					case TYPE_NATIVE:
 						err := deleteNativeStack(ec2Client, elbClient, cloudWatchClient, region, stack)
 						if err != nil {
-							errChan <- err
+							errChan <- types.NewResourceError(stack.ID, region, err)
 						}
 					case TYPE_CF:
 						err := deleteCfStack(cfClient, rdsClient, ec2Client, elbClient, region, stack)
 						if err != nil {
-							errChan <- err
+							errChan <- types.NewResourceError(stack.ID, region, err)
 						}
 					default:
-						errChan <- fmt.Errorf("[AWS] Stack type %s (of stack %s in region %s) delete is not implemented", stack.Metadata[METADATA_TYPE], stack.ID, region)
+						errChan <- types.NewResourceError(stack.ID, region, fmt.Errorf("[AWS] Stack type %s (of stack %s in region %s) delete is not implemented", stack.Metadata[METADATA_TYPE], stack.ID, region))
 					}
 				}
 			}(cfClients[r], rdsClients[r], ec2Clients[r], elbClients[r], cloudWatchClients[r], r, s)
@@ -919,11 +958,12 @@ func deleteVolumes(cloudType types.CloudType, ec2Clients map[string]ec2Client, v
 
 					if detachError != nil {
 						log.Infof("[AWS] Skip volume %s:%s as it can not be detached by [%s].", vol.Name, vol.ID, detachError)
+						errChan <- types.NewResourceError(vol.ID, region, detachError)
 						continue
 					}
 					log.Infof("[AWS] Delete volume: %s:%s", vol.Name, vol.ID)
 					if _, err := ec2Client.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: &vol.ID}); err != nil {
-						errChan <- err
+						errChan <- types.NewResourceError(vol.ID, region, err)
 					}
 				}
 			}
@@ -991,7 +1031,7 @@ func deleteImages(cloudType types.CloudType, ec2Clients map[string]ec2Client, im
 					log.Infof("[AWS] Delete image: %s:%s", image.Name, image.ID)
 					if _, err := ec2Client.DeregisterImage(&ec2.DeregisterImageInput{ImageId: &image.ID}); err != nil {
 						log.Errorf("[AWS] Unable to delete image: %s because: %s", image.ID, err.Error())
-						errChan <- errors.New(image.ID)
+						errChan <- types.NewResourceError(image.ID, region, err)
 					}
 				}
 			}
@@ -1029,11 +1069,30 @@ func (p awsProvider) DeleteAlerts(alertContainer *types.AlertContainer) []error
 }
 
 func (p awsProvider) GetStorages() ([]*types.Storage, error) {
-	return nil, errors.New("[AWS] Getting storages is not supported yet")
+	log.Debug("[AWS] Fetch buckets")
+	return getStorages(p.GetCloudType(), p.getS3ClientsByRegion())
 }
 
 func (p awsProvider) CleanupStorages(storageContainer *types.StorageContainer, retentionDays int) []error {
-	return []error{errors.New("[AWS] Cleanup storages is not supported yet")}
+	log.Debug("[AWS] Cleanup buckets")
+	return cleanupStorages(p.getS3ClientsByRegion(), storageContainer.Get(p.GetCloudType()), retentionDays)
+}
+
+func (p awsProvider) AddTags(items []types.CloudItem, tags types.Tags) []error {
+	log.Debugf("[AWS] Add tags: %v", tags)
+	ec2Clients, _ := p.getEc2AndCTClientsByRegion()
+	return updateTags(p.GetCloudType(), ec2Clients, p.getRdsClientsByRegion(), items, tags, nil)
+}
+
+func (p awsProvider) RemoveTags(items []types.CloudItem, keys []string) []error {
+	log.Debugf("[AWS] Remove tags: %v", keys)
+	ec2Clients, _ := p.getEc2AndCTClientsByRegion()
+	return updateTags(p.GetCloudType(), ec2Clients, p.getRdsClientsByRegion(), items, nil, keys)
+}
+
+func (p awsProvider) GetUtilizations(items []types.CloudItem, period time.Duration) (map[string]types.Utilization, []error) {
+	log.Debugf("[AWS] Fetching utilization of %d items for the last %s", len(items), period)
+	return getUtilizations(p.GetCloudType(), p.getCloudWatchClientsByRegion(), items, period, time.Now())
 }
 
 func (p awsProvider) GetCloudType() types.CloudType {
@@ -1063,6 +1122,8 @@ type ec2Client interface {
 	WaitUntilInstanceTerminated(input *ec2.DescribeInstancesInput) error
 	DescribeAddresses(input *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error)
 	ReleaseAddress(input *ec2.ReleaseAddressInput) (*ec2.ReleaseAddressOutput, error)
+	CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
+	DeleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error)
 }
 
 type cfClient interface {
@@ -1086,6 +1147,8 @@ type rdsClient interface {
 	DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error)
 	ListTagsForResource(input *rds.ListTagsForResourceInput) (*rds.ListTagsForResourceOutput, error)
 	ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.ModifyDBInstanceOutput, error)
+	AddTagsToResource(input *rds.AddTagsToResourceInput) (*rds.AddTagsToResourceOutput, error)
+	RemoveTagsFromResource(input *rds.RemoveTagsFromResourceInput) (*rds.RemoveTagsFromResourceOutput, error)
 }
 
 type elbClient interface {
@@ -1098,6 +1161,7 @@ type elbClient interface {
 type cloudWatchClient interface {
 	DescribeAlarms(input *cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error)
 	DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudwatch.DeleteAlarmsOutput, error)
+	GetMetricData(input *cloudwatch.GetMetricDataInput) (*cloudwatch.GetMetricDataOutput, error)
 }
 
 func getInstances(cloudType types.CloudType, ec2Clients map[string]ec2Client, cloudTrailClients map[string]cloudTrailClient) ([]*types.Instance, error) {
@@ -1742,7 +1806,7 @@ func deleteAlerts(cloudWatchClients map[string]cloudWatchClient, alerts []*types
 			defer wg.Done()
 
 			alertNames := []string{}
-			for _, alert := range alerts {
+			for _, alert := range alertsInRegion {
 				alertNames = append(alertNames, alert.Name)
 			}
 
@@ -1761,7 +1825,9 @@ func deleteAlerts(cloudWatchClients map[string]cloudWatchClient, alerts []*types
 					})
 					if err != nil {
 						log.Errorf("[AWS] Failed to delete alerts %s in region %s, err: %s", currentAlertNames, region, err)
-						errChan <- err
+						for _, alert := range alertsInRegion[i:endIndex] {
+							errChan <- types.NewResourceError(alert.ID, region, err)
+						}
 					}
 				}
 			}
@@ -1781,6 +1847,269 @@ func deleteAlerts(cloudWatchClients map[string]cloudWatchClient, alerts []*types
 	return errors
 }
 
+// updateTags adds the tags to and removes the keys from the instances, volumes, databases and stacks. The tags of a native stack are the tags of its instances.
+func updateTags(cloudType types.CloudType, ec2Clients map[string]ec2Client, rdsClients map[string]rdsClient, items []types.CloudItem, tags types.Tags, keys []string) []error {
+	regionItems := map[string][]types.CloudItem{}
+	for _, item := range items {
+		if item.GetCloudType() == cloudType {
+			regionItems[item.GetRegion()] = append(regionItems[item.GetRegion()], item)
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(regionItems))
+	errChan := make(chan error)
+
+	for r, i := range regionItems {
+		go func(region string, itemsInRegion []types.CloudItem) {
+			defer wg.Done()
+
+			for _, item := range itemsInRegion {
+				if ctx.DryRun {
+					log.Infof("[AWS] Dry-run set, tags of %s are not updated: %s, region: %s", item.GetType(), item.GetName(), region)
+					continue
+				}
+				log.Infof("[AWS] Update tags of %s: %s, region: %s", item.GetType(), item.GetName(), region)
+				var err error
+				switch t := item.GetItem().(type) {
+				case types.Instance, types.Disk:
+					err = updateEc2Tags(ec2Clients[region], []string{item.GetID()}, tags, keys)
+				case types.Database:
+					err = updateRdsTags(rdsClients[region], t.Metadata[METADATA_ARN], tags, keys)
+				case types.Stack:
+					switch t.Metadata[METADATA_TYPE] {
+					case TYPE_NATIVE:
+						err = updateEc2Tags(ec2Clients[region], getResourceList(t.Metadata[METADATA_INSTANCES]), tags, keys)
+					case TYPE_CF:
+						// the tags of a CloudFormation stack can only be changed by a stack update, which may replace its resources
+						err = fmt.Errorf("[AWS] Tags of CloudFormation stack %s cannot be updated without updating the stack", t.Name)
+					default:
+						err = fmt.Errorf("[AWS] Stack type %s does not support tags", t.Metadata[METADATA_TYPE])
+					}
+				default:
+					err = fmt.Errorf("[AWS] Tagging of %s is not supported", item.GetType())
+				}
+				if err != nil {
+					log.Errorf("[AWS] Failed to update tags of %s: %s, err: %s", item.GetType(), item.GetName(), err)
+					errChan <- types.NewResourceError(item.GetID(), region, err)
+				}
+			}
+		}(r, i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func updateEc2Tags(ec2Client ec2Client, resourceIDs []string, tags types.Tags, keys []string) error {
+	if len(tags) != 0 {
+		var ec2Tags []*ec2.Tag
+		for k, v := range tags {
+			ec2Tags = append(ec2Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		if _, err := ec2Client.CreateTags(&ec2.CreateTagsInput{Resources: aws.StringSlice(resourceIDs), Tags: ec2Tags}); err != nil {
+			return err
+		}
+	}
+	if len(keys) != 0 {
+		var ec2Tags []*ec2.Tag
+		for _, k := range keys {
+			ec2Tags = append(ec2Tags, &ec2.Tag{Key: aws.String(k)})
+		}
+		if _, err := ec2Client.DeleteTags(&ec2.DeleteTagsInput{Resources: aws.StringSlice(resourceIDs), Tags: ec2Tags}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateRdsTags(rdsClient rdsClient, arn string, tags types.Tags, keys []string) error {
+	if len(arn) == 0 {
+		return errors.New("[AWS] Database ARN is missing")
+	}
+	if len(tags) != 0 {
+		var rdsTags []*rds.Tag
+		for k, v := range tags {
+			rdsTags = append(rdsTags, &rds.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		if _, err := rdsClient.AddTagsToResource(&rds.AddTagsToResourceInput{ResourceName: aws.String(arn), Tags: rdsTags}); err != nil {
+			return err
+		}
+	}
+	if len(keys) != 0 {
+		if _, err := rdsClient.RemoveTagsFromResource(&rds.RemoveTagsFromResourceInput{ResourceName: aws.String(arn), TagKeys: aws.StringSlice(keys)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMetricData accepts at most 500 queries in a single request
+const maxMetricDataQueries = 500
+
+type utilizationMetric struct {
+	itemID     string
+	namespace  string
+	name       string
+	dimension  string
+	value      string
+	stat       string
+	network    bool
+	bytesTotal bool
+}
+
+// getUtilizations fetches the average CPU and network utilization of the instances and databases.
+// The metrics of a region are fetched with as few GetMetricData requests as possible to stay within the API rate limit.
+func getUtilizations(cloudType types.CloudType, cloudWatchClients map[string]cloudWatchClient, items []types.CloudItem, period time.Duration, now time.Time) (map[string]types.Utilization, []error) {
+	metricPeriod := getMetricPeriod(period)
+	metrics := map[string]utilizationMetric{}
+	regionQueries := map[string][]*cloudwatch.MetricDataQuery{}
+	for _, item := range items {
+		if item.GetCloudType() != cloudType {
+			continue
+		}
+		for _, metric := range getUtilizationMetrics(item) {
+			queryID := fmt.Sprintf("m%d", len(metrics))
+			metrics[queryID] = metric
+			regionQueries[item.GetRegion()] = append(regionQueries[item.GetRegion()], &cloudwatch.MetricDataQuery{
+				Id: aws.String(queryID),
+				MetricStat: &cloudwatch.MetricStat{
+					Metric: &cloudwatch.Metric{
+						Namespace:  aws.String(metric.namespace),
+						MetricName: aws.String(metric.name),
+						Dimensions: []*cloudwatch.Dimension{{Name: aws.String(metric.dimension), Value: aws.String(metric.value)}},
+					},
+					Period: aws.Int64(int64(metricPeriod.Seconds())),
+					Stat:   aws.String(metric.stat),
+				},
+			})
+		}
+	}
+
+	values := map[string][]float64{}
+	var errs []error
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(len(regionQueries))
+
+	for r, q := range regionQueries {
+		go func(region string, cloudWatchClient cloudWatchClient, queries []*cloudwatch.MetricDataQuery) {
+			defer wg.Done()
+
+			if cloudWatchClient == nil {
+				lock.Lock()
+				errs = append(errs, types.NewResourceError("", region, errors.New("[AWS] CloudWatch client not found")))
+				lock.Unlock()
+				return
+			}
+			for start := 0; start < len(queries); start += maxMetricDataQueries {
+				end := start + maxMetricDataQueries
+				if end > len(queries) {
+					end = len(queries)
+				}
+				log.Debugf("[AWS] Fetching metrics (%d) in region: %s", end-start, region)
+				input := &cloudwatch.GetMetricDataInput{
+					StartTime:         aws.Time(now.Add(-period)),
+					EndTime:           aws.Time(now),
+					MetricDataQueries: queries[start:end],
+				}
+				for {
+					output, err := cloudWatchClient.GetMetricData(input)
+					if err != nil {
+						log.Errorf("[AWS] Failed to fetch metrics in region: %s, err: %s", region, err)
+						lock.Lock()
+						errs = append(errs, types.NewResourceError("", region, err))
+						lock.Unlock()
+						return
+					}
+					lock.Lock()
+					for _, result := range output.MetricDataResults {
+						values[*result.Id] = append(values[*result.Id], aws.Float64ValueSlice(result.Values)...)
+					}
+					lock.Unlock()
+					if output.NextToken == nil {
+						break
+					}
+					input.NextToken = output.NextToken
+				}
+			}
+		}(r, cloudWatchClients[r], q)
+	}
+	wg.Wait()
+
+	return aggregateUtilizations(metrics, values, metricPeriod), errs
+}
+
+// An item without CPU data points is left out, because its utilization is unknown
+func aggregateUtilizations(metrics map[string]utilizationMetric, values map[string][]float64, metricPeriod time.Duration) map[string]types.Utilization {
+	utilizations := map[string]types.Utilization{}
+	for queryID, metric := range metrics {
+		if !metric.network && len(values[queryID]) != 0 {
+			utilizations[metric.itemID] = types.Utilization{CPU: average(values[queryID])}
+		}
+	}
+	for queryID, metric := range metrics {
+		utilization, ok := utilizations[metric.itemID]
+		if !metric.network || !ok || len(values[queryID]) == 0 {
+			continue
+		}
+		rate := average(values[queryID])
+		if metric.bytesTotal {
+			rate = rate / metricPeriod.Seconds()
+		}
+		utilization.Network += rate
+		utilizations[metric.itemID] = utilization
+	}
+	return utilizations
+}
+
+func getUtilizationMetrics(item types.CloudItem) []utilizationMetric {
+	switch item.GetItem().(type) {
+	case types.Instance:
+		return []utilizationMetric{
+			{itemID: item.GetID(), namespace: "AWS/EC2", name: "CPUUtilization", dimension: "InstanceId", value: item.GetID(), stat: "Average"},
+			{itemID: item.GetID(), namespace: "AWS/EC2", name: "NetworkIn", dimension: "InstanceId", value: item.GetID(), stat: "Sum", network: true, bytesTotal: true},
+			{itemID: item.GetID(), namespace: "AWS/EC2", name: "NetworkOut", dimension: "InstanceId", value: item.GetID(), stat: "Sum", network: true, bytesTotal: true},
+		}
+	case types.Database:
+		return []utilizationMetric{
+			{itemID: item.GetID(), namespace: "AWS/RDS", name: "CPUUtilization", dimension: "DBInstanceIdentifier", value: item.GetName(), stat: "Average"},
+			{itemID: item.GetID(), namespace: "AWS/RDS", name: "NetworkReceiveThroughput", dimension: "DBInstanceIdentifier", value: item.GetName(), stat: "Average", network: true},
+			{itemID: item.GetID(), namespace: "AWS/RDS", name: "NetworkTransmitThroughput", dimension: "DBInstanceIdentifier", value: item.GetName(), stat: "Average", network: true},
+		}
+	default:
+		log.Debugf("[AWS] Utilization of %s is not supported: %s", item.GetType(), item.GetName())
+		return nil
+	}
+}
+
+// The data points are hourly, or a single one if the period is shorter than an hour
+func getMetricPeriod(period time.Duration) time.Duration {
+	if period >= time.Hour {
+		return time.Hour
+	}
+	if period < time.Minute {
+		return time.Minute
+	}
+	return period.Truncate(time.Minute)
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
 func getDatabases(cloudType types.CloudType, rdsClients map[string]rdsClient, cloudTrailClients map[string]cloudTrailClient) ([]*types.Database, error) {
 	dbChan := make(chan *types.Database)
 	wg := sync.WaitGroup{}
@@ -1809,7 +2138,7 @@ func getDatabases(cloudType types.CloudType, rdsClients map[string]rdsClient, cl
 				if d.State == types.Running && len(d.Owner) == 0 {
 					log.Debugf("[AWS] Check CloudTrail for database: %s", d.Name)
 					if iamUser := getIAMUserFromCloudTrail(d.Name, cloudTrailClient); iamUser != nil {
-						d.Metadata = map[string]string{"IAMUser": *iamUser}
+						d.Metadata["IAMUser"] = *iamUser
 					}
 				}
 				dbChan <- d
@@ -1873,6 +2202,12 @@ func containsInstanceID(instanceIDs []*string, instanceID *string) bool {
 	return found
 }
 
+func sendResourceErrors(errChan chan error, IDs []string, region string, err error) {
+	for _, ID := range IDs {
+		errChan <- types.NewResourceError(ID, region, err)
+	}
+}
+
 func newIamClient() (*iam.IAM, error) {
 	awsSession, err := newSession(nil)
 	if err != nil {
@@ -1941,6 +2276,16 @@ func newCloudWatchClient(region string) (*cloudwatch.CloudWatch, error) {
 	return cloudwatch.New(awsSession), nil
 }
 
+func newS3Client(region string) (*s3.S3, error) {
+	awsSession, err := newSession(func(config *aws.Config) {
+		config.Region = &region
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(awsSession), nil
+}
+
 func newElbClient(region string) (*elb.ELBV2, error) {
 	awsSession, err := newSession(func(config *aws.Config) {
 		config.Region = &region
@@ -1968,14 +2313,17 @@ func (c *ThrottledTransport) RoundTrip(r *http.Request) (*http.Response, error)
 
 func newSession(configure func(*aws.Config)) (*session.Session, error) {
 	httpClient := &http.Client{
-		Transport: NewThrottledTransport(rateLimiter, &http.Transport{
+		Transport: recording.WrapTransport(NewThrottledTransport(rateLimiter, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		})}
+		}))}
 	maxRetries := MAX_RETRIES
 	config := aws.Config{
 		HTTPClient: httpClient,
 		MaxRetries: &maxRetries,
 	}
+	if recording.IsReplaying() {
+		config.Credentials = credentials.AnonymousCredentials
+	}
 	if configure != nil {
 		configure(&config)
 	}
@@ -2005,6 +2353,7 @@ func newInstance(cloudType types.CloudType, inst *ec2.Instance) *types.Instance
 		InstanceType: *inst.InstanceType,
 		State:        getInstanceState(inst),
 		Ephemeral:    ephemeral,
+		StoppedAt:    getStoppedAt(inst),
 	}
 }
 
@@ -2053,6 +2402,24 @@ func getInstanceState(instance *ec2.Instance) types.State {
 	}
 }
 
+// The time of the last state transition is only part of its reason, e.g. "User initiated (2019-09-19 08:39:41 GMT)"
+func getStoppedAt(instance *ec2.Instance) *time.Time {
+	if getInstanceState(instance) != types.Stopped || instance.StateTransitionReason == nil {
+		return nil
+	}
+	match := stateTransitionTimePattern.FindStringSubmatch(*instance.StateTransitionReason)
+	if match == nil {
+		log.Debugf("[AWS] No time in the state transition reason of instance %s: %s", *instance.InstanceId, *instance.StateTransitionReason)
+		return nil
+	}
+	stoppedAt, err := time.Parse("2006-01-02 15:04:05", match[1])
+	if err != nil {
+		log.Warnf("[AWS] Invalid time in the state transition reason of instance %s: %s", *instance.InstanceId, *instance.StateTransitionReason)
+		return nil
+	}
+	return &stoppedAt
+}
+
 func getCFState(stack *cloudformation.Stack) types.State {
 	if stack.StackStatus == nil {
 		return types.Unknown
@@ -2115,6 +2482,7 @@ func newDatabase(cloudType types.CloudType, rds rds.DBInstance, tagList *rds.Lis
 		Owner:        tags[ctx.OwnerLabel],
 		Tags:         tags,
 		CloudType:    cloudType,
+		Metadata:     map[string]string{METADATA_ARN: aws.StringValue(rds.DBInstanceArn)},
 	}
 }
 
@@ -1,11 +1,17 @@
 package aws
 
 import (
+	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudtrail"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
@@ -13,7 +19,9 @@ import (
 	elb "github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,6 +42,34 @@ func TestProviderInit(t *testing.T) {
 	assert.Equal(t, 2, len(provider.ec2Clients))
 }
 
+// The testdata was created with the -record flag, further incidents can be reproduced the same way
+func TestGetInstancesFromRecording(t *testing.T) {
+	replayer, err := recording.NewReplayer("testdata/recording/instances")
+	assert.Nil(t, err)
+	awsSession, err := session.NewSession(&aws.Config{
+		HTTPClient:  &http.Client{Transport: replayer},
+		Region:      aws.String("eu-west-1"),
+		Credentials: credentials.AnonymousCredentials,
+	})
+	assert.Nil(t, err)
+
+	instances, err := getInstances(types.AWS, map[string]ec2Client{"eu-west-1": ec2.New(awsSession)}, map[string]cloudTrailClient{})
+
+	assert.Nil(t, err)
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, "running-instance", instances[0].Name)
+	assert.Equal(t, "alice", instances[0].Owner)
+	assert.Equal(t, "m5.large", instances[0].InstanceType)
+	assert.Equal(t, types.Running, instances[0].State)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), instances[0].Created.UTC())
+	assert.Equal(t, "i-1234567890abcdef1", instances[1].Name)
+	assert.Equal(t, types.Stopped, instances[1].State)
+	assert.Equal(t, time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC), *instances[1].StoppedAt)
+}
+
 func TestGetRunningInstances(t *testing.T) {
 	ec2Clients := map[string]ec2Client{"region": mockEc2Client{operationChannel: make(chan string, 10)}}
 	ctClients := map[string]cloudTrailClient{"region": mockCtClient{}}
@@ -101,6 +137,158 @@ func TestDeleteImages(t *testing.T) {
 	assert.Equal(t, "ami-id-2", <-region2Chan)
 }
 
+func TestUpdateTags(t *testing.T) {
+	operationChannel := make(chan string, 10)
+	ec2Clients := map[string]ec2Client{"region-1": mockEc2Client{operationChannel: operationChannel}}
+	rdsClients := map[string]rdsClient{"region-1": mockRdsClient{operationChannel: operationChannel}}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, ID: "i-1", Region: "region-1"},
+		&types.Database{CloudType: types.AWS, ID: "db-1", Region: "region-1", Metadata: map[string]string{METADATA_ARN: "arn:db-1"}},
+		&types.Stack{CloudType: types.AWS, ID: "stack-1", Region: "region-1", Metadata: map[string]string{METADATA_TYPE: TYPE_NATIVE, METADATA_INSTANCES: "i-2,i-3"}},
+		&types.Instance{CloudType: types.GCP, ID: "i-4", Region: "region-1"},
+	}
+
+	errs := updateTags(types.AWS, ec2Clients, rdsClients, items, types.Tags{"key": "value"}, []string{"other"})
+	close(operationChannel)
+
+	var operations []string
+	for op := range operationChannel {
+		operations = append(operations, op)
+	}
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"CreateTags:i-1", "DeleteTags:i-1", "AddTagsToResource:arn:db-1", "RemoveTagsFromResource:arn:db-1", "CreateTags:i-2,i-3", "DeleteTags:i-2,i-3"}, operations)
+}
+
+func TestUpdateTagsNotSupported(t *testing.T) {
+	items := []types.CloudItem{&types.Image{CloudType: types.AWS, ID: "ami-1", Region: "region-1"}}
+
+	errs := updateTags(types.AWS, nil, nil, items, types.Tags{"key": "value"}, nil)
+
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "ami-1", errs[0].(types.ResourceError).ID)
+}
+
+func TestUpdateTagsOfCloudFormationStack(t *testing.T) {
+	items := []types.CloudItem{&types.Stack{CloudType: types.AWS, ID: "arn:stack-1", Name: "stack-1", Region: "region-1", Metadata: map[string]string{METADATA_TYPE: TYPE_CF}}}
+
+	errs := updateTags(types.AWS, nil, nil, items, types.Tags{"key": "value"}, nil)
+
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "arn:stack-1", errs[0].(types.ResourceError).ID)
+	assert.Contains(t, errs[0].Error(), "stack-1")
+}
+
+func TestGetUtilizations(t *testing.T) {
+	operationChannel := make(chan string, 10)
+	cloudWatchClients := map[string]cloudWatchClient{"region-1": mockCwClient{operationChannel: operationChannel}}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, ID: "i-1", Region: "region-1"},
+		&types.Instance{CloudType: types.AWS, ID: "i-nodata", Region: "region-1"},
+		&types.Database{CloudType: types.AWS, ID: "db-1", Name: "database", Region: "region-1"},
+		&types.Disk{CloudType: types.AWS, ID: "vol-1", Region: "region-1"},
+		&types.Instance{CloudType: types.AWS, ID: "i-2", Region: "region-2"},
+	}
+
+	utilizations, errs := getUtilizations(types.AWS, cloudWatchClients, items, 24*time.Hour, time.Now())
+	close(operationChannel)
+
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "region-2", errs[0].(types.ResourceError).Region)
+	assert.Equal(t, []string{"GetMetricData:9"}, []string{<-operationChannel})
+	assert.Equal(t, 2, len(utilizations))
+	assert.Equal(t, types.Utilization{CPU: 3, Network: 3}, utilizations["i-1"])
+	assert.Equal(t, types.Utilization{CPU: 3, Network: 20}, utilizations["db-1"])
+}
+
+func TestGetUtilizationsBatched(t *testing.T) {
+	operationChannel := make(chan string, 10)
+	cloudWatchClients := map[string]cloudWatchClient{"region-1": mockCwClient{operationChannel: operationChannel}}
+	var items []types.CloudItem
+	for i := 0; i < 200; i++ {
+		items = append(items, &types.Instance{CloudType: types.AWS, ID: fmt.Sprintf("i-%d", i), Region: "region-1"})
+	}
+
+	utilizations, errs := getUtilizations(types.AWS, cloudWatchClients, items, time.Hour, time.Now())
+	close(operationChannel)
+
+	var operations []string
+	for op := range operationChannel {
+		operations = append(operations, op)
+	}
+	assert.Empty(t, errs)
+	assert.Equal(t, 200, len(utilizations))
+	assert.Equal(t, []string{"GetMetricData:500", "GetMetricData:100"}, operations)
+}
+
+func TestGetStorages(t *testing.T) {
+	operationChannel := make(chan string, 20)
+	s3Clients := map[string]s3Client{
+		"us-east-1": mockS3Client{operationChannel: operationChannel},
+		"eu-west-1": mockS3Client{operationChannel: operationChannel},
+	}
+
+	storages, err := getStorages(types.AWS, s3Clients)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(storages))
+	assert.Equal(t, "bucket-1", storages[0].ID)
+	assert.Equal(t, "us-east-1", storages[0].Region)
+	assert.Equal(t, OWNER, storages[0].Owner)
+	assert.Equal(t, NOW, storages[0].Created)
+	assert.Equal(t, "", storages[0].MetaData[METADATA_VERSIONING])
+	assert.Equal(t, "bucket-2", storages[1].ID)
+	assert.Equal(t, "eu-west-1", storages[1].Region)
+	assert.Equal(t, "", storages[1].Owner)
+	assert.Equal(t, 0, len(storages[1].Tags))
+	assert.Equal(t, s3.BucketVersioningStatusEnabled, storages[1].MetaData[METADATA_VERSIONING])
+}
+
+func TestCleanupStorages(t *testing.T) {
+	operationChannel := make(chan string, 20)
+	s3Clients := map[string]s3Client{"us-east-1": mockS3Client{operationChannel: operationChannel}}
+	storages := []*types.Storage{
+		{CloudType: types.AWS, ID: "bucket-1", Name: "bucket-1", Region: "us-east-1"},
+		{CloudType: types.AWS, ID: "bucket-2", Name: "bucket-2", Region: "us-east-1", MetaData: map[string]string{METADATA_VERSIONING: s3.BucketVersioningStatusEnabled}},
+		{CloudType: types.AWS, ID: "bucket-3", Name: "bucket-3", Region: "eu-west-1"},
+		{CloudType: types.AWS, ID: "bucket-4", Name: "bucket-4", Region: "us-east-1"},
+	}
+
+	errs := cleanupStorages(s3Clients, storages, 7)
+	close(operationChannel)
+
+	var operations []string
+	for op := range operationChannel {
+		operations = append(operations, op)
+	}
+	var failed []string
+	for _, err := range errs {
+		failed = append(failed, err.(types.ResourceError).ID)
+	}
+	sort.Strings(failed)
+	assert.Equal(t, []string{"bucket-3", "bucket-4"}, failed)
+	assert.Equal(t, []string{
+		"ListObjectsV2:bucket-1", "DeleteObjects:bucket-1:1000", "DeleteObjects:bucket-1:500",
+		"ListObjectVersions:bucket-2", "DeleteObjects:bucket-2:2",
+	}, operations)
+}
+
+func TestCleanupStoragesDryRun(t *testing.T) {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+	operationChannel := make(chan string, 20)
+	s3Clients := map[string]s3Client{"us-east-1": mockS3Client{operationChannel: operationChannel}}
+	storages := []*types.Storage{{CloudType: types.AWS, ID: "bucket-1", Name: "bucket-1", Region: "us-east-1"}}
+
+	errs := cleanupStorages(s3Clients, storages, 7)
+	close(operationChannel)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, "ListObjectsV2:bucket-1", <-operationChannel)
+	assert.Equal(t, "", <-operationChannel)
+}
+
 func TestNewInstanceWithName(t *testing.T) {
 	ec2Instance := newTestInstance()
 	ec2Instance.Tags = []*ec2.Tag{{Key: &(&types.S{S: "Name"}).S, Value: &(&types.S{S: "name"}).S}}
@@ -115,6 +303,23 @@ func TestNewInstanceMissingName(t *testing.T) {
 
 	assert.Equal(t, "ID", instance.Name)
 }
+func TestNewInstanceStoppedAt(t *testing.T) {
+	ec2Instance := newTestInstance()
+	ec2Instance.State = &ec2.InstanceState{Code: &(&types.I64{I: 80}).I}
+	ec2Instance.StateTransitionReason = &(&types.S{S: "User initiated (2019-09-19 08:39:41 GMT)"}).S
+
+	instance := newInstance(types.AWS, ec2Instance)
+
+	assert.Equal(t, types.Stopped, instance.State)
+	assert.Equal(t, time.Date(2019, 9, 19, 8, 39, 41, 0, time.UTC), *instance.StoppedAt)
+
+	ec2Instance.StateTransitionReason = &(&types.S{S: "Server.ScheduledStop: Stopped due to scheduled retirement"}).S
+	assert.Nil(t, newInstance(types.AWS, ec2Instance).StoppedAt)
+	ec2Instance.State = &ec2.InstanceState{Code: &(&types.I64{I: 16}).I}
+	ec2Instance.StateTransitionReason = &(&types.S{S: "User initiated (2019-09-19 08:39:41 GMT)"}).S
+	assert.Nil(t, newInstance(types.AWS, ec2Instance).StoppedAt)
+}
+
 func TestGetTags(t *testing.T) {
 	assert.Equal(t, types.Tags{"k": "v"}, getEc2Tags([]*ec2.Tag{{Key: &(&types.S{S: "k"}).S, Value: &(&types.S{S: "v"}).S}}))
 }
@@ -467,6 +672,16 @@ func (t mockEc2Client) DescribeAddresses(input *ec2.DescribeAddressesInput) (*ec
 	}, nil
 }
 
+func (t mockEc2Client) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	t.operationChannel <- "CreateTags:" + strings.Join(aws.StringValueSlice(input.Resources), ",")
+	return nil, nil
+}
+
+func (t mockEc2Client) DeleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	t.operationChannel <- "DeleteTags:" + strings.Join(aws.StringValueSlice(input.Resources), ",")
+	return nil, nil
+}
+
 func (t mockEc2Client) ReleaseAddress(input *ec2.ReleaseAddressInput) (*ec2.ReleaseAddressOutput, error) {
 	t.operationChannel <- "ReleaseAddress:" + aws.StringValue(input.AllocationId)
 	return nil, nil
@@ -611,6 +826,16 @@ func (t mockRdsClient) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.
 	return nil, nil
 }
 
+func (t mockRdsClient) AddTagsToResource(input *rds.AddTagsToResourceInput) (*rds.AddTagsToResourceOutput, error) {
+	t.operationChannel <- "AddTagsToResource:" + *input.ResourceName
+	return nil, nil
+}
+
+func (t mockRdsClient) RemoveTagsFromResource(input *rds.RemoveTagsFromResourceInput) (*rds.RemoveTagsFromResourceOutput, error) {
+	t.operationChannel <- "RemoveTagsFromResource:" + *input.ResourceName
+	return nil, nil
+}
+
 type mockElbClient struct {
 	operationChannel chan (string)
 }
@@ -674,6 +899,106 @@ func (t mockCwClient) DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudw
 	return nil, nil
 }
 
+func (t mockCwClient) GetMetricData(input *cloudwatch.GetMetricDataInput) (*cloudwatch.GetMetricDataOutput, error) {
+	t.operationChannel <- fmt.Sprintf("GetMetricData:%d", len(input.MetricDataQueries))
+	var results []*cloudwatch.MetricDataResult
+	for _, query := range input.MetricDataQueries {
+		var values []float64
+		switch *query.MetricStat.Metric.MetricName {
+		case "CPUUtilization":
+			if *query.MetricStat.Metric.Dimensions[0].Value != "i-nodata" {
+				values = []float64{2, 4}
+			}
+		case "NetworkIn", "NetworkOut":
+			values = []float64{3600, 7200}
+		default:
+			values = []float64{10}
+		}
+		results = append(results, &cloudwatch.MetricDataResult{Id: query.Id, Values: aws.Float64Slice(values)})
+	}
+	return &cloudwatch.GetMetricDataOutput{MetricDataResults: results}, nil
+}
+
+type mockS3Client struct {
+	operationChannel chan (string)
+}
+
+func (t mockS3Client) ListBuckets(*s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{
+			{Name: aws.String("bucket-1"), CreationDate: &NOW},
+			{Name: aws.String("bucket-2"), CreationDate: &NOW},
+			{Name: aws.String("bucket-3"), CreationDate: &NOW},
+		},
+	}, nil
+}
+
+func (t mockS3Client) GetBucketLocation(input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	switch *input.Bucket {
+	case "bucket-1":
+		return &s3.GetBucketLocationOutput{}, nil
+	case "bucket-2":
+		return &s3.GetBucketLocationOutput{LocationConstraint: aws.String("EU")}, nil
+	}
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String("ap-south-1")}, nil
+}
+
+func (t mockS3Client) GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	if *input.Bucket == "bucket-1" {
+		return &s3.GetBucketTaggingOutput{TagSet: []*s3.Tag{{Key: aws.String(ctx.OwnerLabel), Value: aws.String(OWNER)}}}, nil
+	}
+	return nil, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+}
+
+func (t mockS3Client) GetBucketVersioning(input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	switch *input.Bucket {
+	case "bucket-2":
+		return &s3.GetBucketVersioningOutput{Status: aws.String(s3.BucketVersioningStatusEnabled)}, nil
+	case "bucket-4":
+		return nil, awserr.New("AccessDenied", "Access Denied", nil)
+	}
+	return &s3.GetBucketVersioningOutput{}, nil
+}
+
+func (t mockS3Client) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	t.operationChannel <- "ListObjectsV2:" + *input.Bucket
+	old := NOW.AddDate(0, 0, -10)
+	for page := 0; page < 2; page++ {
+		var objects []*s3.Object
+		for i := 0; i < 1000; i++ {
+			lastModified := &NOW
+			if i%2 == 0 || page == 0 {
+				lastModified = &old
+			}
+			objects = append(objects, &s3.Object{Key: aws.String(fmt.Sprintf("key-%d-%d", page, i)), LastModified: lastModified, Size: aws.Int64(1024)})
+		}
+		if !fn(&s3.ListObjectsV2Output{Contents: objects}, page == 1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (t mockS3Client) ListObjectVersionsPages(input *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool) error {
+	t.operationChannel <- "ListObjectVersions:" + *input.Bucket
+	old := NOW.AddDate(0, 0, -10)
+	fn(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			{Key: aws.String("key"), VersionId: aws.String("v2"), LastModified: &NOW, Size: aws.Int64(1024)},
+			{Key: aws.String("key"), VersionId: aws.String("v1"), LastModified: &old, Size: aws.Int64(1024)},
+		},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{
+			{Key: aws.String("deleted"), VersionId: aws.String("v1"), LastModified: &old},
+		},
+	}, true)
+	return nil
+}
+
+func (t mockS3Client) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	t.operationChannel <- fmt.Sprintf("DeleteObjects:%s:%d", *input.Bucket, len(input.Delete.Objects))
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
 func getResourceGroupedElbTags() []*elb.Tag {
 	return []*elb.Tag{
 		{
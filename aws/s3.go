@@ -0,0 +1,292 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	METADATA_VERSIONING = "versioning"
+
+	// the versioning of the bucket could not be fetched
+	VERSIONING_UNKNOWN = "Unknown"
+
+	// DeleteObjects accepts at most 1000 keys
+	MAX_DELETE_OBJECTS = 1000
+
+	// the region of the buckets created in us-east-1 is empty, the ones created in eu-west-1 long ago can be EU
+	defaultBucketRegion = "us-east-1"
+)
+
+type s3Client interface {
+	ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
+	GetBucketLocation(input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error)
+	GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error)
+	GetBucketVersioning(input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error)
+	ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error
+	ListObjectVersionsPages(input *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool) error
+	DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error)
+}
+
+// s3Object is an object or an object version of a bucket, the version is empty if the bucket is not versioned
+type s3Object struct {
+	key       string
+	versionID *string
+	size      int64
+}
+
+// The buckets are global, they are listed with the client of any region, but the details are fetched from the region of the bucket.
+// The buckets of the regions without a client are skipped.
+func getStorages(cloudType types.CloudType, s3Clients map[string]s3Client) ([]*types.Storage, error) {
+	if len(s3Clients) == 0 {
+		return nil, nil
+	}
+	listClient, ok := s3Clients[defaultBucketRegion]
+	if !ok {
+		var regions []string
+		for r := range s3Clients {
+			regions = append(regions, r)
+		}
+		sort.Strings(regions)
+		listClient = s3Clients[regions[0]]
+	}
+	result, err := listClient.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		log.Errorf("[AWS] Failed to list the buckets, err: %s", err)
+		return nil, err
+	}
+	log.Debugf("[AWS] Processing buckets (%d)", len(result.Buckets))
+
+	var storages []*types.Storage
+	for _, bucket := range result.Buckets {
+		location, err := listClient.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: bucket.Name})
+		if err != nil {
+			log.Warnf("[AWS] Failed to get the location of bucket %s, err: %s", *bucket.Name, err)
+			continue
+		}
+		region := getBucketRegion(location.LocationConstraint)
+		client, ok := s3Clients[region]
+		if !ok {
+			log.Debugf("[AWS] Skipping bucket %s in region %s, because the region is not enabled", *bucket.Name, region)
+			continue
+		}
+		versioning, err := getBucketVersioning(client, *bucket.Name)
+		if err != nil {
+			log.Warnf("[AWS] Failed to get the versioning of bucket %s, err: %s", *bucket.Name, err)
+			versioning = VERSIONING_UNKNOWN
+		}
+		storages = append(storages, newStorage(cloudType, bucket, region, getBucketTags(client, *bucket.Name), versioning))
+	}
+	return storages, nil
+}
+
+func getBucketRegion(locationConstraint *string) string {
+	switch region := aws.StringValue(locationConstraint); region {
+	case "":
+		return defaultBucketRegion
+	case "EU":
+		return "eu-west-1"
+	default:
+		return region
+	}
+}
+
+// A bucket without tags is reported with an error by the API
+func getBucketTags(client s3Client, bucket string) types.Tags {
+	tags := make(types.Tags, 0)
+	result, err := client.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: &bucket})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "NoSuchTagSet" {
+			log.Warnf("[AWS] Failed to get the tags of bucket %s, err: %s", bucket, err)
+		}
+		return tags
+	}
+	for _, t := range result.TagSet {
+		tags[*t.Key] = *t.Value
+	}
+	return tags
+}
+
+// The versioning of a bucket is empty if it was never enabled, otherwise Enabled or Suspended
+func getBucketVersioning(client s3Client, bucket string) (string, error) {
+	result, err := client.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: &bucket})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(result.Status), nil
+}
+
+func newStorage(cloudType types.CloudType, bucket *s3.Bucket, region string, tags types.Tags, versioning string) *types.Storage {
+	return &types.Storage{
+		ID:        *bucket.Name,
+		Name:      *bucket.Name,
+		Owner:     tags[ctx.OwnerLabel],
+		Created:   getCreated(bucket.CreationDate),
+		CloudType: cloudType,
+		Tags:      tags,
+		Region:    region,
+		MetaData: map[string]string{
+			METADATA_VERSIONING: versioning,
+		},
+	}
+}
+
+// cleanupStorages deletes the objects older than the retention. Every version of the versioned buckets is checked, so the older
+// versions and delete markers are removed too, not only the current versions. The versioning is fetched again instead of relying on
+// the metadata of the storage, because deleting the objects of a versioned bucket without their versions would only add delete markers.
+func cleanupStorages(s3Clients map[string]s3Client, storages []*types.Storage, retentionDays int) []error {
+	retentionTime := time.Now().AddDate(0, 0, -retentionDays)
+	log.Debugf("[AWS] Cleaning up buckets older than %s", retentionTime)
+
+	storagesByRegion := map[string][]*types.Storage{}
+	for _, storage := range storages {
+		storagesByRegion[storage.Region] = append(storagesByRegion[storage.Region], storage)
+	}
+
+	errChan := make(chan error)
+	wg := sync.WaitGroup{}
+	wg.Add(len(storagesByRegion))
+
+	for r, s := range storagesByRegion {
+		go func(region string, storagesInRegion []*types.Storage) {
+			defer wg.Done()
+
+			client, ok := s3Clients[region]
+			if !ok {
+				for _, storage := range storagesInRegion {
+					errChan <- types.NewResourceError(storage.ID, region, fmt.Errorf("region %s is not enabled", region))
+				}
+				return
+			}
+			for _, storage := range storagesInRegion {
+				for _, err := range cleanupStorage(client, storage, retentionTime) {
+					errChan <- types.NewResourceError(storage.ID, region, err)
+				}
+			}
+		}(r, s)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// The expired objects are deleted page by page as they are listed, so the objects of a large bucket are not kept in memory
+func cleanupStorage(client s3Client, storage *types.Storage, retentionTime time.Time) []error {
+	versioning, err := getBucketVersioning(client, storage.Name)
+	if err != nil {
+		log.Errorf("[AWS] Failed to get the versioning of bucket %s, its objects are not cleaned up, err: %s", storage.Name, err)
+		return []error{err}
+	}
+	var expiredObjects int
+	var cleanedUpStorage int64
+	var errs []error
+	err = listExpiredObjects(client, storage.Name, len(versioning) != 0, retentionTime, func(objects []s3Object) {
+		expiredObjects += len(objects)
+		if ctx.DryRun || len(objects) == 0 {
+			return
+		}
+		cleanedUp, deleteErrs := deleteObjects(client, storage, objects)
+		cleanedUpStorage += cleanedUp
+		errs = append(errs, deleteErrs...)
+	})
+	if err != nil {
+		log.Errorf("[AWS] Failed to list the objects of bucket %s, err: %s", storage.Name, err)
+		errs = append(errs, err)
+	}
+	log.Infof("[AWS] Bucket %s has %d objects older than %s", storage.Name, expiredObjects, retentionTime)
+	if ctx.DryRun {
+		log.Infof("[AWS] Dry-run set, objects of bucket %s were not deleted", storage.Name)
+	} else {
+		log.Infof("[AWS] Cleaned up %s worth of objects in bucket %s", utils.GetHumanReadableFileSize(cleanedUpStorage), storage.Name)
+	}
+	return errs
+}
+
+// listExpiredObjects passes the expired objects of each listed page to the handler, a page has at most 1000 objects
+func listExpiredObjects(client s3Client, bucket string, versioned bool, retentionTime time.Time, handle func([]s3Object)) error {
+	if !versioned {
+		return client.ListObjectsV2Pages(&s3.ListObjectsV2Input{Bucket: &bucket}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+			var objects []s3Object
+			for _, o := range page.Contents {
+				if o.LastModified.Before(retentionTime) {
+					objects = append(objects, s3Object{key: *o.Key, size: aws.Int64Value(o.Size)})
+				}
+			}
+			handle(objects)
+			return true
+		})
+	}
+	return client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{Bucket: &bucket}, func(page *s3.ListObjectVersionsOutput, _ bool) bool {
+		var objects []s3Object
+		for _, v := range page.Versions {
+			if v.LastModified.Before(retentionTime) {
+				objects = append(objects, s3Object{key: *v.Key, versionID: v.VersionId, size: aws.Int64Value(v.Size)})
+			}
+		}
+		for _, m := range page.DeleteMarkers {
+			if m.LastModified.Before(retentionTime) {
+				objects = append(objects, s3Object{key: *m.Key, versionID: m.VersionId})
+			}
+		}
+		handle(objects)
+		return true
+	})
+}
+
+// The objects are deleted in batches, the size of the objects that could not be deleted is not counted
+func deleteObjects(client s3Client, storage *types.Storage, objects []s3Object) (int64, []error) {
+	var cleanedUpStorage int64
+	var errs []error
+	for i := 0; i < len(objects); i += MAX_DELETE_OBJECTS {
+		endIndex := i + MAX_DELETE_OBJECTS
+		if endIndex > len(objects) {
+			endIndex = len(objects)
+		}
+		batch := objects[i:endIndex]
+		var identifiers []*s3.ObjectIdentifier
+		for _, o := range batch {
+			identifiers = append(identifiers, &s3.ObjectIdentifier{Key: aws.String(o.key), VersionId: o.versionID})
+		}
+		result, err := client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: &storage.Name,
+			Delete: &s3.Delete{Objects: identifiers, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			log.Errorf("[AWS] Failed to delete %d objects of bucket %s, err: %s", len(batch), storage.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		failed := map[string]bool{}
+		for _, e := range result.Errors {
+			log.Errorf("[AWS] Failed to delete object %s of bucket %s, err: %s", aws.StringValue(e.Key), storage.Name, aws.StringValue(e.Message))
+			failed[aws.StringValue(e.Key)+"/"+aws.StringValue(e.VersionId)] = true
+		}
+		if len(result.Errors) != 0 {
+			errs = append(errs, fmt.Errorf("failed to delete %d objects of bucket %s", len(result.Errors), storage.Name))
+		}
+		for _, o := range batch {
+			if !failed[o.key+"/"+aws.StringValue(o.versionID)] {
+				cleanedUpStorage += o.size
+			}
+		}
+	}
+	return cleanedUpStorage, errs
+}
@@ -4,15 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/monitor/armmonitor"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/postgresql/armpostgresqlflexibleservers/v4"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2015-11-01/subscriptions"
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2021-04-01/storage"
 
+	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,10 +27,12 @@ import (
 
 	"sync"
 
+	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -35,13 +44,21 @@ const (
 
 var provider = azureProvider{}
 
+var errShuttingDown = errors.New("skipped, because the process is shutting down")
+
 type azureProvider struct {
 	subscriptionID         string
 	vmClient               *armcompute.VirtualMachinesClient
 	vmScaleSetClient       *armcompute.VirtualMachineScaleSetsClient
 	vmScaleSetVMClient     *armcompute.VirtualMachineScaleSetVMsClient
 	imageClient            *armcompute.ImagesClient
+	diskClient             *armcompute.DisksClient
 	rgClient               *armresources.ResourceGroupsClient
+	resourcesClient        *armresources.Client
+	metricAlertsClient     *armmonitor.MetricAlertsClient
+	activityLogAlertClient *armmonitor.ActivityLogAlertsClient
+	tagsClient             *armresources.TagsClient
+	metricsClient          *armmonitor.MetricsClient
 	dbClient               *armpostgresqlflexibleservers.ServersClient
 	subscriptionClient     subscriptions.Client
 	storageAccountClient   storage.AccountsClient
@@ -71,7 +88,21 @@ func (p *azureProvider) init(subscriptionID string,
 	credentialProvider func(*azidentity.EnvironmentCredentialOptions) (*azidentity.EnvironmentCredential, error),
 	authorizer func() (autorest.Authorizer, error)) error {
 
-	authorization, err := authorizer()
+	var authorization autorest.Authorizer = autorest.NullAuthorizer{}
+	var credential azcore.TokenCredential = replayCredential{}
+	options := getClientOptions()
+	var err error
+	if !recording.IsReplaying() {
+		authorization, err = authorizer()
+		var credentialOptions *azidentity.EnvironmentCredentialOptions
+		if options != nil {
+			credentialOptions = &azidentity.EnvironmentCredentialOptions{ClientOptions: options.ClientOptions}
+		}
+		if credential, err = credentialProvider(credentialOptions); err != nil {
+			log.Error("[AZURE] Failed to initialize credential: " + err.Error())
+			return err
+		}
+	}
 
 	p.subscriptionID = subscriptionID
 	p.subscriptionClient = subscriptions.NewClient()
@@ -80,33 +111,80 @@ func (p *azureProvider) init(subscriptionID string,
 	p.storageAccountClient.Authorizer = authorization
 	p.storageContainerClient = storage.NewBlobContainersClient(subscriptionID)
 	p.storageContainerClient.Authorizer = authorization
+	if recording.IsEnabled() {
+		sender := &http.Client{Transport: recording.WrapTransport(nil)}
+		p.subscriptionClient.Sender = sender
+		p.storageAccountClient.Sender = sender
+		p.storageContainerClient.Sender = sender
+	}
 
-	credential, err := credentialProvider(nil)
-	if err != nil {
-		log.Error("[AZURE] Failed to initialize credential: " + err.Error())
+	if p.vmClient, err = armcompute.NewVirtualMachinesClient(subscriptionID, credential, options); err != nil {
+		return err
+	}
+	if p.vmScaleSetClient, err = armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, credential, options); err != nil {
+		return err
+	}
+	if p.vmScaleSetVMClient, err = armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, credential, options); err != nil {
+		return err
+	}
+	if p.imageClient, err = armcompute.NewImagesClient(subscriptionID, credential, options); err != nil {
+		return err
+	}
+	if p.diskClient, err = armcompute.NewDisksClient(subscriptionID, credential, options); err != nil {
+		return err
+	}
+	if p.rgClient, err = armresources.NewResourceGroupsClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.vmClient, err = armcompute.NewVirtualMachinesClient(subscriptionID, credential, nil); err != nil {
+	if p.dbClient, err = armpostgresqlflexibleservers.NewServersClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.vmScaleSetClient, err = armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, credential, nil); err != nil {
+	if p.tagsClient, err = armresources.NewTagsClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.vmScaleSetVMClient, err = armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, credential, nil); err != nil {
+	if p.metricsClient, err = armmonitor.NewMetricsClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.imageClient, err = armcompute.NewImagesClient(subscriptionID, credential, nil); err != nil {
+	if p.resourcesClient, err = armresources.NewClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.rgClient, err = armresources.NewResourceGroupsClient(subscriptionID, credential, nil); err != nil {
+	if p.metricAlertsClient, err = armmonitor.NewMetricAlertsClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
-	if p.dbClient, err = armpostgresqlflexibleservers.NewServersClient(subscriptionID, credential, nil); err != nil {
+	if p.activityLogAlertClient, err = armmonitor.NewActivityLogAlertsClient(subscriptionID, credential, options); err != nil {
 		return err
 	}
 	return nil
 }
 
+// getClientOptions routes the API calls through the recorder or the replayer, the default options are used if none of them is enabled
+func getClientOptions() *arm.ClientOptions {
+	if !recording.IsEnabled() {
+		return nil
+	}
+	return &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: &http.Client{Transport: recording.WrapTransport(nil)}}}
+}
+
+func getBlobSender() pipeline.Factory {
+	if !recording.IsEnabled() {
+		return nil
+	}
+	client := &http.Client{Transport: recording.WrapTransport(nil)}
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(requestContext context.Context, request pipeline.Request) (pipeline.Response, error) {
+			response, err := client.Do(request.WithContext(requestContext))
+			return pipeline.NewHTTPResponse(response), err
+		}
+	})
+}
+
+// replayCredential is used instead of the environment credential when the recorded responses are replayed
+type replayCredential struct{}
+
+func (replayCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: recording.Redacted, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
 func (p azureProvider) GetAccountName() string {
 	if result, err := p.subscriptionClient.Get(context.Background(), p.subscriptionID); err != nil {
 		log.Errorf("[AZURE] Failed to retrieve subscription info, err: %s", err.Error())
@@ -250,11 +328,13 @@ type azureScaleSetInstance struct {
 }
 
 func (p azureProvider) GetDisks() ([]*types.Disk, error) {
-	return nil, errors.New("[AZURE] Disk operations are not supported")
+	log.Debug("[AZURE] Fetching disks")
+	return getDisks(p.diskClient)
 }
 
-func (p azureProvider) DeleteDisks(*types.DiskContainer) []error {
-	return []error{errors.New("[AZURE] Disk deletion is not supported")}
+func (p azureProvider) DeleteDisks(disks *types.DiskContainer) []error {
+	log.Debug("[AZURE] Delete disks")
+	return deleteDisks(p.diskClient, disks.Get(types.AZURE))
 }
 
 func (p azureProvider) GetImages() ([]*types.Image, error) {
@@ -324,14 +404,17 @@ func (p azureProvider) TerminateInstances(instanceContainer *types.InstanceConta
 				<-sem
 			}()
 
-			if ctx.DryRun {
+			if ctx.IsShuttingDown() {
+				log.Warnf("[AZURE] Shutting down, instance is not terminated: %s", instance.Name)
+				errChan <- types.NewResourceError(instance.ID, instance.Region, errShuttingDown)
+			} else if ctx.DryRun {
 				log.Infof("[AZURE] Dry-run set, instance is not terminated: %s", instance.Name)
 			} else {
 				log.Infof("[AZURE] Terminating instance %s", instance.Name)
 				_, err := p.vmClient.BeginDelete(context.Background(), instance.Metadata[ResourceGroupName], instance.Name, nil)
 				if err != nil {
 					log.Errorf("[AZURE] Failed to terminate instance %s, err: %s", instance.Name, err.Error())
-					errChan <- err
+					errChan <- types.NewResourceError(instance.ID, instance.Region, err)
 				} else {
 					log.Debugf("[AZURE] Instance stopped: %s", instance.Name)
 				}
@@ -369,7 +452,7 @@ func (p azureProvider) TerminateStacks(stacks *types.StackContainer) []error {
 				if strings.Contains(err.Error(), "ScopeLocked") {
 					log.Warnf("[AZURE] Resource group %s has a resource lock, so it can not be deleted", rgName)
 				} else {
-					errs = append(errs, err)
+					errs = append(errs, types.NewResourceError(rg.ID, rg.Region, err))
 				}
 			}
 		}
@@ -394,6 +477,11 @@ func (p azureProvider) StopInstances(instances *types.InstanceContainer) []error
 				<-sem
 			}()
 
+			if ctx.IsShuttingDown() {
+				log.Warnf("[AZURE] Shutting down, instance is not stopped: %s", instance.Name)
+				errChan <- types.NewResourceError(instance.ID, instance.Region, errShuttingDown)
+				return
+			}
 			log.Debugf("[AZURE] Stopping instance: %s", instance.Name)
 			var err error
 			if _, ok := instance.Metadata[ScaleSetName]; ok {
@@ -402,7 +490,7 @@ func (p azureProvider) StopInstances(instances *types.InstanceContainer) []error
 				_, err = p.vmClient.BeginDeallocate(context.Background(), instance.Metadata[ResourceGroupName], instance.Name, nil)
 			}
 			if err != nil {
-				errChan <- err
+				errChan <- types.NewResourceError(instance.ID, instance.Region, err)
 			} else {
 				log.Debugf("[AZURE] Instance stopped: %s", instance.Name)
 			}
@@ -432,7 +520,7 @@ func (p azureProvider) StopDatabases(databases *types.DatabaseContainer) (errs [
 			_, err := p.dbClient.BeginStop(context.Background(), database.Metadata[ResourceGroupName], database.Name, nil)
 			if err != nil {
 				log.Errorf("[AZURE] Failed to stop database: %s", database.Name)
-				errs = append(errs, err)
+				errs = append(errs, types.NewResourceError(database.ID, database.Region, err))
 				continue
 			}
 		}
@@ -481,11 +569,13 @@ func (p azureProvider) GetDatabases() ([]*types.Database, error) {
 }
 
 func (p azureProvider) GetAlerts() ([]*types.Alert, error) {
-	return nil, errors.New("[AZURE] Getting alerts is not supported yet")
+	log.Debug("[AZURE] Fetching alerts")
+	return getAlerts(p.subscriptionID, p.metricAlertsClient, p.activityLogAlertClient, p.resourcesClient, p.rgClient)
 }
 
-func (p azureProvider) DeleteAlerts(*types.AlertContainer) []error {
-	return []error{errors.New("[AZURE] Deleting alerts is not supported yet")}
+func (p azureProvider) DeleteAlerts(alerts *types.AlertContainer) []error {
+	log.Debug("[AZURE] Delete alerts")
+	return deleteAlerts(p.metricAlertsClient, p.activityLogAlertClient, alerts.Get(types.AZURE))
 }
 
 func (p azureProvider) GetStorages() ([]*types.Storage, error) {
@@ -564,7 +654,7 @@ func (p azureProvider) getServiceUrl(storage types.Storage) (*azblob.ServiceURL,
 		log.Errorf("[AZURE] Failed to create url for storage account %s, err: %s", storage.Name, err)
 		return nil, err
 	}
-	serviceUrl := azblob.NewServiceURL(*url, azblob.NewPipeline(credential, azblob.PipelineOptions{}))
+	serviceUrl := azblob.NewServiceURL(*url, azblob.NewPipeline(credential, azblob.PipelineOptions{HTTPSender: getBlobSender()}))
 	log.Debugf("[AZURE] Service url for storage account %s is %s", storage.Name, serviceUrl.String())
 	return &serviceUrl, nil
 }
@@ -609,14 +699,14 @@ func (p azureProvider) CleanupStorages(storageContainer *types.StorageContainer,
 				containerUrls, err := p.getContainerUrls(*storage)
 				if err != nil {
 					log.Errorf("[AZURE] Failed to get containers for storage account %s, err: %s", storage.Name, err)
-					errorChan <- err
+					errorChan <- types.NewResourceError(storage.ID, region, err)
 					continue
 				}
 				for _, containerUrl := range *containerUrls {
 					blobs, err := p.getBlobsInContainer(*storage, containerUrl)
 					if err != nil {
 						log.Errorf("[AZURE] Failed to list blobs in container %s , err: %s", containerUrl.String(), err)
-						errorChan <- err
+						errorChan <- types.NewResourceError(storage.ID, region, err)
 						continue
 					}
 					for _, blob := range *blobs {
@@ -626,7 +716,7 @@ func (p azureProvider) CleanupStorages(storageContainer *types.StorageContainer,
 								_, err := containerUrl.NewBlobURL(blob.Name).Delete(context.Background(), azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
 								if err != nil {
 									log.Errorf("[AZURE] Failed to delete blob %s in storage account %s", blob.Name, storage.Name)
-									errorChan <- err
+									errorChan <- types.NewResourceError(storage.ID, region, err)
 								} else {
 									cleanedUpStorage += *blob.Properties.ContentLength
 								}
@@ -652,6 +742,514 @@ func (p azureProvider) CleanupStorages(storageContainer *types.StorageContainer,
 	return errs
 }
 
+func (p azureProvider) AddTags(items []types.CloudItem, tags types.Tags) []error {
+	log.Debugf("[AZURE] Add tags: %v", tags)
+	return updateTags(p.tagsClient, items, armresources.TagsPatchOperationMerge, func(types.CloudItem) types.Tags {
+		return tags
+	})
+}
+
+func (p azureProvider) RemoveTags(items []types.CloudItem, keys []string) []error {
+	log.Debugf("[AZURE] Remove tags: %v", keys)
+	// tags are deleted by name and value, so the current values are needed
+	return updateTags(p.tagsClient, items, armresources.TagsPatchOperationDelete, func(item types.CloudItem) types.Tags {
+		tags := types.Tags{}
+		for _, k := range keys {
+			if v, ok := item.GetTags()[k]; ok {
+				tags[k] = v
+			}
+		}
+		return tags
+	})
+}
+
+func (p azureProvider) GetUtilizations(items []types.CloudItem, period time.Duration) (map[string]types.Utilization, []error) {
+	log.Debugf("[AZURE] Fetching utilization of %d items for the last %s", len(items), period)
+	return getUtilizations(p.metricsClient, items, period, time.Now())
+}
+
+type disksClient interface {
+	NewListPager(options *armcompute.DisksClientListOptions) *runtime.Pager[armcompute.DisksClientListResponse]
+	BeginDelete(ctx context.Context, resourceGroupName string, diskName string, options *armcompute.DisksClientBeginDeleteOptions) (*runtime.Poller[armcompute.DisksClientDeleteResponse], error)
+}
+
+func getDisks(disksClient disksClient) ([]*types.Disk, error) {
+	var disks []*types.Disk
+	pager := disksClient.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			log.Errorf("[AZURE] Failed to fetch the disks, err: %s", err.Error())
+			return nil, err
+		}
+		for _, disk := range page.Value {
+			disks = append(disks, newDisk(*disk))
+		}
+	}
+	return disks, nil
+}
+
+// The deletion of a disk is a long-running operation, it is polled until the disk is gone. Only a few disks are deleted at the
+// same time, so the deletions that have not started yet are skipped on shutdown.
+func deleteDisks(disksClient disksClient, disks []*types.Disk) []error {
+	wg := sync.WaitGroup{}
+	wg.Add(len(disks))
+	errorChan := make(chan error)
+	sem := make(chan bool, 5)
+
+	for _, d := range disks {
+		go func(disk *types.Disk) {
+			sem <- true
+			defer func() {
+				wg.Done()
+				<-sem
+			}()
+
+			resourceGroup, name := getResourceGroupName(disk.ID)
+			if ctx.IsShuttingDown() {
+				log.Warnf("[AZURE] Shutting down, disk is not deleted: %s", disk.ID)
+				errorChan <- types.NewResourceError(disk.ID, disk.Region, errShuttingDown)
+				return
+			}
+			if ctx.DryRun {
+				log.Infof("[AZURE] Dry-run set, disk is not deleted: %s, resource group: %s, region: %s", name, resourceGroup, disk.Region)
+				return
+			}
+			log.Infof("[AZURE] Delete disk: %s", disk.ID)
+			poller, err := disksClient.BeginDelete(context.Background(), resourceGroup, name, nil)
+			if err == nil {
+				_, err = poller.PollUntilDone(context.Background(), nil)
+			}
+			if err != nil {
+				log.Errorf("[AZURE] Unable to delete disk: %s because: %s", disk.ID, err.Error())
+				errorChan <- types.NewResourceError(disk.ID, disk.Region, err)
+			}
+		}(d)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errorChan)
+	}()
+
+	var errs []error
+	for err := range errorChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+type metricAlertsClient interface {
+	NewListBySubscriptionPager(options *armmonitor.MetricAlertsClientListBySubscriptionOptions) *runtime.Pager[armmonitor.MetricAlertsClientListBySubscriptionResponse]
+	Delete(ctx context.Context, resourceGroupName string, ruleName string, options *armmonitor.MetricAlertsClientDeleteOptions) (armmonitor.MetricAlertsClientDeleteResponse, error)
+}
+
+type activityLogAlertsClient interface {
+	NewListBySubscriptionIDPager(options *armmonitor.ActivityLogAlertsClientListBySubscriptionIDOptions) *runtime.Pager[armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse]
+	Delete(ctx context.Context, resourceGroupName string, activityLogAlertName string, options *armmonitor.ActivityLogAlertsClientDeleteOptions) (armmonitor.ActivityLogAlertsClientDeleteResponse, error)
+}
+
+type resourcesClient interface {
+	NewListPager(options *armresources.ClientListOptions) *runtime.Pager[armresources.ClientListResponse]
+}
+
+type resourceGroupsClient interface {
+	NewListPager(options *armresources.ResourceGroupsClientListOptions) *runtime.Pager[armresources.ResourceGroupsClientListResponse]
+}
+
+const (
+	alertKindMetric      = "metric"
+	alertKindActivityLog = "activityLog"
+)
+
+// getAlerts lists the metric alert rules and the activity log alerts. An alert is unused if none of its target resources exist,
+// the existing resources and resource groups are listed once for all the alerts.
+func getAlerts(subscriptionID string, metricAlertsClient metricAlertsClient, activityLogAlertsClient activityLogAlertsClient,
+	resourcesClient resourcesClient, resourceGroupsClient resourceGroupsClient) ([]*types.Alert, error) {
+
+	var alerts []*types.Alert
+	metricAlerts := metricAlertsClient.NewListBySubscriptionPager(nil)
+	for metricAlerts.More() {
+		page, err := metricAlerts.NextPage(context.Background())
+		if err != nil {
+			log.Errorf("[AZURE] Failed to fetch the metric alerts, err: %s", err.Error())
+			return nil, err
+		}
+		for _, alert := range page.Value {
+			var scopes []*string
+			var updated *time.Time
+			if alert.Properties != nil {
+				scopes, updated = alert.Properties.Scopes, alert.Properties.LastUpdatedTime
+			}
+			alerts = append(alerts, newAlert(*alert.ID, *alert.Name, *alert.Location, alertKindMetric, alert.Tags, scopes, updated))
+		}
+	}
+	activityLogAlerts := activityLogAlertsClient.NewListBySubscriptionIDPager(nil)
+	for activityLogAlerts.More() {
+		page, err := activityLogAlerts.NextPage(context.Background())
+		if err != nil {
+			log.Errorf("[AZURE] Failed to fetch the activity log alerts, err: %s", err.Error())
+			return nil, err
+		}
+		for _, alert := range page.Value {
+			var scopes []*string
+			if alert.Properties != nil {
+				scopes = alert.Properties.Scopes
+			}
+			alerts = append(alerts, newAlert(*alert.ID, *alert.Name, *alert.Location, alertKindActivityLog, alert.Tags, scopes, nil))
+		}
+	}
+	log.Debugf("[AZURE] Processing alerts (%d)", len(alerts))
+	if len(alerts) == 0 {
+		return alerts, nil
+	}
+
+	existingIDs, err := getExistingResourceIDs(resourcesClient, resourceGroupsClient)
+	if err != nil {
+		log.Warnf("[AZURE] Failed to fetch the resources, the state of the alerts is unknown, err: %s", err.Error())
+		return alerts, nil
+	}
+	for _, alert := range alerts {
+		alert.State = getAlertState(subscriptionID, strings.Split(alert.Metadata["scopes"], ","), existingIDs)
+	}
+	return alerts, nil
+}
+
+func getExistingResourceIDs(resourcesClient resourcesClient, resourceGroupsClient resourceGroupsClient) (map[string]bool, error) {
+	existingIDs := map[string]bool{}
+	resources := resourcesClient.NewListPager(nil)
+	for resources.More() {
+		page, err := resources.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range page.Value {
+			existingIDs[strings.ToLower(*resource.ID)] = true
+		}
+	}
+	resourceGroups := resourceGroupsClient.NewListPager(nil)
+	for resourceGroups.More() {
+		page, err := resourceGroups.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, rg := range page.Value {
+			existingIDs[strings.ToLower(*rg.ID)] = true
+		}
+	}
+	return existingIDs, nil
+}
+
+// The child resources are not listed, so they are considered existing as long as their parent exists. The scopes of other
+// subscriptions cannot be checked, they are considered existing too.
+func getAlertState(subscriptionID string, scopes []string, existingIDs map[string]bool) types.State {
+	if len(scopes) == 0 || len(scopes[0]) == 0 {
+		return types.Unknown
+	}
+	for _, scope := range scopes {
+		parts := strings.Split(strings.Trim(strings.ToLower(scope), "/"), "/")
+		switch {
+		case len(parts) < 2 || parts[0] != "subscriptions" || parts[1] != strings.ToLower(subscriptionID):
+			return types.InUse
+		case len(parts) == 2:
+			return types.InUse
+		case len(parts) > 8:
+			parts = parts[:8]
+		}
+		if existingIDs["/"+strings.Join(parts, "/")] {
+			return types.InUse
+		}
+	}
+	return types.Unused
+}
+
+func newAlert(ID, name, location, kind string, tagMap map[string]*string, scopes []*string, updated *time.Time) *types.Alert {
+	tags := utils.ConvertTags(tagMap)
+	var scopeIDs []string
+	for _, scope := range scopes {
+		scopeIDs = append(scopeIDs, *scope)
+	}
+	resourceGroupName, _ := getResourceGroupName(ID)
+	alert := &types.Alert{
+		ID:        ID,
+		Name:      name,
+		Created:   getCreationTimeFromTags(tags, utils.ConvertTimeUnix),
+		CloudType: types.AZURE,
+		Region:    location,
+		Owner:     tags[ctx.OwnerLabel],
+		State:     types.Unknown,
+		Tags:      tags,
+		Metadata:  map[string]string{ResourceGroupName: resourceGroupName, "kind": kind, "scopes": strings.Join(scopeIDs, ",")},
+	}
+	// the time of the last update is not the creation time, the creation time is taken from the tags as for the other resources
+	if updated != nil {
+		alert.Metadata["lastUpdatedTime"] = updated.Format(time.RFC3339)
+	}
+	return alert
+}
+
+// The kind of the alert is decided by its resource ID, so the alerts read from a file can be deleted too
+func deleteAlerts(metricAlertsClient metricAlertsClient, activityLogAlertsClient activityLogAlertsClient, alerts []*types.Alert) []error {
+	log.Infof("[AZURE] Deleting %d alerts", len(alerts))
+	wg := sync.WaitGroup{}
+	wg.Add(len(alerts))
+	errorChan := make(chan error)
+
+	for _, a := range alerts {
+		go func(alert *types.Alert) {
+			defer wg.Done()
+
+			resourceGroup, name := getResourceGroupName(alert.ID)
+			if ctx.IsShuttingDown() {
+				log.Warnf("[AZURE] Shutting down, alert is not deleted: %s", alert.ID)
+				errorChan <- types.NewResourceError(alert.ID, alert.Region, errShuttingDown)
+				return
+			}
+			if ctx.DryRun {
+				log.Infof("[AZURE] Dry-run set, alert is not deleted: %s, resource group: %s", name, resourceGroup)
+				return
+			}
+			log.Infof("[AZURE] Delete alert: %s", alert.ID)
+			var err error
+			switch lowerID := strings.ToLower(alert.ID); {
+			case strings.Contains(lowerID, "/providers/microsoft.insights/metricalerts/"):
+				_, err = metricAlertsClient.Delete(context.Background(), resourceGroup, name, nil)
+			case strings.Contains(lowerID, "/providers/microsoft.insights/activitylogalerts/"):
+				_, err = activityLogAlertsClient.Delete(context.Background(), resourceGroup, name, nil)
+			default:
+				err = fmt.Errorf("unknown alert type: %s", alert.ID)
+			}
+			if err != nil {
+				log.Errorf("[AZURE] Unable to delete alert: %s because: %s", alert.ID, err.Error())
+				errorChan <- types.NewResourceError(alert.ID, alert.Region, err)
+			}
+		}(a)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errorChan)
+	}()
+
+	var errs []error
+	for err := range errorChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+type tagsClient interface {
+	UpdateAtScope(ctx context.Context, scope string, parameters armresources.TagsPatchResource, options *armresources.TagsClientUpdateAtScopeOptions) (armresources.TagsClientUpdateAtScopeResponse, error)
+}
+
+func updateTags(tagsClient tagsClient, items []types.CloudItem, operation armresources.TagsPatchOperation, getTags func(types.CloudItem) types.Tags) []error {
+	var errs []error
+	for _, item := range items {
+		if item.GetCloudType() != types.AZURE {
+			continue
+		}
+		tags := getTags(item)
+		if len(tags) == 0 {
+			continue
+		}
+		if ctx.DryRun {
+			log.Infof("[AZURE] Dry-run set, tags of %s are not updated: %s", item.GetType(), item.GetName())
+			continue
+		}
+		log.Infof("[AZURE] Update tags of %s: %s", item.GetType(), item.GetName())
+		azureTags := map[string]*string{}
+		for k, v := range tags {
+			azureTags[k] = &(&types.S{S: v}).S
+		}
+		_, err := tagsClient.UpdateAtScope(context.Background(), item.GetID(), armresources.TagsPatchResource{
+			Operation:  &operation,
+			Properties: &armresources.Tags{Tags: azureTags},
+		}, nil)
+		if err != nil {
+			log.Errorf("[AZURE] Failed to update tags of %s: %s, err: %s", item.GetType(), item.GetName(), err)
+			errs = append(errs, types.NewResourceError(item.GetID(), item.GetRegion(), err))
+		}
+	}
+	return errs
+}
+
+type metricsClient interface {
+	ListAtSubscriptionScope(ctx context.Context, region string, options *armmonitor.MetricsClientListAtSubscriptionScopeOptions) (armmonitor.MetricsClientListAtSubscriptionScopeResponse, error)
+}
+
+type utilizationMetrics struct {
+	cpu     string
+	network []string
+}
+
+// network metrics are totals per interval
+var utilizationMetricsByNamespace = map[string]utilizationMetrics{
+	"Microsoft.Compute/virtualMachines":         {cpu: "Percentage CPU", network: []string{"Network In Total", "Network Out Total"}},
+	"Microsoft.DBforPostgreSQL/flexibleServers": {cpu: "cpu_percent", network: []string{"network_bytes_ingress", "network_bytes_egress"}},
+}
+
+type metricsQuery struct {
+	region    string
+	namespace string
+}
+
+// the number of resource IDs in the filter of a metrics request
+const metricsBatchSize = 20
+
+// getUtilizations fetches the metrics of the virtual machines or databases of a region with subscription level requests filtered
+// by the resource IDs of the items. The filter is needed, because without it only the top series by value are returned, which
+// leaves out the idle resources. Scale set VMs are not reported by the virtual machine namespace, so their utilization is unknown.
+func getUtilizations(metricsClient metricsClient, items []types.CloudItem, period time.Duration, now time.Time) (map[string]types.Utilization, []error) {
+	queryItems := map[metricsQuery]map[string]string{}
+	for _, item := range items {
+		if item.GetCloudType() != types.AZURE {
+			continue
+		}
+		var query metricsQuery
+		switch item.GetItem().(type) {
+		case types.Instance:
+			query = metricsQuery{item.GetRegion(), "Microsoft.Compute/virtualMachines"}
+		case types.Database:
+			query = metricsQuery{item.GetRegion(), "Microsoft.DBforPostgreSQL/flexibleServers"}
+		default:
+			log.Debugf("[AZURE] Utilization of %s is not supported: %s", item.GetType(), item.GetName())
+			continue
+		}
+		if _, ok := queryItems[query]; !ok {
+			queryItems[query] = map[string]string{}
+		}
+		// the resource IDs of the metrics are not case sensitive
+		queryItems[query][strings.ToLower(item.GetID())] = item.GetID()
+	}
+
+	interval, intervalName := time.Hour, "PT1H"
+	if period < time.Hour {
+		interval, intervalName = time.Minute, "PT1M"
+	}
+	timespan := fmt.Sprintf("%s/%s", now.Add(-period).UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	aggregation := "Average,Total"
+
+	cpuValues := map[string][]float64{}
+	networkRates := map[string]float64{}
+	var errs []error
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for q, i := range queryItems {
+		for _, batch := range getMetricsBatches(i) {
+			wg.Add(1)
+			go func(query metricsQuery, itemIDs map[string]string) {
+				defer wg.Done()
+
+				metrics := utilizationMetricsByNamespace[query.namespace]
+				namespace := query.namespace
+				names := strings.Join(append([]string{metrics.cpu}, metrics.network...), ",")
+				var conditions []string
+				for lowerID := range itemIDs {
+					conditions = append(conditions, fmt.Sprintf("Microsoft.ResourceId eq '%s'", lowerID))
+				}
+				sort.Strings(conditions)
+				filter := strings.Join(conditions, " or ")
+				top := int32(len(itemIDs))
+				log.Debugf("[AZURE] Fetching metrics of %s (%d) in region: %s", query.namespace, len(itemIDs), query.region)
+				resp, err := metricsClient.ListAtSubscriptionScope(context.Background(), query.region, &armmonitor.MetricsClientListAtSubscriptionScopeOptions{
+					Metricnamespace: &namespace,
+					Metricnames:     &names,
+					Aggregation:     &aggregation,
+					Interval:        &intervalName,
+					Timespan:        &timespan,
+					Filter:          &filter,
+					Top:             &top,
+				})
+				if err != nil {
+					log.Errorf("[AZURE] Failed to fetch metrics of %s in region: %s, err: %s", query.namespace, query.region, err)
+					lock.Lock()
+					errs = append(errs, types.NewResourceError("", query.region, err))
+					lock.Unlock()
+					return
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+				for _, metric := range resp.Value {
+					if metric.Name == nil || metric.Name.Value == nil {
+						continue
+					}
+					isCPU := *metric.Name.Value == metrics.cpu
+					for _, series := range metric.Timeseries {
+						itemID, ok := itemIDs[strings.ToLower(getResourceIDOfSeries(series))]
+						if !ok {
+							continue
+						}
+						var values []float64
+						for _, data := range series.Data {
+							if isCPU && data.Average != nil {
+								values = append(values, *data.Average)
+							} else if !isCPU && data.Total != nil {
+								values = append(values, *data.Total/interval.Seconds())
+							}
+						}
+						if len(values) == 0 {
+							continue
+						}
+						if isCPU {
+							cpuValues[itemID] = append(cpuValues[itemID], values...)
+						} else {
+							networkRates[itemID] += average(values)
+						}
+					}
+				}
+			}(q, batch)
+		}
+	}
+	wg.Wait()
+
+	// an item without CPU data points is left out, because its utilization is unknown
+	utilizations := map[string]types.Utilization{}
+	for itemID, values := range cpuValues {
+		utilizations[itemID] = types.Utilization{CPU: average(values), Network: networkRates[itemID]}
+	}
+	return utilizations, errs
+}
+
+func getMetricsBatches(itemIDs map[string]string) []map[string]string {
+	var lowerIDs []string
+	for lowerID := range itemIDs {
+		lowerIDs = append(lowerIDs, lowerID)
+	}
+	sort.Strings(lowerIDs)
+	var batches []map[string]string
+	for i := 0; i < len(lowerIDs); i += metricsBatchSize {
+		endIndex := i + metricsBatchSize
+		if endIndex > len(lowerIDs) {
+			endIndex = len(lowerIDs)
+		}
+		batch := map[string]string{}
+		for _, lowerID := range lowerIDs[i:endIndex] {
+			batch[lowerID] = itemIDs[lowerID]
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func getResourceIDOfSeries(series *armmonitor.TimeSeriesElement) string {
+	for _, metadata := range series.Metadatavalues {
+		if metadata.Name != nil && metadata.Name.Value != nil && metadata.Value != nil && strings.EqualFold(*metadata.Name.Value, "Microsoft.ResourceId") {
+			return *metadata.Value
+		}
+	}
+	return ""
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
 func deleteImages(imagesClient imagesClient, imagesToDelete []azureImage, existingImages []armcompute.Image) []error {
 	wg := sync.WaitGroup{}
 	errorChan := make(chan error)
@@ -670,7 +1268,7 @@ func deleteImages(imagesClient imagesClient, imagesToDelete []azureImage, existi
 							_, err := imagesClient.BeginDelete(context.Background(), resourceGroup, *i.Name, nil)
 							if err != nil {
 								log.Errorf("[AZURE] Unable to delete image: %s because: %s", ai.ID, err.Error())
-								errorChan <- errors.New(ai.ID)
+								errorChan <- types.NewResourceError(ai.ID, ai.Region, err)
 							}
 						}
 					}(image, imageToDelete)
@@ -722,12 +1320,15 @@ func getScaleSetVMInstanceID(name string) string {
 func newInstanceByVM(inst azureInstance) *types.Instance {
 	vm := inst.instance
 	view := inst.instanceView
-	return newInstance(*vm.Name, *vm.ID, *vm.Location, string(*vm.Properties.HardwareProfile.VMSize), inst.resourceGroupName, getInstanceState(view.Statuses), vm.Tags)
+	instance := newInstance(*vm.Name, *vm.ID, *vm.Location, string(*vm.Properties.HardwareProfile.VMSize), inst.resourceGroupName, getInstanceState(view.Statuses), vm.Tags)
+	instance.StoppedAt = getStoppedAt(instance.State, view.Statuses)
+	return instance
 }
 
 func newInstanceByScaleSetVM(inst azureScaleSetInstance) *types.Instance {
 	instance := newInstance(*inst.instance.Name, *inst.instance.ID, *inst.instance.Location, string(*inst.instance.Properties.HardwareProfile.VMSize), inst.resourceGroupName, getScaleSetInstanceState(inst.instanceView), inst.tagMap)
 	instance.Metadata[ScaleSetName] = inst.scaleSetName
+	instance.StoppedAt = getStoppedAt(instance.State, inst.instanceView.Statuses)
 	return instance
 }
 
@@ -741,6 +1342,48 @@ func newImage(image armcompute.Image) *types.Image {
 	}
 }
 
+func newDisk(disk armcompute.Disk) *types.Disk {
+	tags := utils.ConvertTags(disk.Tags)
+	resourceGroupName, _ := getResourceGroupName(*disk.ID)
+	result := &types.Disk{
+		ID:        *disk.ID,
+		Name:      *disk.Name,
+		CloudType: types.AZURE,
+		Region:    *disk.Location,
+		Owner:     tags[ctx.OwnerLabel],
+		Tags:      tags,
+		State:     types.Unknown,
+		Metadata:  map[string]string{ResourceGroupName: resourceGroupName},
+	}
+	if disk.SKU != nil && disk.SKU.Name != nil {
+		result.Type = string(*disk.SKU.Name)
+	}
+	if properties := disk.Properties; properties != nil {
+		if properties.TimeCreated != nil {
+			result.Created = *properties.TimeCreated
+		}
+		if properties.DiskSizeGB != nil {
+			result.Size = int64(*properties.DiskSizeGB)
+		}
+		if properties.DiskState != nil {
+			result.State = getDiskState(*properties.DiskState)
+		}
+	}
+	return result
+}
+
+// A reserved disk is attached to a deallocated virtual machine
+func getDiskState(state armcompute.DiskState) types.State {
+	switch state {
+	case armcompute.DiskStateUnattached:
+		return types.Unused
+	case armcompute.DiskStateAttached, armcompute.DiskStateReserved:
+		return types.InUse
+	default:
+		return types.Unknown
+	}
+}
+
 func newInstance(name, ID, location, instanceType, resourceGroupName string, state types.State, tagMap map[string]*string) *types.Instance {
 	tags := utils.ConvertTags(tagMap)
 	return &types.Instance{
@@ -775,6 +1418,20 @@ func getScaleSetInstanceState(view armcompute.VirtualMachineScaleSetVMsClientGet
 	return types.Unknown
 }
 
+// The time of the stopped or deallocated power state is used if the instance view has it. The time of the provisioning state is
+// not used, because it belongs to the last operation on the instance, which is not necessarily the stop.
+func getStoppedAt(state types.State, view []*armcompute.InstanceViewStatus) *time.Time {
+	if state != types.Stopped {
+		return nil
+	}
+	for _, v := range view {
+		if v.Code != nil && (*v.Code == "PowerState/deallocated" || *v.Code == "PowerState/stopped") && v.Time != nil {
+			return v.Time
+		}
+	}
+	return nil
+}
+
 // Possible values:
 //
 //	"PowerState/deallocated"
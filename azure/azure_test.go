@@ -2,13 +2,27 @@ package azure
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
-	"testing"
-	"time"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/monitor/armmonitor"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 
 	"github.com/Azure/go-autorest/autorest"
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 
 	"github.com/stretchr/testify/assert"
@@ -168,3 +182,432 @@ func getStubConvertTimeUnixEpochZero() (*callInfo, func(string) time.Time) {
 		return time.Date(1970, 1, 1, 0, 0, 0, 0, time.Local)
 	}
 }
+
+type mockTagsClient struct {
+	scopes     []string
+	operations []armresources.TagsPatchOperation
+}
+
+func (c *mockTagsClient) UpdateAtScope(ctx context.Context, scope string, parameters armresources.TagsPatchResource, options *armresources.TagsClientUpdateAtScopeOptions) (armresources.TagsClientUpdateAtScopeResponse, error) {
+	c.scopes = append(c.scopes, scope)
+	c.operations = append(c.operations, *parameters.Operation)
+	return armresources.TagsClientUpdateAtScopeResponse{}, nil
+}
+
+func TestUpdateTags(t *testing.T) {
+	client := &mockTagsClient{}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AZURE, ID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm", Tags: types.Tags{"key": "value"}},
+		&types.Instance{CloudType: types.AZURE, ID: "untagged"},
+		&types.Instance{CloudType: types.GCP, ID: "gcp"},
+	}
+
+	errs := updateTags(client, items, armresources.TagsPatchOperationDelete, func(item types.CloudItem) types.Tags {
+		return item.GetTags()
+	})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm"}, client.scopes)
+	assert.Equal(t, []armresources.TagsPatchOperation{armresources.TagsPatchOperationDelete}, client.operations)
+}
+
+type mockMetricsClient struct {
+	lock    sync.Mutex
+	regions []string
+	filters []string
+}
+
+func (c *mockMetricsClient) ListAtSubscriptionScope(ctx context.Context, region string, options *armmonitor.MetricsClientListAtSubscriptionScopeOptions) (armmonitor.MetricsClientListAtSubscriptionScopeResponse, error) {
+	c.lock.Lock()
+	c.regions = append(c.regions, region)
+	c.filters = append(c.filters, *options.Filter)
+	c.lock.Unlock()
+	resourceIDName := "Microsoft.ResourceId"
+	newSeries := func(resourceID string, values ...float64) *armmonitor.TimeSeriesElement {
+		series := &armmonitor.TimeSeriesElement{Metadatavalues: []*armmonitor.MetadataValue{{Name: &armmonitor.LocalizableString{Value: &resourceIDName}, Value: &resourceID}}}
+		for i := range values {
+			series.Data = append(series.Data, &armmonitor.MetricValue{Average: &values[i], Total: &values[i]})
+		}
+		return series
+	}
+	cpuName, networkInName := "Percentage CPU", "Network In Total"
+	response := armmonitor.MetricsClientListAtSubscriptionScopeResponse{}
+	response.Value = []*armmonitor.SubscriptionScopeMetric{
+		{Name: &armmonitor.LocalizableString{Value: &cpuName}, Timeseries: []*armmonitor.TimeSeriesElement{newSeries("/SUBSCRIPTIONS/SUB/VM-1", 2, 4), newSeries("/subscriptions/sub/vm-2")}},
+		{Name: &armmonitor.LocalizableString{Value: &networkInName}, Timeseries: []*armmonitor.TimeSeriesElement{newSeries("/subscriptions/sub/vm-1", 3600, 7200)}},
+	}
+	return response, nil
+}
+
+func TestGetUtilizations(t *testing.T) {
+	client := &mockMetricsClient{}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AZURE, ID: "/subscriptions/sub/vm-1", Region: "westeurope"},
+		&types.Instance{CloudType: types.AZURE, ID: "/subscriptions/sub/vm-2", Region: "westeurope"},
+		&types.Disk{CloudType: types.AZURE, ID: "/subscriptions/sub/disk", Region: "eastus"},
+		&types.Instance{CloudType: types.AWS, ID: "i-1", Region: "eastus"},
+	}
+
+	utilizations, errs := getUtilizations(client, items, 24*time.Hour, time.Now())
+
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"westeurope"}, client.regions)
+	assert.Equal(t, []string{"Microsoft.ResourceId eq '/subscriptions/sub/vm-1' or Microsoft.ResourceId eq '/subscriptions/sub/vm-2'"}, client.filters)
+	assert.Equal(t, map[string]types.Utilization{"/subscriptions/sub/vm-1": {CPU: 3, Network: 1.5}}, utilizations)
+}
+
+func TestGetUtilizationsBatched(t *testing.T) {
+	client := &mockMetricsClient{}
+	var items []types.CloudItem
+	for i := 0; i < 45; i++ {
+		items = append(items, &types.Instance{CloudType: types.AZURE, ID: fmt.Sprintf("/subscriptions/sub/vm-%02d", i), Region: "westeurope"})
+	}
+
+	getUtilizations(client, items, 24*time.Hour, time.Now())
+
+	assert.Equal(t, 3, len(client.filters))
+	sort.Strings(client.filters)
+	assert.Equal(t, 20, strings.Count(client.filters[0], "Microsoft.ResourceId eq"))
+	assert.Equal(t, 5, strings.Count(client.filters[2], "Microsoft.ResourceId eq"))
+}
+
+// The testdata was created with the -record flag, further incidents can be reproduced the same way
+func TestGetInstancesFromRecording(t *testing.T) {
+	replayer, err := recording.NewReplayer("testdata/recording/instances")
+	assert.Nil(t, err)
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: &http.Client{Transport: replayer}}}
+	provider := azureProvider{}
+	provider.vmClient, err = armcompute.NewVirtualMachinesClient("sub-id", replayCredential{}, options)
+	assert.Nil(t, err)
+	provider.vmScaleSetClient, err = armcompute.NewVirtualMachineScaleSetsClient("sub-id", replayCredential{}, options)
+	assert.Nil(t, err)
+
+	instances, err := provider.GetInstances()
+
+	assert.Nil(t, err)
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name > instances[j].Name
+	})
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, "running-vm", instances[0].Name)
+	assert.Equal(t, "alice", instances[0].Owner)
+	assert.Equal(t, "westeurope", instances[0].Region)
+	assert.Equal(t, "Standard_D2s_v3", instances[0].InstanceType)
+	assert.Equal(t, types.Running, instances[0].State)
+	assert.Equal(t, "deallocated-vm", instances[1].Name)
+	assert.Equal(t, types.Stopped, instances[1].State)
+}
+
+func TestGetStoppedAt(t *testing.T) {
+	stoppedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	provisionedAt := stoppedAt.Add(time.Hour)
+	provisioning, deallocated, stopped := "ProvisioningState/succeeded", "PowerState/deallocated", "PowerState/stopped"
+	view := []*armcompute.InstanceViewStatus{{Code: &provisioning, Time: &provisionedAt}, {Code: &deallocated}}
+
+	assert.Equal(t, types.Stopped, getInstanceState(view))
+	// the time of the provisioning state is not the time of the stop
+	assert.Nil(t, getStoppedAt(types.Stopped, view))
+
+	for _, code := range []*string{&deallocated, &stopped} {
+		view := []*armcompute.InstanceViewStatus{{Code: &provisioning, Time: &provisionedAt}, {Code: code, Time: &stoppedAt}}
+		assert.Equal(t, stoppedAt, *getStoppedAt(types.Stopped, view))
+		assert.Nil(t, getStoppedAt(types.Running, view))
+	}
+}
+
+type mockDisksClient struct {
+	pages   [][]*armcompute.Disk
+	deleted chan string
+}
+
+func (c mockDisksClient) NewListPager(*armcompute.DisksClientListOptions) *runtime.Pager[armcompute.DisksClientListResponse] {
+	return runtime.NewPager(runtime.PagingHandler[armcompute.DisksClientListResponse]{
+		More: func(page armcompute.DisksClientListResponse) bool {
+			return page.NextLink != nil
+		},
+		Fetcher: func(_ context.Context, previous *armcompute.DisksClientListResponse) (armcompute.DisksClientListResponse, error) {
+			next := 0
+			if previous != nil {
+				next, _ = strconv.Atoi(*previous.NextLink)
+			}
+			page := armcompute.DisksClientListResponse{DiskList: armcompute.DiskList{Value: c.pages[next]}}
+			if next+1 < len(c.pages) {
+				nextLink := strconv.Itoa(next + 1)
+				page.NextLink = &nextLink
+			}
+			return page, nil
+		},
+	})
+}
+
+func (c mockDisksClient) BeginDelete(_ context.Context, resourceGroupName string, diskName string, _ *armcompute.DisksClientBeginDeleteOptions) (*runtime.Poller[armcompute.DisksClientDeleteResponse], error) {
+	c.deleted <- resourceGroupName + "/" + diskName
+	switch diskName {
+	case "locked":
+		return nil, errors.New("ScopeLocked")
+	case "failing":
+		return runtime.NewPoller(nil, runtime.Pipeline{}, &runtime.NewPollerOptions[armcompute.DisksClientDeleteResponse]{Handler: mockDeletePoller{err: errors.New("InternalError")}})
+	}
+	return runtime.NewPoller(nil, runtime.Pipeline{}, &runtime.NewPollerOptions[armcompute.DisksClientDeleteResponse]{Handler: mockDeletePoller{}})
+}
+
+// mockDeletePoller is a long-running deletion that is already finished
+type mockDeletePoller struct {
+	err error
+}
+
+func (p mockDeletePoller) Done() bool {
+	return true
+}
+
+func (p mockDeletePoller) Poll(context.Context) (*http.Response, error) {
+	return nil, nil
+}
+
+func (p mockDeletePoller) Result(context.Context, *armcompute.DisksClientDeleteResponse) error {
+	return p.err
+}
+
+func newTestDisk(name string, state armcompute.DiskState) *armcompute.Disk {
+	ID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/" + name
+	location, size, created := "westeurope", int32(128), time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	sku := armcompute.DiskStorageAccountTypesPremiumLRS
+	owner := "owner"
+	return &armcompute.Disk{
+		ID:         &ID,
+		Name:       &name,
+		Location:   &location,
+		SKU:        &armcompute.DiskSKU{Name: &sku},
+		Tags:       map[string]*string{ctx.OwnerLabel: &owner},
+		Properties: &armcompute.DiskProperties{DiskState: &state, DiskSizeGB: &size, TimeCreated: &created},
+	}
+}
+
+func TestGetDisks(t *testing.T) {
+	client := mockDisksClient{pages: [][]*armcompute.Disk{
+		{newTestDisk("disk-1", armcompute.DiskStateUnattached)},
+		{newTestDisk("disk-2", armcompute.DiskStateReserved)},
+	}}
+
+	disks, err := getDisks(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(disks))
+	assert.Equal(t, "disk-1", disks[0].Name)
+	assert.Equal(t, types.Unused, disks[0].State)
+	assert.Equal(t, int64(128), disks[0].Size)
+	assert.Equal(t, "Premium_LRS", disks[0].Type)
+	assert.Equal(t, "owner", disks[0].Owner)
+	assert.Equal(t, "westeurope", disks[0].Region)
+	assert.Equal(t, "rg", disks[0].Metadata[ResourceGroupName])
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), disks[0].Created)
+	assert.Equal(t, types.InUse, disks[1].State)
+}
+
+func TestDeleteDisks(t *testing.T) {
+	deleteChan := make(chan string, 10)
+	client := mockDisksClient{deleted: deleteChan}
+	var disks []*types.Disk
+	for _, name := range []string{"disk", "locked", "failing"} {
+		disks = append(disks, &types.Disk{CloudType: types.AZURE, ID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/" + name, Region: "westeurope"})
+	}
+
+	errs := deleteDisks(client, disks)
+	close(deleteChan)
+
+	var deleted []string
+	for disk := range deleteChan {
+		deleted = append(deleted, disk)
+	}
+	sort.Strings(deleted)
+	assert.Equal(t, []string{"rg/disk", "rg/failing", "rg/locked"}, deleted)
+	var failed []string
+	for _, err := range errs {
+		failed = append(failed, err.(types.ResourceError).ID)
+	}
+	sort.Strings(failed)
+	assert.Equal(t, []string{disks[2].ID, disks[1].ID}, failed)
+}
+
+type mockAlertsClient struct {
+	metricAlerts      []*armmonitor.MetricAlertResource
+	activityLogAlerts []*armmonitor.ActivityLogAlertResource
+	deleted           chan string
+}
+
+func (c mockAlertsClient) NewListBySubscriptionPager(*armmonitor.MetricAlertsClientListBySubscriptionOptions) *runtime.Pager[armmonitor.MetricAlertsClientListBySubscriptionResponse] {
+	return runtime.NewPager(runtime.PagingHandler[armmonitor.MetricAlertsClientListBySubscriptionResponse]{
+		More: func(armmonitor.MetricAlertsClientListBySubscriptionResponse) bool {
+			return false
+		},
+		Fetcher: func(context.Context, *armmonitor.MetricAlertsClientListBySubscriptionResponse) (armmonitor.MetricAlertsClientListBySubscriptionResponse, error) {
+			return armmonitor.MetricAlertsClientListBySubscriptionResponse{MetricAlertResourceCollection: armmonitor.MetricAlertResourceCollection{Value: c.metricAlerts}}, nil
+		},
+	})
+}
+
+func (c mockAlertsClient) NewListBySubscriptionIDPager(*armmonitor.ActivityLogAlertsClientListBySubscriptionIDOptions) *runtime.Pager[armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse] {
+	return runtime.NewPager(runtime.PagingHandler[armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse]{
+		More: func(armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse) bool {
+			return false
+		},
+		Fetcher: func(context.Context, *armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse) (armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse, error) {
+			return armmonitor.ActivityLogAlertsClientListBySubscriptionIDResponse{AlertRuleList: armmonitor.AlertRuleList{Value: c.activityLogAlerts}}, nil
+		},
+	})
+}
+
+type mockMetricAlertsClient struct {
+	mockAlertsClient
+}
+
+func (c mockMetricAlertsClient) Delete(_ context.Context, resourceGroupName string, ruleName string, _ *armmonitor.MetricAlertsClientDeleteOptions) (armmonitor.MetricAlertsClientDeleteResponse, error) {
+	c.deleted <- "metric:" + resourceGroupName + "/" + ruleName
+	return armmonitor.MetricAlertsClientDeleteResponse{}, nil
+}
+
+type mockActivityLogAlertsClient struct {
+	mockAlertsClient
+}
+
+func (c mockActivityLogAlertsClient) Delete(_ context.Context, resourceGroupName string, activityLogAlertName string, _ *armmonitor.ActivityLogAlertsClientDeleteOptions) (armmonitor.ActivityLogAlertsClientDeleteResponse, error) {
+	c.deleted <- "activityLog:" + resourceGroupName + "/" + activityLogAlertName
+	return armmonitor.ActivityLogAlertsClientDeleteResponse{}, nil
+}
+
+type mockResourcesClient struct {
+	resourceIDs      []string
+	resourceGroupIDs []string
+}
+
+func (c mockResourcesClient) NewListPager(*armresources.ClientListOptions) *runtime.Pager[armresources.ClientListResponse] {
+	var resources []*armresources.GenericResourceExpanded
+	for i := range c.resourceIDs {
+		resources = append(resources, &armresources.GenericResourceExpanded{ID: &c.resourceIDs[i]})
+	}
+	return runtime.NewPager(runtime.PagingHandler[armresources.ClientListResponse]{
+		More: func(armresources.ClientListResponse) bool {
+			return false
+		},
+		Fetcher: func(context.Context, *armresources.ClientListResponse) (armresources.ClientListResponse, error) {
+			return armresources.ClientListResponse{ResourceListResult: armresources.ResourceListResult{Value: resources}}, nil
+		},
+	})
+}
+
+type mockResourceGroupsClient struct {
+	mockResourcesClient
+}
+
+func (c mockResourceGroupsClient) NewListPager(*armresources.ResourceGroupsClientListOptions) *runtime.Pager[armresources.ResourceGroupsClientListResponse] {
+	var resourceGroups []*armresources.ResourceGroup
+	for i := range c.resourceGroupIDs {
+		resourceGroups = append(resourceGroups, &armresources.ResourceGroup{ID: &c.resourceGroupIDs[i]})
+	}
+	return runtime.NewPager(runtime.PagingHandler[armresources.ResourceGroupsClientListResponse]{
+		More: func(armresources.ResourceGroupsClientListResponse) bool {
+			return false
+		},
+		Fetcher: func(context.Context, *armresources.ResourceGroupsClientListResponse) (armresources.ResourceGroupsClientListResponse, error) {
+			return armresources.ResourceGroupsClientListResponse{ResourceGroupListResult: armresources.ResourceGroupListResult{Value: resourceGroups}}, nil
+		},
+	})
+}
+
+func newTestMetricAlert(name string, scopes ...string) *armmonitor.MetricAlertResource {
+	ID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Insights/metricAlerts/" + name
+	location, updated, created := "global", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), "1700000000"
+	return &armmonitor.MetricAlertResource{ID: &ID, Name: &name, Location: &location, Tags: map[string]*string{"creation-timestamp": &created}, Properties: &armmonitor.MetricAlertProperties{Scopes: toPtrs(scopes), LastUpdatedTime: &updated}}
+}
+
+func newTestActivityLogAlert(name string, scopes ...string) *armmonitor.ActivityLogAlertResource {
+	ID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Insights/activityLogAlerts/" + name
+	location := "global"
+	return &armmonitor.ActivityLogAlertResource{ID: &ID, Name: &name, Location: &location, Properties: &armmonitor.AlertRuleProperties{Scopes: toPtrs(scopes)}}
+}
+
+func toPtrs(values []string) []*string {
+	var ptrs []*string
+	for i := range values {
+		ptrs = append(ptrs, &values[i])
+	}
+	return ptrs
+}
+
+func TestGetAlerts(t *testing.T) {
+	vm := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm"
+	alertsClient := mockAlertsClient{
+		metricAlerts: []*armmonitor.MetricAlertResource{
+			newTestMetricAlert("existing-vm", strings.ToUpper(vm)),
+			newTestMetricAlert("deleted-vm", "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/deleted"),
+			newTestMetricAlert("one-existing-vm", "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/deleted", vm),
+			newTestMetricAlert("child-of-deleted", "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Sql/servers/deleted/databases/db"),
+			newTestMetricAlert("child-of-existing", vm+"/extensions/ext"),
+		},
+		activityLogAlerts: []*armmonitor.ActivityLogAlertResource{
+			newTestActivityLogAlert("deleted-rg", "/subscriptions/sub/resourceGroups/deleted"),
+			newTestActivityLogAlert("existing-rg", "/subscriptions/sub/resourceGroups/rg"),
+			newTestActivityLogAlert("subscription", "/subscriptions/sub"),
+			newTestActivityLogAlert("other-subscription", "/subscriptions/other/resourceGroups/rg"),
+		},
+	}
+	resources := mockResourcesClient{resourceIDs: []string{vm}, resourceGroupIDs: []string{"/subscriptions/sub/resourceGroups/rg"}}
+
+	alerts, err := getAlerts("sub", mockMetricAlertsClient{alertsClient}, mockActivityLogAlertsClient{alertsClient}, resources, mockResourceGroupsClient{resources})
+
+	assert.Nil(t, err)
+	states := map[string]types.State{}
+	for _, alert := range alerts {
+		states[alert.Name] = alert.State
+	}
+	assert.Equal(t, map[string]types.State{
+		"existing-vm":        types.InUse,
+		"deleted-vm":         types.Unused,
+		"one-existing-vm":    types.InUse,
+		"child-of-deleted":   types.Unused,
+		"child-of-existing":  types.InUse,
+		"deleted-rg":         types.Unused,
+		"existing-rg":        types.InUse,
+		"subscription":       types.InUse,
+		"other-subscription": types.InUse,
+	}, states)
+	assert.Equal(t, "rg", alerts[0].Metadata[ResourceGroupName])
+	assert.Equal(t, time.Unix(1700000000, 0), alerts[0].Created)
+	assert.Equal(t, "2024-03-01T10:00:00Z", alerts[0].Metadata["lastUpdatedTime"])
+}
+
+func TestDeleteAlerts(t *testing.T) {
+	alertsClient := mockAlertsClient{deleted: make(chan string, 10)}
+	alerts := []*types.Alert{
+		{CloudType: types.AZURE, ID: *newTestMetricAlert("metric-alert").ID},
+		{CloudType: types.AZURE, ID: *newTestActivityLogAlert("activity-log-alert").ID},
+		{CloudType: types.AZURE, ID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Insights/scheduledQueryRules/query"},
+	}
+
+	errs := deleteAlerts(mockMetricAlertsClient{alertsClient}, mockActivityLogAlertsClient{alertsClient}, alerts)
+	close(alertsClient.deleted)
+
+	var deleted []string
+	for alert := range alertsClient.deleted {
+		deleted = append(deleted, alert)
+	}
+	sort.Strings(deleted)
+	assert.Equal(t, []string{"activityLog:rg/activity-log-alert", "metric:rg/metric-alert"}, deleted)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, alerts[2].ID, errs[0].(types.ResourceError).ID)
+}
+
+func TestDeleteAlertsDryRun(t *testing.T) {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+	alertsClient := mockAlertsClient{deleted: make(chan string, 10)}
+	alerts := []*types.Alert{{CloudType: types.AZURE, ID: *newTestMetricAlert("metric-alert").ID}}
+
+	errs := deleteAlerts(mockMetricAlertsClient{alertsClient}, mockActivityLogAlertsClient{alertsClient}, alerts)
+	close(alertsClient.deleted)
+
+	assert.Empty(t, errs)
+	assert.Empty(t, <-alertsClient.deleted)
+}
@@ -1,6 +1,10 @@
 package context
 
-import "github.com/hortonworks/cloud-haunter/types"
+import (
+	"sync/atomic"
+
+	"github.com/hortonworks/cloud-haunter/types"
+)
 
 var (
 	// Version is global variable to store application version generated during release
@@ -23,6 +27,12 @@ var (
 	// AzureCreationTimeLabel is the instance creation time on Azure, because Azure is stupid and doesn't tell
 	AzureCreationTimeLabel = "creation-timestamp,cb-creation-timestamp,cdp-creation-timestamp"
 
+	// MarkedAtLabel is the tag/label that holds the unix timestamp when the item was marked by the mark action
+	MarkedAtLabel = "cloud-haunter-marked-at"
+
+	// MarkReasonLabel is the tag/label that holds the filters that caused the item to be marked
+	MarkReasonLabel = "cloud-haunter-mark-reason"
+
 	AwsBulkOperationSize = 50
 
 	// AWS rate limit interval in seconds for each API calls regardless the region
@@ -61,3 +71,16 @@ var Actions = make(map[types.ActionType]types.Action)
 
 // FilterConfig contains the include/exclude configurations from config file
 var FilterConfig types.IFilterConfig
+
+// shuttingDown is set when the daemon is asked to stop
+var shuttingDown atomic.Bool
+
+// RequestShutdown signals the running actions to stop after their current batch
+func RequestShutdown() {
+	shuttingDown.Store(true)
+}
+
+// IsShuttingDown returns true if the shutdown of the process has been requested
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
@@ -0,0 +1,531 @@
+package dummy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var provider *dummyProvider
+
+var errNotFound = errors.New("resource not found")
+
+// dummyProvider is an in-memory cloud provider seeded from a fixture file. The actions change its state,
+// so the result of a job can be checked by a subsequent operation without a real cloud account.
+type dummyProvider struct {
+	lock sync.Mutex
+	fixture
+}
+
+// fixture contains the resources of the dummy provider in the same JSON format as the json action prints them
+type fixture struct {
+	Account   string            `json:"Account"`
+	Instances []*types.Instance `json:"Instances"`
+	Stacks    []*types.Stack    `json:"Stacks"`
+	Disks     []*types.Disk     `json:"Disks"`
+	Images    []*types.Image    `json:"Images"`
+	Databases []*types.Database `json:"Databases"`
+	Alerts    []*types.Alert    `json:"Alerts"`
+	Storages  []*types.Storage  `json:"Storages"`
+	Accesses  []*types.Access   `json:"Accesses"`
+
+	// Objects are the objects of the storages by storage ID, removed by the cleanup if they are older than the retention days
+	Objects map[string][]StorageObject `json:"Objects"`
+
+	// Utilizations are the utilizations of the instances and databases by ID
+	Utilizations map[string]types.Utilization `json:"Utilizations"`
+}
+
+// StorageObject is an object of a dummy storage
+type StorageObject struct {
+	Name    string    `json:"Name"`
+	Created time.Time `json:"Created"`
+	Size    int64     `json:"Size"`
+}
+
+func init() {
+	if fixtureLoc := os.Getenv("DUMMY_FIXTURE"); len(fixtureLoc) != 0 {
+		Register(fixtureLoc)
+	}
+}
+
+// Register registers the DUMMY provider seeded from the fixture, the fixture is loaded when the provider is used first
+func Register(fixtureLoc string) {
+	provider = nil
+	ctx.CloudProviders[types.DUMMY] = func() types.CloudProvider {
+		if provider == nil {
+			log.Debugf("[DUMMY] Loading fixture: %s", fixtureLoc)
+			p, err := loadProvider(fixtureLoc)
+			if err != nil {
+				panic("[DUMMY] Failed to load fixture, err: " + err.Error())
+			}
+			provider = p
+			log.Info("[DUMMY] Successfully prepared")
+		}
+		return provider
+	}
+}
+
+func loadProvider(location string) (*dummyProvider, error) {
+	raw, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(raw)
+}
+
+func newProvider(raw []byte) (*dummyProvider, error) {
+	p := &dummyProvider{}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p.fixture); err != nil {
+		return nil, err
+	}
+	if len(p.Account) == 0 {
+		p.Account = "dummy"
+	}
+	p.init()
+	return p, nil
+}
+
+// The cloud type of every resource is DUMMY, regardless of the fixture
+func (p *dummyProvider) init() {
+	for _, i := range p.Instances {
+		i.CloudType = types.DUMMY
+	}
+	for _, s := range p.Stacks {
+		s.CloudType = types.DUMMY
+	}
+	for _, d := range p.Disks {
+		d.CloudType = types.DUMMY
+	}
+	for _, i := range p.Images {
+		i.CloudType = types.DUMMY
+	}
+	for _, d := range p.Databases {
+		d.CloudType = types.DUMMY
+	}
+	for _, a := range p.Alerts {
+		a.CloudType = types.DUMMY
+	}
+	for _, s := range p.Storages {
+		s.CloudType = types.DUMMY
+	}
+	for _, a := range p.Accesses {
+		a.CloudType = types.DUMMY
+	}
+}
+
+func (p *dummyProvider) GetAccountName() string {
+	return p.Account
+}
+
+func (p *dummyProvider) GetInstances() ([]*types.Instance, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var instances []*types.Instance
+	for _, i := range p.Instances {
+		instance := *i
+		instance.Tags = copyTags(i.Tags)
+		instances = append(instances, &instance)
+	}
+	return instances, nil
+}
+
+func (p *dummyProvider) StopInstances(instances *types.InstanceContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, instance := range instances.Get(types.DUMMY) {
+		if i := findInstance(p.Instances, instance.ID); i == nil {
+			errs = append(errs, types.NewResourceError(instance.ID, instance.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, instance is not stopped: %s", instance.Name)
+		} else {
+			log.Infof("[DUMMY] Stop instance: %s", instance.Name)
+			now := time.Now()
+			i.State = types.Stopped
+			i.StoppedAt = &now
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) TerminateInstances(instances *types.InstanceContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, instance := range instances.Get(types.DUMMY) {
+		if findInstance(p.Instances, instance.ID) == nil {
+			errs = append(errs, types.NewResourceError(instance.ID, instance.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, instance is not terminated: %s", instance.Name)
+		} else {
+			log.Infof("[DUMMY] Terminate instance: %s", instance.Name)
+			p.Instances = removeInstance(p.Instances, instance.ID)
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) StopDatabases(databases *types.DatabaseContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, database := range databases.Get(types.DUMMY) {
+		if d := findDatabase(p.Databases, database.ID); d == nil {
+			errs = append(errs, types.NewResourceError(database.ID, database.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, database is not stopped: %s", database.Name)
+		} else {
+			log.Infof("[DUMMY] Stop database: %s", database.Name)
+			d.State = types.Stopped
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) TerminateStacks(stacks *types.StackContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, stack := range stacks.Get(types.DUMMY) {
+		index := -1
+		for i, s := range p.Stacks {
+			if s.ID == stack.ID {
+				index = i
+			}
+		}
+		if index < 0 {
+			errs = append(errs, types.NewResourceError(stack.ID, stack.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, stack is not terminated: %s", stack.Name)
+		} else {
+			log.Infof("[DUMMY] Terminate stack: %s", stack.Name)
+			p.Stacks = append(p.Stacks[:index], p.Stacks[index+1:]...)
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetStacks() ([]*types.Stack, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var stacks []*types.Stack
+	for _, s := range p.Stacks {
+		stack := *s
+		stack.Tags = copyTags(s.Tags)
+		stacks = append(stacks, &stack)
+	}
+	return stacks, nil
+}
+
+func (p *dummyProvider) GetDisks() ([]*types.Disk, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var disks []*types.Disk
+	for _, d := range p.Disks {
+		disk := *d
+		disk.Tags = copyTags(d.Tags)
+		disks = append(disks, &disk)
+	}
+	return disks, nil
+}
+
+func (p *dummyProvider) DeleteDisks(disks *types.DiskContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, disk := range disks.Get(types.DUMMY) {
+		index := -1
+		for i, d := range p.Disks {
+			if d.ID == disk.ID {
+				index = i
+			}
+		}
+		if index < 0 {
+			errs = append(errs, types.NewResourceError(disk.ID, disk.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, disk is not deleted: %s", disk.Name)
+		} else {
+			log.Infof("[DUMMY] Delete disk: %s", disk.Name)
+			p.Disks = append(p.Disks[:index], p.Disks[index+1:]...)
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetImages() ([]*types.Image, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var images []*types.Image
+	for _, i := range p.Images {
+		image := *i
+		image.Tags = copyTags(i.Tags)
+		images = append(images, &image)
+	}
+	return images, nil
+}
+
+func (p *dummyProvider) DeleteImages(images *types.ImageContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, image := range images.Get(types.DUMMY) {
+		index := -1
+		for i, img := range p.Images {
+			if img.ID == image.ID {
+				index = i
+			}
+		}
+		if index < 0 {
+			errs = append(errs, types.NewResourceError(image.ID, image.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, image is not deleted: %s", image.Name)
+		} else {
+			log.Infof("[DUMMY] Delete image: %s", image.Name)
+			p.Images = append(p.Images[:index], p.Images[index+1:]...)
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetDatabases() ([]*types.Database, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var databases []*types.Database
+	for _, d := range p.Databases {
+		database := *d
+		database.Tags = copyTags(d.Tags)
+		databases = append(databases, &database)
+	}
+	return databases, nil
+}
+
+func (p *dummyProvider) GetAlerts() ([]*types.Alert, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var alerts []*types.Alert
+	for _, a := range p.Alerts {
+		alert := *a
+		alert.Tags = copyTags(a.Tags)
+		alerts = append(alerts, &alert)
+	}
+	return alerts, nil
+}
+
+func (p *dummyProvider) DeleteAlerts(alerts *types.AlertContainer) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, alert := range alerts.Get(types.DUMMY) {
+		index := -1
+		for i, a := range p.Alerts {
+			if a.ID == alert.ID {
+				index = i
+			}
+		}
+		if index < 0 {
+			errs = append(errs, types.NewResourceError(alert.ID, alert.Region, errNotFound))
+		} else if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, alert is not deleted: %s", alert.Name)
+		} else {
+			log.Infof("[DUMMY] Delete alert: %s", alert.Name)
+			p.Alerts = append(p.Alerts[:index], p.Alerts[index+1:]...)
+		}
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetStorages() ([]*types.Storage, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var storages []*types.Storage
+	for _, s := range p.Storages {
+		storage := *s
+		storage.Tags = copyTags(s.Tags)
+		storages = append(storages, &storage)
+	}
+	return storages, nil
+}
+
+func (p *dummyProvider) CleanupStorages(storages *types.StorageContainer, retentionDays int) []error {
+	retentionTime := time.Now().AddDate(0, 0, -retentionDays)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, storage := range storages.Get(types.DUMMY) {
+		if _, ok := p.Objects[storage.ID]; !ok {
+			log.Debugf("[DUMMY] Storage does not have any objects: %s", storage.Name)
+			continue
+		}
+		var retained []StorageObject
+		var deleted int64
+		for _, object := range p.Objects[storage.ID] {
+			if object.Created.Before(retentionTime) && !ctx.DryRun {
+				deleted += object.Size
+			} else {
+				retained = append(retained, object)
+			}
+		}
+		p.Objects[storage.ID] = retained
+		log.Infof("[DUMMY] Cleaned up %d bytes from storage: %s", deleted, storage.Name)
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetAccesses() ([]*types.Access, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var accesses []*types.Access
+	for _, a := range p.Accesses {
+		access := *a
+		access.Tags = copyTags(a.Tags)
+		accesses = append(accesses, &access)
+	}
+	return accesses, nil
+}
+
+func (p *dummyProvider) AddTags(items []types.CloudItem, tags types.Tags) []error {
+	return p.updateTags(items, func(itemTags types.Tags) types.Tags {
+		if itemTags == nil {
+			itemTags = types.Tags{}
+		}
+		for k, v := range tags {
+			itemTags[k] = v
+		}
+		return itemTags
+	})
+}
+
+func (p *dummyProvider) RemoveTags(items []types.CloudItem, keys []string) []error {
+	return p.updateTags(items, func(itemTags types.Tags) types.Tags {
+		for _, k := range keys {
+			delete(itemTags, k)
+		}
+		return itemTags
+	})
+}
+
+func (p *dummyProvider) updateTags(items []types.CloudItem, update func(types.Tags) types.Tags) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, item := range items {
+		if ctx.DryRun {
+			log.Infof("[DUMMY] Dry-run set, tags are not updated on %s: %s", item.GetType(), item.GetName())
+			continue
+		}
+		switch item.GetItem().(type) {
+		case types.Instance:
+			if i := findInstance(p.Instances, item.GetID()); i != nil {
+				i.Tags = update(i.Tags)
+				continue
+			}
+		case types.Database:
+			if d := findDatabase(p.Databases, item.GetID()); d != nil {
+				d.Tags = update(d.Tags)
+				continue
+			}
+		case types.Disk:
+			if d := findDisk(p.Disks, item.GetID()); d != nil {
+				d.Tags = update(d.Tags)
+				continue
+			}
+		case types.Stack:
+			if s := findStack(p.Stacks, item.GetID()); s != nil {
+				s.Tags = update(s.Tags)
+				continue
+			}
+		default:
+			errs = append(errs, types.NewResourceError(item.GetID(), item.GetRegion(), fmt.Errorf("tags are not supported on type: %s", item.GetType())))
+			continue
+		}
+		errs = append(errs, types.NewResourceError(item.GetID(), item.GetRegion(), errNotFound))
+	}
+	return errs
+}
+
+func (p *dummyProvider) GetUtilizations(items []types.CloudItem, _ time.Duration) (map[string]types.Utilization, []error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	utilizations := map[string]types.Utilization{}
+	for _, item := range items {
+		if utilization, ok := p.Utilizations[item.GetID()]; ok {
+			utilizations[item.GetID()] = utilization
+		}
+	}
+	return utilizations, nil
+}
+
+// GetObjects returns the objects of the storage, it's used to check the result of the cleanup
+func (p *dummyProvider) GetObjects(storageID string) []StorageObject {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]StorageObject{}, p.Objects[storageID]...)
+}
+
+func findInstance(instances []*types.Instance, id string) *types.Instance {
+	for _, i := range instances {
+		if i.ID == id {
+			return i
+		}
+	}
+	return nil
+}
+
+func removeInstance(instances []*types.Instance, id string) []*types.Instance {
+	var retained []*types.Instance
+	for _, i := range instances {
+		if i.ID != id {
+			retained = append(retained, i)
+		}
+	}
+	return retained
+}
+
+func findDatabase(databases []*types.Database, id string) *types.Database {
+	for _, d := range databases {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func findDisk(disks []*types.Disk, id string) *types.Disk {
+	for _, d := range disks {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func findStack(stacks []*types.Stack, id string) *types.Stack {
+	for _, s := range stacks {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func copyTags(tags types.Tags) types.Tags {
+	if tags == nil {
+		return nil
+	}
+	copied := types.Tags{}
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,175 @@
+package dummy
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/hortonworks/cloud-haunter/action"
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	_ "github.com/hortonworks/cloud-haunter/filter"
+	_ "github.com/hortonworks/cloud-haunter/operation"
+	"github.com/hortonworks/cloud-haunter/plan"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type dummySuite struct {
+	suite.Suite
+	provider *dummyProvider
+}
+
+func (s *dummySuite) SetupTest() {
+	p, err := loadProvider("testdata/fixture.json")
+	s.Require().Nil(err)
+	findInstance(p.Instances, "i-2").Created = time.Now()
+	s.provider = p
+	ctx.CloudProviders[types.DUMMY] = func() types.CloudProvider {
+		return s.provider
+	}
+}
+
+func (s *dummySuite) TearDownTest() {
+	delete(ctx.CloudProviders, types.DUMMY)
+}
+
+func (s *dummySuite) execute(op types.OpType, action types.ActionType, filters ...types.FilterType) *types.ActionReport {
+	job := types.Job{Operation: op, Action: action, Clouds: []types.CloudType{types.DUMMY}}
+	for _, f := range filters {
+		job.Filters = append(job.Filters, types.JobFilter{Type: f})
+	}
+	report, err := plan.ExecuteJob(job)
+	s.Require().Nil(err)
+	return report
+}
+
+func (s *dummySuite) TestLoadFixture() {
+	s.Equal("dummy-account", s.provider.GetAccountName())
+	instances, _ := s.provider.GetInstances()
+	s.Equal(3, len(instances))
+	s.Equal(types.DUMMY, instances[0].CloudType)
+	s.Equal(types.Running, instances[0].State)
+	s.Equal("alice", instances[0].Tags["owner"])
+
+	for _, getter := range []func() (int, error){
+		func() (int, error) { i, err := s.provider.GetStacks(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetDisks(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetImages(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetDatabases(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetAlerts(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetStorages(); return len(i), err },
+		func() (int, error) { i, err := s.provider.GetAccesses(); return len(i), err },
+	} {
+		count, err := getter()
+		s.Nil(err)
+		s.NotZero(count)
+	}
+}
+
+func (s *dummySuite) TestInvalidFixture() {
+	_, err := newProvider([]byte(`{"Instances": [], "Unknown": []}`))
+	s.NotNil(err)
+	_, err = loadProvider("testdata/missing.json")
+	s.True(os.IsNotExist(err))
+}
+
+func (s *dummySuite) TestStopLongRunningInstances() {
+	report := s.execute(types.Instances, types.StopAction, types.LongRunningFilter)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Equal("i-1", report.Results[0].ID)
+	instances, _ := s.provider.GetInstances()
+	s.Equal(types.Stopped, instances[0].State)
+	s.Equal(types.Running, instances[1].State)
+}
+
+func (s *dummySuite) TestStopIdleInstances() {
+	report := s.execute(types.Instances, types.StopAction, types.IdleFilter)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Equal("i-1", report.Results[0].ID)
+}
+
+func (s *dummySuite) TestTerminateLongStoppedInstances() {
+	report := s.execute(types.Instances, types.TerminationAction, types.LongStoppedFilter)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	s.Equal("i-3", report.Results[0].ID)
+}
+
+func (s *dummySuite) TestDryRun() {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+
+	report := s.execute(types.Instances, types.TerminationAction, types.LongRunningFilter)
+
+	s.Equal(1, report.Count(types.OutcomeDryRun))
+	instances, _ := s.provider.GetInstances()
+	s.Equal(3, len(instances))
+}
+
+func (s *dummySuite) TestTerminateInstances() {
+	report := s.execute(types.Instances, types.TerminationAction, types.LongRunningFilter)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	instances, _ := s.provider.GetInstances()
+	s.Equal(2, len(instances))
+	s.Equal("i-2", instances[0].ID)
+}
+
+func (s *dummySuite) TestDeleteUnusedDisksAndAlerts() {
+	report := s.execute(types.Disks, types.TerminationAction, types.UnusedFilter)
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	disks, _ := s.provider.GetDisks()
+	s.Equal(1, len(disks))
+	s.Equal("d-2", disks[0].ID)
+
+	report = s.execute(types.Alerts, types.TerminationAction, types.UnusedFilter)
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	alerts, _ := s.provider.GetAlerts()
+	s.Empty(alerts)
+}
+
+func (s *dummySuite) TestTerminateStacksAndImages() {
+	s.execute(types.Stacks, types.TerminationAction, types.LongRunningFilter)
+	stacks, _ := s.provider.GetStacks()
+	s.Empty(stacks)
+
+	s.execute(types.Images, types.TerminationAction)
+	images, _ := s.provider.GetImages()
+	s.Empty(images)
+}
+
+func (s *dummySuite) TestCleanupStorages() {
+	report := s.execute(types.Storages, types.CleanupAction)
+
+	s.Equal(1, report.Count(types.OutcomeSucceeded))
+	objects := s.provider.GetObjects("st-1")
+	s.Equal(1, len(objects))
+	s.Equal("recent", objects[0].Name)
+}
+
+func (s *dummySuite) TestMarkAndUnmark() {
+	s.execute(types.Instances, types.MarkAction, types.LongRunningFilter)
+	instances, _ := s.provider.GetInstances()
+	s.Contains(instances[0].Tags, ctx.MarkedAtLabel)
+	s.Equal("alice", instances[0].Tags["owner"])
+	s.NotContains(instances[1].Tags, ctx.MarkedAtLabel)
+
+	s.execute(types.Instances, types.UnmarkAction, types.LongRunningFilter)
+	instances, _ = s.provider.GetInstances()
+	s.NotContains(instances[0].Tags, ctx.MarkedAtLabel)
+}
+
+func (s *dummySuite) TestNotFound() {
+	errs := s.provider.StopInstances(types.NewInstanceContainer([]*types.Instance{{ID: "i-unknown", CloudType: types.DUMMY}}))
+
+	s.Equal(1, len(errs))
+	s.Contains(errs[0].Error(), errNotFound.Error())
+}
+
+func TestDummySuite(t *testing.T) {
+	suite.Run(t, new(dummySuite))
+}
@@ -1,7 +1,9 @@
 package operation
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
 	"github.com/hortonworks/cloud-haunter/types"
@@ -12,25 +14,57 @@ import (
 func filter(filterName string, items []types.CloudItem, filterType types.FilterConfigType, isNeeded func(types.CloudItem) bool) []types.CloudItem {
 	var filtered []types.CloudItem
 	for _, item := range items {
-		var include bool
-		if filterType.IsInclusive() {
-			include = isFilterMatch(filterName, item, filterType, ctx.FilterConfig)
-		} else {
-			include = !isFilterMatch(filterName, item, filterType, ctx.FilterConfig)
+		match, reason := getFilterMatch(filterName, item, filterType, ctx.FilterConfig)
+		include := match
+		if !filterType.IsInclusive() {
+			include = !match
 		}
 		if include {
 			log.Debugf("[%s] item %s is not filtered, because of filter config", filterName, item.GetName())
 		} else {
 			log.Debugf("[%s] item %s is filtered, because of filter config", filterName, item.GetName())
 		}
-		if isNeeded(item) && include {
+		needed := isNeeded(item)
+		recordDecision(FilterDecision{Filter: filterName, Item: item, Needed: needed, Included: include, Inclusive: filterType.IsInclusive(), Reason: reason})
+		if needed && include {
 			filtered = append(filtered, item)
 		}
 	}
 	return filtered
 }
 
+// MatchFilterConfig returns the items that match the include criteria of the given filter config instead of the global one
+func MatchFilterConfig(items []types.CloudItem, filterConfig types.IFilterConfig) []types.CloudItem {
+	var matching []types.CloudItem
+	for _, item := range items {
+		if isFilterMatch("MATCH", item, types.InclusiveFilter, filterConfig) {
+			matching = append(matching, item)
+		}
+	}
+	return matching
+}
+
+// RemoveExcluded drops the items that carry the ignore label or are excluded by the global filter config. It is used where items
+// are selected without a filter, e.g. by negating one, so the protected items are never selected.
+func RemoveExcluded(filterName string, items []types.CloudItem) []types.CloudItem {
+	var retained []types.CloudItem
+	for _, item := range items {
+		if isFilterMatch(filterName, item, types.ExclusiveFilter, ctx.FilterConfig) {
+			log.Debugf("[%s] item %s is filtered, because of filter config", filterName, item.GetName())
+			continue
+		}
+		retained = append(retained, item)
+	}
+	return retained
+}
+
 func isFilterMatch(filterName string, item types.CloudItem, filterType types.FilterConfigType, filterConfig types.IFilterConfig) bool {
+	match, _ := getFilterMatch(filterName, item, filterType, filterConfig)
+	return match
+}
+
+// The reason is the rule of the filter config that matched the item, or why none of them did
+func getFilterMatch(filterName string, item types.CloudItem, filterType types.FilterConfigType, filterConfig types.IFilterConfig) (bool, string) {
 	name := item.GetName()
 	_, ignoreLabelFound := item.GetTags()[ctx.IgnoreLabel]
 	if ignoreLabelFound {
@@ -40,70 +74,121 @@ func isFilterMatch(filterName string, item types.CloudItem, filterType types.Fil
 		} else {
 			if filterType.IsInclusive() {
 				log.Debugf("[%s] inclusive filter applied on item: %s", filterName, name)
-				return false
+				return false, "ignore label " + ctx.IgnoreLabel
 			}
 			log.Debugf("[%s] exclusive filter applied on item: %s", filterName, name)
-			return true
+			return true, "ignore label " + ctx.IgnoreLabel
 		}
 	}
 
 	if filterConfig == nil {
-		return false
+		return false, "no filter config"
 	}
 
-	var filterEntityType types.FilterEntityType
-
-	switch item.GetItem().(type) {
-	case types.Access:
-		if filterType.IsInclusive() {
-			filterEntityType = types.IncludeAccess
-		} else {
-			filterEntityType = types.ExcludeAccess
-		}
-	case types.Instance, types.Stack, types.Database, types.Disk, types.Alert, types.Storage:
-		if filterType.IsInclusive() {
-			filterEntityType = types.IncludeInstance
-		} else {
-			filterEntityType = types.ExcludeInstance
-		}
-	default:
+	filterEntityTypes := getFilterEntityTypes(item, filterType)
+	if len(filterEntityTypes) == 0 {
 		log.Warnf("Filtering is not implemented for type %s", reflect.TypeOf(item))
-		return false
+		return false, fmt.Sprintf("filtering is not implemented for type %s", reflect.TypeOf(item))
 	}
 
 	filtered, applied := false, false
-
-	if names := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Name); names != nil {
-		log.Debugf("[%s] filtering item %s to names [%s]", filterName, item.GetName(), names)
-		filtered, applied = filtered || utils.IsStartsWith(item.GetName(), names...), true
+	var reason string
+	matchRule := func(match bool, filterEntityType types.FilterEntityType, rule string) {
+		if match && !filtered {
+			reason = fmt.Sprintf("%s %s", filterEntityType, rule)
+		}
+		filtered, applied = filtered || match, true
 	}
 
-	if owners := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Owner); owners != nil {
-		log.Debugf("[%s] filtering item %s with exact match '%t' to owners [%s]", filterName, item.GetName(), ctx.ExactMatchOwner, owners)
-		var ownerMatch bool
-		if ctx.ExactMatchOwner {
-			ownerMatch = utils.IsAnyEquals(item.GetOwner(), owners...)
-		} else {
-			ownerMatch = utils.IsStartsWith(item.GetOwner(), owners...)
+	for _, filterEntityType := range filterEntityTypes {
+		if names := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Name); names != nil {
+			log.Debugf("[%s] filtering item %s to names [%s]", filterName, item.GetName(), names)
+			matchRule(utils.IsStartsWith(item.GetName(), names...), filterEntityType, fmt.Sprintf("name %s", names))
+		}
+
+		if owners := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Owner); owners != nil {
+			log.Debugf("[%s] filtering item %s with exact match '%t' to owners [%s]", filterName, item.GetName(), ctx.ExactMatchOwner, owners)
+			var ownerMatch bool
+			if ctx.ExactMatchOwner {
+				ownerMatch = utils.IsAnyEquals(item.GetOwner(), owners...)
+			} else {
+				ownerMatch = utils.IsStartsWith(item.GetOwner(), owners...)
+			}
+			matchRule(ownerMatch, filterEntityType, fmt.Sprintf("owner %s", owners))
+		}
+
+		if labels := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Label); labels != nil {
+			log.Debugf("[%s] filtering item %s to labels [%s]", filterName, item.GetName(), labels)
+			matchRule(utils.IsAnyLabelMatch(item.GetTags(), item.GetCloudType() == types.GCP, labels...), filterEntityType, fmt.Sprintf("label %s", labels))
 		}
-		filtered, applied = filtered || ownerMatch, true
-	}
 
-	if labels := filterConfig.GetFilterValues(filterEntityType, item.GetCloudType(), types.Label); labels != nil {
-		log.Debugf("[%s] filtering item %s to labels [%s]", filterName, item.GetName(), labels)
-		filtered, applied = filtered || utils.IsAnyStartsWith(item.GetTags(), labels...), true
+		if conditional, ok := filterConfig.(types.ConditionalFilterConfig); ok {
+			for _, conditions := range conditional.GetConditions(filterEntityType, item.GetCloudType()) {
+				log.Debugf("[%s] filtering item %s to conditions %v", filterName, item.GetName(), conditions)
+				matchRule(isConditionsMatch(item, conditions), filterEntityType, fmt.Sprintf("conditions %s", formatConditions(conditions)))
+			}
+		}
 	}
 
 	if applied {
 		if filtered {
 			log.Debugf("[%s] item %s matches filter", filterName, item.GetName())
-		} else {
-			log.Debugf("[%s] item %s does not match filter", filterName, item.GetName())
+			return true, reason
 		}
-		return filtered
-	} else {
-		log.Debugf("[%s] item %s could not be filtered", filterName, item.GetName())
+		log.Debugf("[%s] item %s does not match filter", filterName, item.GetName())
+		return false, fmt.Sprintf("no %s rule matches", filterEntityTypes)
 	}
+	log.Debugf("[%s] item %s could not be filtered", filterName, item.GetName())
+	return false, fmt.Sprintf("no %s rule for %s", filterEntityTypes, item.GetCloudType())
+}
 
-	return false
+// The rules of the instances apply to every item type that was filtered by them before the item specific entity types were introduced
+func getFilterEntityTypes(item types.CloudItem, filterType types.FilterConfigType) []types.FilterEntityType {
+	pick := func(include, exclude types.FilterEntityType) types.FilterEntityType {
+		if filterType.IsInclusive() {
+			return include
+		}
+		return exclude
+	}
+	instance := pick(types.IncludeInstance, types.ExcludeInstance)
+	switch item.GetItem().(type) {
+	case types.Access:
+		return []types.FilterEntityType{pick(types.IncludeAccess, types.ExcludeAccess)}
+	case types.Instance:
+		return []types.FilterEntityType{instance}
+	case types.Stack:
+		return []types.FilterEntityType{pick(types.IncludeStack, types.ExcludeStack), instance}
+	case types.Database:
+		return []types.FilterEntityType{pick(types.IncludeDatabase, types.ExcludeDatabase), instance}
+	case types.Disk:
+		return []types.FilterEntityType{pick(types.IncludeDisk, types.ExcludeDisk), instance}
+	case types.Alert:
+		return []types.FilterEntityType{pick(types.IncludeAlert, types.ExcludeAlert), instance}
+	case types.Storage:
+		return []types.FilterEntityType{pick(types.IncludeStorage, types.ExcludeStorage), instance}
+	case types.Image:
+		return []types.FilterEntityType{pick(types.IncludeImage, types.ExcludeImage)}
+	}
+	return nil
+}
+
+func isConditionsMatch(item types.CloudItem, conditions []types.FilterCondition) bool {
+	for _, condition := range conditions {
+		if !condition.Matches(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatConditions(conditions []types.FilterCondition) string {
+	var formatted []string
+	for _, c := range conditions {
+		value := c.Value
+		if c.Operator == types.In {
+			value = fmt.Sprintf("%s", c.Values)
+		}
+		formatted = append(formatted, fmt.Sprintf("%s %s %s", c.Attribute, c.Operator, value))
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
 }
@@ -2,6 +2,7 @@ package operation
 
 import (
 	"testing"
+	"time"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
 	"github.com/hortonworks/cloud-haunter/types"
@@ -321,3 +322,178 @@ func TestExclusiveFilter(t *testing.T) {
 
 	assert.Equal(t, 3, len(filtered))
 }
+
+func TestMatchFilterConfig(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.GCP, Name: "instance-1", Owner: "alice"},
+		&types.Instance{CloudType: types.GCP, Name: "instance-2", Owner: "bob"},
+		&types.Instance{CloudType: types.AWS, Name: "instance-3", Owner: "alice"},
+		&types.Instance{CloudType: types.GCP, Name: "ignored", Owner: "alice", Tags: types.Tags{ctx.IgnoreLabel: "true"}},
+	}
+	filterConfig := types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{{
+		Types:      []types.FilterEntityType{types.IncludeInstance},
+		CloudTypes: []types.CloudType{"gcp"},
+		Properties: []types.FilterConfigProperty{types.Owner},
+		Values:     []string{"alice"},
+	}}}
+
+	matching := MatchFilterConfig(items, filterConfig)
+
+	assert.Equal(t, 1, len(matching))
+	assert.Equal(t, "instance-1", matching[0].GetName())
+}
+
+func TestFilterConfigConditions(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, Name: "excluded", InstanceType: "m5.24xlarge", Region: "us-east-1", Tags: types.Tags{"team": "ml"}},
+		&types.Instance{CloudType: types.AWS, Name: "other-team", InstanceType: "m5.24xlarge", Region: "us-east-1", Tags: types.Tags{"team": "web"}},
+		&types.Instance{CloudType: types.AWS, Name: "other-region", InstanceType: "m5.24xlarge", Region: "eu-west-1", Tags: types.Tags{"team": "ml"}},
+		&types.Instance{CloudType: types.GCP, Name: "other-cloud", InstanceType: "m5.24xlarge", Region: "us-east-1", Tags: types.Tags{"team": "ml"}},
+		&types.Disk{CloudType: types.AWS, Name: "large-disk", Size: 2000},
+		&types.Disk{CloudType: types.AWS, Name: "small-disk", Size: 100},
+	}
+	filterConfig, err := utils.LoadFilterConfigV2("../utils/testdata/filterConfigV2Conditions.yml")
+	assert.Nil(t, err)
+
+	var notFiltered []string
+	for _, item := range items {
+		if !isFilterMatch("TEST", item, types.ExclusiveFilter, filterConfig) {
+			notFiltered = append(notFiltered, item.GetName())
+		}
+	}
+
+	assert.Equal(t, []string{"other-team", "other-region", "other-cloud", "small-disk"}, notFiltered)
+}
+
+func TestFilterConfigEntityTypes(t *testing.T) {
+	filterConfig := types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{
+		{
+			Types:      []types.FilterEntityType{types.IncludeImage, types.IncludeStorage},
+			CloudTypes: []types.CloudType{"aws"},
+			Properties: []types.FilterConfigProperty{types.Name},
+			Values:     []string{"keep"},
+		},
+		{
+			Types:      []types.FilterEntityType{types.IncludeInstance},
+			CloudTypes: []types.CloudType{"aws"},
+			Properties: []types.FilterConfigProperty{types.Owner},
+			Values:     []string{"alice"},
+		},
+	}}
+
+	assert.True(t, isFilterMatch("TEST", &types.Image{CloudType: types.AWS, Name: "keep-image"}, types.InclusiveFilter, filterConfig))
+	assert.False(t, isFilterMatch("TEST", &types.Image{CloudType: types.AWS, Name: "other"}, types.InclusiveFilter, filterConfig))
+	assert.True(t, isFilterMatch("TEST", &types.Storage{CloudType: types.AWS, Name: "keep-bucket"}, types.InclusiveFilter, filterConfig))
+	assert.True(t, isFilterMatch("TEST", &types.Storage{CloudType: types.AWS, Name: "other", Owner: "alice"}, types.InclusiveFilter, filterConfig))
+	assert.False(t, isFilterMatch("TEST", &types.Instance{CloudType: types.AWS, Name: "keep-instance"}, types.InclusiveFilter, filterConfig))
+}
+
+func TestFilterConditionOperators(t *testing.T) {
+	created := time.Now().Add(-48 * time.Hour)
+	instance := &types.Instance{CloudType: types.AWS, Name: "worker-1", InstanceType: "m5.xlarge", State: types.Running, Created: created,
+		Metadata: map[string]string{"cluster": "prod", "nodes": "3", "ttl": "1h"}}
+
+	for _, tc := range []struct {
+		condition types.FilterCondition
+		matches   bool
+	}{
+		{types.FilterCondition{Attribute: "name", Operator: types.Equals, Value: "worker-1"}, true},
+		{types.FilterCondition{Attribute: "name", Operator: types.Prefix, Value: "work"}, true},
+		{types.FilterCondition{Attribute: "name", Operator: types.Regex, Value: "^worker-[0-9]+$"}, true},
+		{types.FilterCondition{Attribute: "instanceType", Operator: types.Glob, Value: "m5.*"}, true},
+		{types.FilterCondition{Attribute: "instanceType", Operator: types.Glob, Value: "c5.*"}, false},
+		{types.FilterCondition{Attribute: "state", Operator: types.In, Values: []string{"stopped", "running"}}, true},
+		{types.FilterCondition{Attribute: "age", Operator: types.GreaterThan, Value: "24h"}, true},
+		{types.FilterCondition{Attribute: "age", Operator: types.LessThan, Value: "24h"}, false},
+		{types.FilterCondition{Attribute: "metadata:cluster", Operator: types.Equals, Value: "prod"}, true},
+		{types.FilterCondition{Attribute: "tag:team", Operator: types.Equals, Value: ""}, false},
+		{types.FilterCondition{Attribute: "size", Operator: types.GreaterThan, Value: "0"}, false},
+		{types.FilterCondition{Attribute: "metadata:nodes", Operator: types.GreaterThan, Value: "2"}, true},
+		{types.FilterCondition{Attribute: "metadata:ttl", Operator: types.LessThan, Value: "2h"}, true},
+		// the value of the item is compared only if it is of the same kind as the value of the condition
+		{types.FilterCondition{Attribute: "metadata:ttl", Operator: types.LessThan, Value: "2"}, false},
+	} {
+		assert.Nil(t, tc.condition.Validate())
+		assert.Equal(t, tc.matches, tc.condition.Matches(instance), "%v", tc.condition)
+	}
+
+	// the conditions that are not validated compile their regular expression on every match
+	assert.True(t, types.FilterCondition{Attribute: "name", Operator: types.Regex, Value: "^worker-[0-9]+$"}.Matches(instance))
+	assert.False(t, types.FilterCondition{Attribute: "name", Operator: types.Regex, Value: "("}.Matches(instance))
+
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "color", Operator: types.Equals, Value: "red"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "name", Operator: types.Regex, Value: "("}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "size", Operator: types.GreaterThan, Value: "large"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "size", Operator: types.GreaterThan, Value: "24h"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "age", Operator: types.GreaterThan, Value: "30"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "name", Operator: types.LessThan, Value: "10"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "tag:ttl", Operator: types.LessThan, Value: "soon"}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "state", Operator: types.In}).Validate())
+	assert.NotNil(t, (&types.FilterCondition{Attribute: "tag:", Operator: types.Equals, Value: "ml"}).Validate())
+}
+
+func TestLabelMatch(t *testing.T) {
+	filterConfigV1, err := utils.LoadFilterConfig("testdata/sample-labels.yml")
+	assert.Nil(t, err)
+	filterConfigV2 := types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{
+		{
+			Types:      []types.FilterEntityType{types.ExcludeInstance},
+			CloudTypes: []types.CloudType{"aws", "azure"},
+			Properties: []types.FilterConfigProperty{types.Label},
+			Values:     []string{"env=prod", "team=data*"},
+		},
+		{
+			Types:      []types.FilterEntityType{types.ExcludeInstance},
+			CloudTypes: []types.CloudType{"gcp"},
+			Properties: []types.FilterConfigProperty{types.Label},
+			Values:     []string{"Env=Prod", "Team=Data*"},
+		},
+		{
+			Types:      []types.FilterEntityType{types.IncludeInstance},
+			CloudTypes: []types.CloudType{"aws", "azure"},
+			Properties: []types.FilterConfigProperty{types.Label},
+			Values:     []string{"env!=prod"},
+		},
+		{
+			Types:      []types.FilterEntityType{types.IncludeInstance},
+			CloudTypes: []types.CloudType{"gcp"},
+			Properties: []types.FilterConfigProperty{types.Label},
+			Values:     []string{"ENV!=PROD"},
+		},
+	}}
+
+	for _, filterConfig := range []types.IFilterConfig{filterConfigV1, filterConfigV2} {
+		for _, cloud := range []types.CloudType{types.AWS, types.AZURE, types.GCP} {
+			for _, tc := range []struct {
+				tags     types.Tags
+				excluded bool
+				included bool
+			}{
+				{types.Tags{"env": "prod"}, true, false},
+				{types.Tags{"env": "dev"}, false, true},
+				{types.Tags{"team": "database"}, true, true},
+				{types.Tags{"team": "web"}, false, true},
+				{types.Tags{"environment": "prod"}, false, true},
+				{types.Tags{}, false, true},
+			} {
+				item := &types.Instance{CloudType: cloud, Name: "instance", Tags: tc.tags}
+				assert.Equal(t, tc.excluded, isFilterMatch("TEST", item, types.ExclusiveFilter, filterConfig), "%s %v excluded", cloud, tc.tags)
+				assert.Equal(t, tc.included, isFilterMatch("TEST", item, types.InclusiveFilter, filterConfig), "%s %v included", cloud, tc.tags)
+			}
+		}
+	}
+}
+
+func TestLabelMatchIsCaseSensitiveExceptOnGCP(t *testing.T) {
+	filterConfig := types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{{
+		Types:      []types.FilterEntityType{types.ExcludeInstance},
+		CloudTypes: []types.CloudType{"aws", "azure", "gcp"},
+		Properties: []types.FilterConfigProperty{types.Label},
+		Values:     []string{"Env=Prod"},
+	}}}
+
+	assert.False(t, isFilterMatch("TEST", &types.Instance{CloudType: types.AWS, Tags: types.Tags{"env": "prod"}}, types.ExclusiveFilter, filterConfig))
+	assert.True(t, isFilterMatch("TEST", &types.Instance{CloudType: types.AWS, Tags: types.Tags{"Env": "Prod"}}, types.ExclusiveFilter, filterConfig))
+	assert.False(t, isFilterMatch("TEST", &types.Instance{CloudType: types.AZURE, Tags: types.Tags{"env": "prod"}}, types.ExclusiveFilter, filterConfig))
+	assert.True(t, isFilterMatch("TEST", &types.Instance{CloudType: types.GCP, Tags: types.Tags{"env": "prod"}}, types.ExclusiveFilter, filterConfig))
+}
@@ -0,0 +1,132 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	monthlyCost = "monthly"
+	accruedCost = "accrued"
+
+	itemMode  = "item"
+	ownerMode = "owner"
+)
+
+var defaultCostThreshold = 500.0
+
+type costly struct {
+	threshold float64
+	// monthly or accrued
+	cost string
+	// in owner mode the threshold is the budget of the owners, all the items of the owners above the budget are passed
+	mode string
+}
+
+func init() {
+	f := costly{threshold: defaultCostThreshold, cost: monthlyCost, mode: itemMode}
+	if thresholdEnv := os.Getenv("COSTLY_THRESHOLD"); len(thresholdEnv) > 0 {
+		threshold, err := strconv.ParseFloat(thresholdEnv, 64)
+		if err != nil {
+			log.Errorf("[COSTLY] err: %s", err)
+			return
+		}
+		f.threshold = threshold
+	}
+	if costEnv := os.Getenv("COSTLY_COST"); len(costEnv) > 0 {
+		f.cost = costEnv
+	}
+	if modeEnv := os.Getenv("COSTLY_MODE"); len(modeEnv) > 0 {
+		f.mode = modeEnv
+	}
+	if err := f.validate(); err != nil {
+		log.Errorf("[COSTLY] err: %s", err)
+		return
+	}
+	log.Infof("[COSTLY] threshold set to: %.2f, cost: %s, mode: %s", f.threshold, f.cost, f.mode)
+	ctx.Filters[types.CostlyFilter] = f
+}
+
+func (f costly) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "threshold":
+			threshold, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("[COSTLY] invalid threshold: %s, err: %s", v, err)
+			}
+			f.threshold = threshold
+		case "cost":
+			f.cost = v
+		case "mode":
+			f.mode = v
+		default:
+			return nil, fmt.Errorf("[COSTLY] unknown parameter: %s", k)
+		}
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f costly) validate() error {
+	if f.cost != monthlyCost && f.cost != accruedCost {
+		return fmt.Errorf("[COSTLY] invalid cost: %s, valid values: %s, %s", f.cost, monthlyCost, accruedCost)
+	}
+	if f.mode != itemMode && f.mode != ownerMode {
+		return fmt.Errorf("[COSTLY] invalid mode: %s, valid values: %s, %s", f.mode, itemMode, ownerMode)
+	}
+	return nil
+}
+
+func (f costly) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[COSTLY] Filtering items (%d): [%s]", len(items), items)
+	if f.mode == ownerMode {
+		return f.filterOwners(items)
+	}
+	return filter("COSTLY", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		cost := types.GetCost(item)
+		if cost == nil {
+			log.Debugf("[COSTLY] Filter %s, because its cost is unknown: %s", item.GetType(), item.GetName())
+			return false
+		}
+		match := f.getCost(*cost) > f.threshold
+		log.Debugf("[COSTLY] %s: %s %s cost: %.2f match: %v", item.GetType(), item.GetName(), f.cost, f.getCost(*cost), match)
+		return match
+	})
+}
+
+// The items without owner are not considered, as they do not belong to the same budget
+func (f costly) filterOwners(items []types.CloudItem) []types.CloudItem {
+	ownerCosts := map[string]float64{}
+	for _, item := range items {
+		if cost := types.GetCost(item); cost != nil {
+			ownerCosts[item.GetOwner()] += f.getCost(*cost)
+		}
+	}
+	for owner, cost := range ownerCosts {
+		log.Debugf("[COSTLY] Owner: %s %s cost: %.2f", owner, f.cost, cost)
+	}
+	return filter("COSTLY", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		if owner := item.GetOwner(); len(owner) == 0 || owner == "???" {
+			log.Debugf("[COSTLY] Filter %s, because it does not have an owner: %s", item.GetType(), item.GetName())
+			return false
+		}
+		match := ownerCosts[item.GetOwner()] > f.threshold
+		log.Debugf("[COSTLY] %s: %s owner: %s match: %v", item.GetType(), item.GetName(), item.GetOwner(), match)
+		return match
+	})
+}
+
+func (f costly) getCost(cost types.Cost) float64 {
+	if f.cost == accruedCost {
+		return cost.Accrued
+	}
+	return cost.GetMonthly()
+}
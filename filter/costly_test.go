@@ -0,0 +1,68 @@
+package operation
+
+import (
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostlyInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.CostlyFilter])
+}
+
+func getCostlyItems() []types.CloudItem {
+	return []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, Name: "cheap", Owner: "alice", Cost: &types.Cost{Hourly: 0.1, Accrued: 1000}},
+		&types.Instance{CloudType: types.AWS, Name: "expensive", Owner: "alice", Cost: &types.Cost{Hourly: 1, Accrued: 10}},
+		&types.Disk{CloudType: types.AWS, Name: "disk", Owner: "bob", Cost: &types.Cost{Hourly: 0.5, Accrued: 50}},
+		&types.Database{CloudType: types.AWS, Name: "database", Owner: "bob", Cost: &types.Cost{Hourly: 0.5, Accrued: 50}},
+		&types.Instance{CloudType: types.AWS, Name: "unknown cost", Owner: "bob"},
+		&types.Instance{CloudType: types.AWS, Name: "ownerless", Cost: &types.Cost{Hourly: 2, Accrued: 2000}},
+	}
+}
+
+func getNames(items []types.CloudItem) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, item.GetName())
+	}
+	return names
+}
+
+func TestCostlyFilterMonthly(t *testing.T) {
+	filteredItems := costly{threshold: 300, cost: monthlyCost, mode: itemMode}.Execute(getCostlyItems())
+
+	assert.Equal(t, []string{"expensive", "disk", "database", "ownerless"}, getNames(filteredItems))
+}
+
+func TestCostlyFilterAccrued(t *testing.T) {
+	filteredItems := costly{threshold: 100, cost: accruedCost, mode: itemMode}.Execute(getCostlyItems())
+
+	assert.Equal(t, []string{"cheap", "ownerless"}, getNames(filteredItems))
+}
+
+func TestCostlyFilterOwnerBudget(t *testing.T) {
+	filteredItems := costly{threshold: 750, cost: monthlyCost, mode: ownerMode}.Execute(getCostlyItems())
+
+	assert.Equal(t, []string{"cheap", "expensive"}, getNames(filteredItems))
+
+	filteredItems = costly{threshold: 700, cost: monthlyCost, mode: ownerMode}.Execute(getCostlyItems())
+
+	assert.Equal(t, []string{"cheap", "expensive", "disk", "database", "unknown cost"}, getNames(filteredItems))
+}
+
+func TestCostlyWithParameters(t *testing.T) {
+	filter, err := costly{threshold: defaultCostThreshold, cost: monthlyCost, mode: itemMode}.WithParameters(map[string]string{"threshold": "1000", "cost": "accrued", "mode": "owner"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, costly{threshold: 1000, cost: accruedCost, mode: ownerMode}, filter)
+
+	_, err = costly{cost: monthlyCost, mode: itemMode}.WithParameters(map[string]string{"cost": "yearly"})
+	assert.NotNil(t, err)
+	_, err = costly{cost: monthlyCost, mode: itemMode}.WithParameters(map[string]string{"mode": "team"})
+	assert.NotNil(t, err)
+	_, err = costly{cost: monthlyCost, mode: itemMode}.WithParameters(map[string]string{"budget": "10"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,131 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultExpiryTags = []string{"expires-at", "ttl"}
+
+// GCP label values cannot contain colons, so the date is accepted without time as well
+var expiryDateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+type expired struct {
+	expiryTags    []string
+	defaultPeriod time.Duration
+}
+
+func init() {
+	expiryTags := defaultExpiryTags
+	if tagsEnv := os.Getenv("EXPIRY_TAGS"); len(tagsEnv) > 0 {
+		expiryTags = splitExpiryTags(tagsEnv)
+	}
+	runningEnv := os.Getenv("RUNNING_PERIOD")
+	var defaultPeriod time.Duration
+	if len(runningEnv) > 0 {
+		duration, err := time.ParseDuration(runningEnv)
+		if err != nil {
+			log.Errorf("[EXPIRED] err: %s", err)
+			return
+		}
+		defaultPeriod = duration
+	} else {
+		defaultPeriod = defaultRunningPeriod
+	}
+	log.Infof("[EXPIRED] expiry tags set to: %s, default period set to: %s", expiryTags, defaultPeriod)
+	ctx.Filters[types.ExpiredFilter] = expired{expiryTags, defaultPeriod}
+}
+
+func (f expired) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[EXPIRED] invalid period: %s, err: %s", v, err)
+			}
+			f.defaultPeriod = duration
+		case "tags":
+			f.expiryTags = splitExpiryTags(v)
+		default:
+			return nil, fmt.Errorf("[EXPIRED] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
+// The protected items are not reported, as the filter would not select them anyway
+func (f expired) Validate(items []types.CloudItem) []types.InvalidItem {
+	var invalidItems []types.InvalidItem
+	for _, item := range RemoveExcluded("EXPIRED", items) {
+		if _, err := f.getExpiry(item); err != nil {
+			invalidItems = append(invalidItems, types.InvalidItem{Item: item, Err: err})
+		}
+	}
+	return invalidItems
+}
+
+func (f expired) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[EXPIRED] Filtering items (%d): [%s]", len(items), items)
+	now := time.Now()
+	return filter("EXPIRED", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		expiry, err := f.getExpiry(item)
+		if err != nil {
+			log.Warnf("[EXPIRED] Filter %s, because its expiry is invalid: %s, err: %s", item.GetType(), item.GetName(), err)
+			return false
+		}
+		match := expiry.Before(now)
+		log.Debugf("[EXPIRED] %s: %s expiry: %s match: %v", item.GetType(), item.GetName(), expiry, match)
+		return match
+	})
+}
+
+// The first expiry tag of the item is used in the configured order, which can be an absolute date or a duration relative to the creation time
+func (f expired) getExpiry(item types.CloudItem) (time.Time, error) {
+	tags := item.GetTags()
+	for _, key := range f.expiryTags {
+		value, ok := tags[key]
+		if !ok {
+			continue
+		}
+		if duration, err := parseExpiryDuration(value); err == nil {
+			return item.GetCreated().Add(duration), nil
+		}
+		for _, layout := range expiryDateLayouts {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid value of expiry tag %s: %s", key, value)
+	}
+	return item.GetCreated().Add(f.defaultPeriod), nil
+}
+
+// Besides the standard durations the number of days are accepted as well, e.g. 7d
+func parseExpiryDuration(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func splitExpiryTags(tags string) []string {
+	var expiryTags []string
+	for _, tag := range strings.Split(tags, ",") {
+		if trimmed := strings.TrimSpace(tag); len(trimmed) != 0 {
+			expiryTags = append(expiryTags, trimmed)
+		}
+	}
+	return expiryTags
+}
@@ -0,0 +1,92 @@
+package operation
+
+import (
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.ExpiredFilter])
+}
+
+func TestExpiredFilter(t *testing.T) {
+	now := time.Now()
+	items := []types.CloudItem{
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "not expired default",
+			Created:   now.Add(-defaultRunningPeriod).Add(1 * time.Minute),
+		},
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "expired default",
+			Created:   now.Add(-defaultRunningPeriod).Add(-1 * time.Minute),
+		},
+		&types.Disk{
+			CloudType: types.GCP,
+			Name:      "not expired ttl",
+			Created:   now.Add(-48 * time.Hour),
+			Tags:      types.Tags{"ttl": "3d"},
+		},
+		&types.Stack{
+			CloudType: types.AZURE,
+			Name:      "expired ttl",
+			Created:   now.Add(-48 * time.Hour),
+			Tags:      types.Tags{"ttl": "36h"},
+		},
+		&types.Database{
+			CloudType: types.AWS,
+			Name:      "not expired date",
+			Created:   now.Add(-48 * time.Hour),
+			Tags:      types.Tags{"expires-at": now.Add(48 * time.Hour).Format("2006-01-02")},
+		},
+		&types.Access{
+			CloudType: types.AWS,
+			Name:      "expired date",
+			Created:   now,
+			Tags:      types.Tags{"expires-at": now.Add(-1 * time.Hour).Format(time.RFC3339), "ttl": "72h"},
+		},
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "invalid",
+			Created:   now.Add(-48 * time.Hour),
+			Tags:      types.Tags{"ttl": "forever"},
+		},
+	}
+
+	filteredItems := expired{defaultExpiryTags, defaultRunningPeriod}.Execute(items)
+
+	assert.Equal(t, 3, len(filteredItems))
+	assert.Equal(t, "expired default", filteredItems[0].GetName())
+	assert.Equal(t, "expired ttl", filteredItems[1].GetName())
+	assert.Equal(t, "expired date", filteredItems[2].GetName())
+}
+
+func TestExpiredValidate(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{Name: "valid", CloudType: types.AWS, Tags: types.Tags{"ttl": "24h"}},
+		&types.Instance{Name: "invalid", CloudType: types.AWS, Tags: types.Tags{"expires-at": "tomorrow"}},
+		&types.Instance{Name: "ignored", CloudType: types.AWS, Tags: types.Tags{"expires-at": "tomorrow", ctx.IgnoreLabel: "true"}},
+	}
+
+	invalidItems := expired{defaultExpiryTags, defaultRunningPeriod}.Validate(items)
+
+	assert.Equal(t, 1, len(invalidItems))
+	assert.Equal(t, "invalid", invalidItems[0].Item.GetName())
+	assert.Equal(t, "invalid value of expiry tag expires-at: tomorrow", invalidItems[0].Err.Error())
+}
+
+func TestExpiredWithParameters(t *testing.T) {
+	filter, err := expired{defaultExpiryTags, defaultRunningPeriod}.WithParameters(map[string]string{"period": "6h", "tags": "lifetime, expiry"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 6*time.Hour, filter.(expired).defaultPeriod)
+	assert.Equal(t, []string{"lifetime", "expiry"}, filter.(expired).expiryTags)
+
+	_, err = expired{defaultExpiryTags, defaultRunningPeriod}.WithParameters(map[string]string{"ttl": "6h"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,47 @@
+package operation
+
+import (
+	"sync"
+
+	"github.com/hortonworks/cloud-haunter/types"
+)
+
+// FilterDecision is the result of a filter on an item: whether it meets the criteria of the filter and whether the filter config
+// lets it through, with the rule of the filter config that decided
+type FilterDecision struct {
+	Filter    string
+	Item      types.CloudItem
+	Needed    bool
+	Included  bool
+	Inclusive bool
+	Reason    string
+}
+
+var (
+	decisionLock sync.Mutex
+	decisions    []FilterDecision
+	recording    bool
+)
+
+// RecordDecisions collects the decisions of the filters until the returned function is called, which returns them.
+// The filters that do not use the filter config do not record decisions.
+func RecordDecisions() func() []FilterDecision {
+	decisionLock.Lock()
+	decisions, recording = nil, true
+	decisionLock.Unlock()
+	return func() []FilterDecision {
+		decisionLock.Lock()
+		defer decisionLock.Unlock()
+		recorded := decisions
+		decisions, recording = nil, false
+		return recorded
+	}
+}
+
+func recordDecision(decision FilterDecision) {
+	decisionLock.Lock()
+	defer decisionLock.Unlock()
+	if recording {
+		decisions = append(decisions, decision)
+	}
+}
@@ -0,0 +1,148 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultIdlePeriod = 24 * time.Hour
+
+// percent
+var defaultCPUThreshold = 5.0
+
+type idle struct {
+	period       time.Duration
+	cpuThreshold float64
+	// bytes per second, the network utilization is not checked if it's not positive
+	networkThreshold float64
+}
+
+func init() {
+	f := idle{period: defaultIdlePeriod, cpuThreshold: defaultCPUThreshold}
+	if periodEnv := os.Getenv("IDLE_PERIOD"); len(periodEnv) > 0 {
+		duration, err := time.ParseDuration(periodEnv)
+		if err != nil {
+			log.Errorf("[IDLE] err: %s", err)
+			return
+		}
+		f.period = duration
+	}
+	if cpuEnv := os.Getenv("IDLE_CPU_THRESHOLD"); len(cpuEnv) > 0 {
+		threshold, err := strconv.ParseFloat(cpuEnv, 64)
+		if err != nil {
+			log.Errorf("[IDLE] err: %s", err)
+			return
+		}
+		f.cpuThreshold = threshold
+	}
+	if networkEnv := os.Getenv("IDLE_NETWORK_THRESHOLD"); len(networkEnv) > 0 {
+		threshold, err := strconv.ParseFloat(networkEnv, 64)
+		if err != nil {
+			log.Errorf("[IDLE] err: %s", err)
+			return
+		}
+		f.networkThreshold = threshold
+	}
+	log.Infof("[IDLE] period set to: %s, CPU threshold set to: %.2f%%, network threshold set to: %.0f B/s", f.period, f.cpuThreshold, f.networkThreshold)
+	ctx.Filters[types.IdleFilter] = f
+}
+
+func (f idle) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[IDLE] invalid period: %s, err: %s", v, err)
+			}
+			f.period = duration
+		case "cpu":
+			threshold, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("[IDLE] invalid CPU threshold: %s, err: %s", v, err)
+			}
+			f.cpuThreshold = threshold
+		case "network":
+			threshold, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("[IDLE] invalid network threshold: %s, err: %s", v, err)
+			}
+			f.networkThreshold = threshold
+		default:
+			return nil, fmt.Errorf("[IDLE] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
+func (f idle) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[IDLE] Filtering items (%d): [%s]", len(items), items)
+	utilizations := f.getUtilizations(items)
+	return filter("IDLE", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		if !isIdleCandidate(item) {
+			log.Debugf("[IDLE] Filter %s, because it's not a running instance or database: %s", item.GetType(), item.GetName())
+			return false
+		}
+		utilization, ok := utilizations[item.GetCloudType()][item.GetID()]
+		if !ok {
+			log.Debugf("[IDLE] Filter %s, because its utilization is unknown: %s", item.GetType(), item.GetName())
+			return false
+		}
+		match := utilization.CPU < f.cpuThreshold && (f.networkThreshold <= 0 || utilization.Network < f.networkThreshold)
+		log.Debugf("[IDLE] %s: %s CPU: %.2f%% network: %.0f B/s match: %v", item.GetType(), item.GetName(), utilization.CPU, utilization.Network, match)
+		return match
+	})
+}
+
+// The utilizations are fetched from the cloud providers in parallel, one request per cloud with all the items
+func (f idle) getUtilizations(items []types.CloudItem) map[types.CloudType]map[string]types.Utilization {
+	cloudItems := map[types.CloudType][]types.CloudItem{}
+	for _, item := range items {
+		if isIdleCandidate(item) {
+			cloudItems[item.GetCloudType()] = append(cloudItems[item.GetCloudType()], item)
+		}
+	}
+
+	utilizations := map[types.CloudType]map[string]types.Utilization{}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(len(cloudItems))
+	for c, i := range cloudItems {
+		go func(cloud types.CloudType, itemsInCloud []types.CloudItem) {
+			defer wg.Done()
+
+			provider, ok := ctx.CloudProviders[cloud]
+			if !ok {
+				log.Warnf("[IDLE] Cloud provider not found: %s", cloud)
+				return
+			}
+			result, errs := provider().GetUtilizations(itemsInCloud, f.period)
+			for _, err := range errs {
+				log.Errorf("[IDLE] Failed to fetch utilization on %s, err: %s", cloud, err)
+			}
+			lock.Lock()
+			utilizations[cloud] = result
+			lock.Unlock()
+		}(c, i)
+	}
+	wg.Wait()
+	return utilizations
+}
+
+func isIdleCandidate(item types.CloudItem) bool {
+	switch t := item.GetItem().(type) {
+	case types.Instance:
+		return t.State == types.Running
+	case types.Database:
+		return t.State == types.Running
+	default:
+		return false
+	}
+}
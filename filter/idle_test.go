@@ -0,0 +1,76 @@
+package operation
+
+import (
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type utilizationProvider struct {
+	types.CloudProvider
+	utilizations map[string]types.Utilization
+}
+
+func (p utilizationProvider) GetUtilizations(items []types.CloudItem, period time.Duration) (map[string]types.Utilization, []error) {
+	return p.utilizations, nil
+}
+
+func TestIdleInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.IdleFilter])
+}
+
+func TestIdleFilter(t *testing.T) {
+	providers := ctx.CloudProviders
+	defer func() {
+		ctx.CloudProviders = providers
+	}()
+	ctx.CloudProviders = map[types.CloudType]func() types.CloudProvider{
+		types.DUMMY: func() types.CloudProvider {
+			return utilizationProvider{utilizations: map[string]types.Utilization{
+				"idle":         {CPU: 1, Network: 10},
+				"busy cpu":     {CPU: 80, Network: 10},
+				"busy network": {CPU: 1, Network: 100000},
+				"stopped":      {CPU: 0, Network: 0},
+				"database":     {CPU: 2, Network: 0},
+			}}
+		},
+	}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.DUMMY, ID: "idle", State: types.Running},
+		&types.Instance{CloudType: types.DUMMY, ID: "busy cpu", State: types.Running},
+		&types.Instance{CloudType: types.DUMMY, ID: "busy network", State: types.Running},
+		&types.Instance{CloudType: types.DUMMY, ID: "stopped", State: types.Stopped},
+		&types.Instance{CloudType: types.DUMMY, ID: "unknown", State: types.Running},
+		&types.Database{CloudType: types.DUMMY, ID: "database", State: types.Running},
+		&types.Disk{CloudType: types.DUMMY, ID: "disk"},
+		&types.Instance{CloudType: types.GCP, ID: "idle", State: types.Running},
+	}
+
+	filteredItems := idle{period: defaultIdlePeriod, cpuThreshold: defaultCPUThreshold}.Execute(items)
+
+	assert.Equal(t, 3, len(filteredItems))
+	assert.Equal(t, "idle", filteredItems[0].GetID())
+	assert.Equal(t, "busy network", filteredItems[1].GetID())
+	assert.Equal(t, "database", filteredItems[2].GetID())
+
+	filteredItems = idle{period: defaultIdlePeriod, cpuThreshold: defaultCPUThreshold, networkThreshold: 1024}.Execute(items)
+
+	assert.Equal(t, 2, len(filteredItems))
+	assert.Equal(t, "idle", filteredItems[0].GetID())
+	assert.Equal(t, "database", filteredItems[1].GetID())
+}
+
+func TestIdleWithParameters(t *testing.T) {
+	filter, err := idle{period: defaultIdlePeriod, cpuThreshold: defaultCPUThreshold}.WithParameters(map[string]string{"period": "168h", "cpu": "10", "network": "2048"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, idle{period: 168 * time.Hour, cpuThreshold: 10, networkThreshold: 2048}, filter)
+
+	_, err = idle{}.WithParameters(map[string]string{"cpu": "low"})
+	assert.NotNil(t, err)
+	_, err = idle{}.WithParameters(map[string]string{"memory": "10"})
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"fmt"
 	"time"
 
 	"os"
@@ -33,6 +34,22 @@ func init() {
 	ctx.Filters[types.LongRunningFilter] = longRunning{runningPeriod}
 }
 
+func (f longRunning) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[LONGRUNNING] invalid period: %s, err: %s", v, err)
+			}
+			f.runningPeriod = duration
+		default:
+			return nil, fmt.Errorf("[LONGRUNNING] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
 func (f longRunning) Execute(items []types.CloudItem) []types.CloudItem {
 	log.Debugf("[LONGRUNNING] Filtering instances (%d): [%s]", len(items), items)
 	now := time.Now()
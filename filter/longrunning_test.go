@@ -34,3 +34,18 @@ func TestLongRunningFilter(t *testing.T) {
 
 	assert.Equal(t, 1, len(filteredItems))
 }
+
+func TestLongRunningWithParameters(t *testing.T) {
+	filter, err := longRunning{defaultRunningPeriod}.WithParameters(map[string]string{"period": "6h"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 6*time.Hour, filter.(longRunning).runningPeriod)
+}
+
+func TestLongRunningWithInvalidParameters(t *testing.T) {
+	_, err := longRunning{defaultRunningPeriod}.WithParameters(map[string]string{"period": "6 hours"})
+	assert.NotNil(t, err)
+
+	_, err = longRunning{defaultRunningPeriod}.WithParameters(map[string]string{"unknown": "6h"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,70 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultStoppedPeriod = 14 * 24 * time.Hour
+
+type longStopped struct {
+	stoppedPeriod time.Duration
+}
+
+func init() {
+	stoppedEnv := os.Getenv("STOPPED_PERIOD")
+	var stoppedPeriod time.Duration
+	if len(stoppedEnv) > 0 {
+		duration, err := time.ParseDuration(stoppedEnv)
+		if err != nil {
+			log.Errorf("[LONGSTOPPED] err: %s", err)
+			return
+		}
+		stoppedPeriod = duration
+	} else {
+		stoppedPeriod = defaultStoppedPeriod
+	}
+	log.Infof("[LONGSTOPPED] stopped period set to: %s", stoppedPeriod)
+	ctx.Filters[types.LongStoppedFilter] = longStopped{stoppedPeriod}
+}
+
+func (f longStopped) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[LONGSTOPPED] invalid period: %s, err: %s", v, err)
+			}
+			f.stoppedPeriod = duration
+		default:
+			return nil, fmt.Errorf("[LONGSTOPPED] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
+// The instances without a known stop time are never matched, the providers set it only for the stopped instances
+func (f longStopped) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[LONGSTOPPED] Filtering items (%d): [%s]", len(items), items)
+	now := time.Now()
+	return filter("LONGSTOPPED", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		instance, ok := item.GetItem().(types.Instance)
+		if !ok {
+			log.Warnf("[LONGSTOPPED] Filter does not apply for cloud item: %s", item.GetName())
+			return false
+		}
+		if instance.State == types.Running || instance.StoppedAt == nil {
+			log.Debugf("[LONGSTOPPED] Filter instance, because it's not stopped or its stop time is unknown: %s", item.GetName())
+			return false
+		}
+		match := instance.StoppedAt.Add(f.stoppedPeriod).Before(now)
+		log.Debugf("[LONGSTOPPED] %s: %s stopped at: %s match: %v", item.GetType(), item.GetName(), instance.StoppedAt, match)
+		return match
+	})
+}
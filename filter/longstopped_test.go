@@ -0,0 +1,43 @@
+package operation
+
+import (
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongStoppedInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.LongStoppedFilter])
+}
+
+func TestLongStoppedFilter(t *testing.T) {
+	longAgo := time.Now().Add(-defaultStoppedPeriod).Add(-1 * time.Minute)
+	recently := time.Now().Add(-defaultStoppedPeriod).Add(1 * time.Minute)
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, Name: "long stopped", State: types.Stopped, StoppedAt: &longAgo},
+		&types.Instance{CloudType: types.GCP, Name: "long stopped on GCP", State: types.Terminated, StoppedAt: &longAgo},
+		&types.Instance{CloudType: types.AWS, Name: "recently stopped", State: types.Stopped, StoppedAt: &recently},
+		&types.Instance{CloudType: types.AWS, Name: "unknown stop time", State: types.Stopped},
+		&types.Instance{CloudType: types.AWS, Name: "running", State: types.Running, StoppedAt: &longAgo},
+		&types.Disk{CloudType: types.AWS, Name: "disk"},
+	}
+
+	filteredItems := longStopped{defaultStoppedPeriod}.Execute(items)
+
+	assert.Equal(t, 2, len(filteredItems))
+	assert.Equal(t, "long stopped", filteredItems[0].GetName())
+	assert.Equal(t, "long stopped on GCP", filteredItems[1].GetName())
+}
+
+func TestLongStoppedWithParameters(t *testing.T) {
+	filter, err := longStopped{defaultStoppedPeriod}.WithParameters(map[string]string{"period": "24h"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, longStopped{24 * time.Hour}, filter)
+
+	_, err = longStopped{defaultStoppedPeriod}.WithParameters(map[string]string{"period": "1 day"})
+	assert.NotNil(t, err)
+}
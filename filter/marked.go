@@ -0,0 +1,71 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultGracePeriod = 72 * time.Hour
+
+type marked struct {
+	gracePeriod time.Duration
+}
+
+func init() {
+	graceEnv := os.Getenv("MARK_GRACE_PERIOD")
+	var gracePeriod time.Duration
+	if len(graceEnv) > 0 {
+		duration, err := time.ParseDuration(graceEnv)
+		if err != nil {
+			log.Errorf("[MARKED] err: %s", err)
+			return
+		}
+		gracePeriod = duration
+	} else {
+		gracePeriod = defaultGracePeriod
+	}
+	log.Infof("[MARKED] grace period set to: %s", gracePeriod)
+	ctx.Filters[types.MarkedFilter] = marked{gracePeriod}
+}
+
+func (f marked) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[MARKED] invalid period: %s, err: %s", v, err)
+			}
+			f.gracePeriod = duration
+		default:
+			return nil, fmt.Errorf("[MARKED] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
+func (f marked) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[MARKED] Filtering items (%d): [%s]", len(items), items)
+	now := time.Now()
+	return filter("MARKED", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		markedAt, ok := item.GetTags()[ctx.MarkedAtLabel]
+		if !ok {
+			log.Debugf("[MARKED] Filter %s, because it's not marked: %s", item.GetType(), item.GetName())
+			return false
+		}
+		timestamp, err := strconv.ParseInt(markedAt, 10, 64)
+		if err != nil {
+			log.Warnf("[MARKED] Filter %s, because the mark is invalid: %s, mark: %s", item.GetType(), item.GetName(), markedAt)
+			return false
+		}
+		match := time.Unix(timestamp, 0).Add(f.gracePeriod).Before(now)
+		log.Debugf("[MARKED] %s: %s match: %v", item.GetType(), item.GetName(), match)
+		return match
+	})
+}
@@ -0,0 +1,55 @@
+package operation
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkedInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.MarkedFilter])
+}
+
+func TestMarkedFilter(t *testing.T) {
+	now := time.Now()
+	items := []types.CloudItem{
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "not marked",
+		},
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "recently marked",
+			Tags:      types.Tags{ctx.MarkedAtLabel: strconv.FormatInt(now.Add(-defaultGracePeriod).Add(1*time.Minute).Unix(), 10)},
+		},
+		&types.Instance{
+			CloudType: types.AWS,
+			Name:      "invalid mark",
+			Tags:      types.Tags{ctx.MarkedAtLabel: "yesterday"},
+		},
+		&types.Disk{
+			CloudType: types.GCP,
+			Name:      "marked",
+			Tags:      types.Tags{ctx.MarkedAtLabel: strconv.FormatInt(now.Add(-defaultGracePeriod).Add(-1*time.Minute).Unix(), 10)},
+		},
+	}
+
+	filteredItems := marked{defaultGracePeriod}.Execute(items)
+
+	assert.Equal(t, 1, len(filteredItems))
+	assert.Equal(t, "marked", filteredItems[0].GetName())
+}
+
+func TestMarkedWithParameters(t *testing.T) {
+	filter, err := marked{defaultGracePeriod}.WithParameters(map[string]string{"period": "24h"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 24*time.Hour, filter.(marked).gracePeriod)
+
+	_, err = marked{defaultGracePeriod}.WithParameters(map[string]string{"grace": "24h"})
+	assert.NotNil(t, err)
+}
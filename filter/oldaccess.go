@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -32,6 +33,22 @@ func init() {
 	ctx.Filters[types.OldAccessFilter] = oldAccess{availablePeriod}
 }
 
+func (f oldAccess) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[OLDACCESS] invalid period: %s, err: %s", v, err)
+			}
+			f.availablePeriod = duration
+		default:
+			return nil, fmt.Errorf("[OLDACCESS] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
 func (f oldAccess) Execute(items []types.CloudItem) []types.CloudItem {
 	log.Debugf("[OLDACCESS] Filtering accesses (%d): [%s]", len(items), items)
 	return filter("OLDACCESS", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
@@ -0,0 +1,80 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/state"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultUnchangedPeriod = 14 * 24 * time.Hour
+
+// unchanged matches the items that are in the same state for longer than the period according to the state store,
+// optionally only the ones in the given state, e.g. stopped for more than 14 days
+type unchanged struct {
+	period time.Duration
+	state  types.State
+}
+
+func init() {
+	period := defaultUnchangedPeriod
+	if periodEnv := os.Getenv("UNCHANGED_PERIOD"); len(periodEnv) > 0 {
+		duration, err := time.ParseDuration(periodEnv)
+		if err != nil {
+			log.Errorf("[UNCHANGED] err: %s", err)
+			return
+		}
+		period = duration
+	}
+	filterState := types.State(os.Getenv("UNCHANGED_STATE"))
+	log.Infof("[UNCHANGED] period set to: %s, state: %s", period, filterState)
+	ctx.Filters[types.UnchangedFilter] = unchanged{period: period, state: filterState}
+}
+
+func (f unchanged) WithParameters(parameters map[string]string) (types.Filter, error) {
+	for k, v := range parameters {
+		switch k {
+		case "period":
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("[UNCHANGED] invalid period: %s, err: %s", v, err)
+			}
+			f.period = duration
+		case "state":
+			f.state = types.State(v)
+		default:
+			return nil, fmt.Errorf("[UNCHANGED] unknown parameter: %s", k)
+		}
+	}
+	return f, nil
+}
+
+func (f unchanged) Execute(items []types.CloudItem) []types.CloudItem {
+	log.Debugf("[UNCHANGED] Filtering items (%d): [%s]", len(items), items)
+	if !state.IsEnabled() {
+		panic("[UNCHANGED] state store is not configured, use the -state flag")
+	}
+	now := time.Now()
+	return filter("UNCHANGED", items, types.ExclusiveFilter, func(item types.CloudItem) bool {
+		if len(f.state) != 0 && types.GetState(item) != f.state {
+			log.Debugf("[UNCHANGED] Filter %s, because it's not in %s state: %s", item.GetType(), f.state, item.GetName())
+			return false
+		}
+		history, err := state.GetHistory(item)
+		if err != nil {
+			log.Errorf("[UNCHANGED] Filter %s, because its history cannot be read: %s, err: %s", item.GetType(), item.GetName(), err)
+			return false
+		}
+		if history == nil {
+			log.Debugf("[UNCHANGED] Filter %s, because it was never seen: %s", item.GetType(), item.GetName())
+			return false
+		}
+		match := history.StateSince.Add(f.period).Before(now)
+		log.Debugf("[UNCHANGED] %s: %s in state %s since %s match: %v", item.GetType(), item.GetName(), history.State, history.StateSince, match)
+		return match
+	})
+}
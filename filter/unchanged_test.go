@@ -0,0 +1,55 @@
+package operation
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/state"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnchangedInit(t *testing.T) {
+	assert.NotNil(t, ctx.Filters[types.UnchangedFilter])
+}
+
+func TestUnchangedFilter(t *testing.T) {
+	assert.Nil(t, state.Init(filepath.Join(t.TempDir(), "state.db")))
+	defer state.Close()
+	items := []types.CloudItem{
+		&types.Instance{ID: "i-1", Name: "stopped", CloudType: types.AWS, State: types.Stopped},
+		&types.Instance{ID: "i-2", Name: "running", CloudType: types.AWS, State: types.Running},
+	}
+	state.RecordItems(items)
+	neverSeen := &types.Instance{ID: "i-3", Name: "never seen", CloudType: types.AWS, State: types.Stopped}
+
+	filteredItems := unchanged{period: 0, state: types.Stopped}.Execute(append(items, neverSeen))
+	assert.Equal(t, 1, len(filteredItems))
+	assert.Equal(t, "stopped", filteredItems[0].GetName())
+
+	filteredItems = unchanged{period: 0}.Execute(items)
+	assert.Equal(t, 2, len(filteredItems))
+
+	filteredItems = unchanged{period: time.Hour}.Execute(items)
+	assert.Equal(t, 0, len(filteredItems))
+}
+
+func TestUnchangedWithoutStateStore(t *testing.T) {
+	assert.Panics(t, func() {
+		unchanged{period: time.Hour}.Execute([]types.CloudItem{&types.Instance{CloudType: types.AWS}})
+	})
+}
+
+func TestUnchangedWithParameters(t *testing.T) {
+	filter, err := unchanged{period: defaultUnchangedPeriod}.WithParameters(map[string]string{"period": "24h", "state": "stopped"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, unchanged{period: 24 * time.Hour, state: types.Stopped}, filter)
+
+	_, err = unchanged{}.WithParameters(map[string]string{"period": "1 day"})
+	assert.NotNil(t, err)
+	_, err = unchanged{}.WithParameters(map[string]string{"unknown": "1"})
+	assert.NotNil(t, err)
+}
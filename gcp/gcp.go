@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 	"github.com/hortonworks/cloud-haunter/utils"
 	log "github.com/sirupsen/logrus"
@@ -17,23 +19,34 @@ import (
 	"strconv"
 	"sync"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iam/v1"
+	monitoring "google.golang.org/api/monitoring/v3"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	storage "google.golang.org/api/storage/v1"
 )
 
 var provider = gcpProvider{}
 
+var errShuttingDown = errors.New("skipped, because the process is shutting down")
+
+// the cleanup of the storages can be limited to the objects with the prefix
+var storageCleanupPrefix string
+
 type gcpProvider struct {
-	projectID     string
-	computeClient *compute.Service
-	iamClient     *iam.Service
-	sqlClient     *sqladmin.Service
+	projectID        string
+	computeClient    *compute.Service
+	iamClient        *iam.Service
+	sqlClient        *sqladmin.Service
+	monitoringClient *monitoring.Service
+	storageClient    *storage.Service
 }
 
 func init() {
+	storageCleanupPrefix = os.Getenv("GOOGLE_STORAGE_CLEANUP_PREFIX")
 	projectID := os.Getenv("GOOGLE_PROJECT_ID")
 	if len(projectID) == 0 {
 		log.Warn("[GCP] GOOGLE_PROJECT_ID environment variable is missing")
@@ -47,11 +60,11 @@ func init() {
 	ctx.CloudProviders[types.GCP] = func() types.CloudProvider {
 		if len(provider.projectID) == 0 {
 			log.Debug("[GCP] Trying to prepare")
-			computeClient, iamClient, sqlClient, err := initClients()
+			computeClient, iamClient, sqlClient, monitoringClient, storageClient, err := initClients()
 			if err != nil {
 				panic("[GCP] Failed to authenticate, err: " + err.Error())
 			}
-			if err := provider.init(projectID, computeClient, iamClient, sqlClient); err != nil {
+			if err := provider.init(projectID, computeClient, iamClient, sqlClient, monitoringClient, storageClient); err != nil {
 				panic("[GCP] Failed to initialize provider, err: " + err.Error())
 			}
 			log.Info("[GCP] Successfully prepared")
@@ -60,16 +73,33 @@ func init() {
 	}
 }
 
-func initClients() (computeClient *http.Client, iamClient *http.Client, sqlClient *http.Client, err error) {
-	computeClient, err = google.DefaultClient(context.Background(), compute.CloudPlatformScope)
+func initClients() (computeClient *http.Client, iamClient *http.Client, sqlClient *http.Client, monitoringClient *http.Client, storageClient *http.Client, err error) {
+	if recording.IsReplaying() {
+		// the recorded responses are served without authentication
+		client := &http.Client{Transport: recording.WrapTransport(nil)}
+		return client, client, client, client, client, nil
+	}
+	background := context.Background()
+	if recording.IsEnabled() {
+		background = context.WithValue(background, oauth2.HTTPClient, &http.Client{Transport: recording.WrapTransport(nil)})
+	}
+	computeClient, err = google.DefaultClient(background, compute.CloudPlatformScope)
+	if err != nil {
+		return
+	}
+	iamClient, err = google.DefaultClient(background, iam.CloudPlatformScope)
+	if err != nil {
+		return
+	}
+	sqlClient, err = google.DefaultClient(background, sqladmin.SqlserviceAdminScope)
 	if err != nil {
 		return
 	}
-	iamClient, err = google.DefaultClient(context.Background(), iam.CloudPlatformScope)
+	monitoringClient, err = google.DefaultClient(background, monitoring.MonitoringScope)
 	if err != nil {
 		return
 	}
-	sqlClient, err = google.DefaultClient(context.Background(), sqladmin.SqlserviceAdminScope)
+	storageClient, err = google.DefaultClient(background, storage.DevstorageReadWriteScope)
 	if err != nil {
 		return
 	}
@@ -77,7 +107,7 @@ func initClients() (computeClient *http.Client, iamClient *http.Client, sqlClien
 }
 
 func (p *gcpProvider) init(projectID string, computeHTTPClient *http.Client, iamHTTPClient *http.Client,
-	sqlHTTPClient *http.Client) error {
+	sqlHTTPClient *http.Client, monitoringHTTPClient *http.Client, storageHTTPClient *http.Client) error {
 
 	p.projectID = projectID
 	computeClient, err := compute.New(computeHTTPClient)
@@ -95,6 +125,16 @@ func (p *gcpProvider) init(projectID string, computeHTTPClient *http.Client, iam
 		return errors.New("Failed to initialize Sql admin client, err: " + err.Error())
 	}
 	p.sqlClient = sqlClient
+	monitoringClient, err := monitoring.New(monitoringHTTPClient)
+	if err != nil {
+		return errors.New("Failed to initialize monitoring client, err: " + err.Error())
+	}
+	p.monitoringClient = monitoringClient
+	storageClient, err := storage.New(storageHTTPClient)
+	if err != nil {
+		return errors.New("Failed to initialize storage client, err: " + err.Error())
+	}
+	p.storageClient = storageClient
 	return nil
 }
 
@@ -353,7 +393,7 @@ func (p gcpProvider) DeleteDisks(disks *types.DiskContainer) []error {
 				log.Infof("[GCP] Sending request to delete disk in zone %s : %s", zone, disk.Name)
 
 				if _, err := p.computeClient.Disks.Delete(p.projectID, zone, disk.Name).Do(); err != nil {
-					errChan <- err
+					errChan <- types.NewResourceError(disk.ID, disk.Region, err)
 				}
 			}
 		}(d)
@@ -387,23 +427,39 @@ func (p gcpProvider) DeleteImages(images *types.ImageContainer) []error {
 func (p gcpProvider) TerminateInstances(instances *types.InstanceContainer) []error {
 	log.Debug("[GCP] Terminating instances")
 
-	gcpInstances := instances.Get(types.GCP)
 	instanceGroups, err := p.computeClient.InstanceGroupManagers.AggregatedList(p.projectID).Do()
 	if err != nil {
 		log.Errorf("[GCP] Failed to fetch instance groups, err: %s", err.Error())
 		return []error{err}
 	}
+	getGroupDeleteAggregator := func(zone, name string) instanceDeleteAggregator {
+		return p.computeClient.InstanceGroupManagers.Delete(p.projectID, zone, name)
+	}
+	getInstanceDeleteAggregator := func(zone, name string) instanceDeleteAggregator {
+		return p.computeClient.Instances.Delete(p.projectID, zone, name)
+	}
+	return terminateInstances(instanceGroups, getGroupDeleteAggregator, getInstanceDeleteAggregator, instances.Get(types.GCP))
+}
+
+type instanceDeleteAggregator interface {
+	Do(opts ...googleapi.CallOption) (*compute.Operation, error)
+}
+
+// The instances of a managed instance group are terminated by deleting the group, otherwise the group would recreate them.
+// If the deletion of the group fails, every instance of the group fails.
+func terminateInstances(instanceGroups *compute.InstanceGroupManagerAggregatedList, getGroupDeleteAggregator, getInstanceDeleteAggregator func(string, string) instanceDeleteAggregator,
+	gcpInstances []*types.Instance) []error {
 
 	instancesToDelete := []*types.Instance{}
-	instanceGroupsToDelete := map[*compute.InstanceGroupManager]bool{}
+	instanceGroupsToDelete := map[*compute.InstanceGroupManager][]*types.Instance{}
 
 	for _, inst := range gcpInstances {
 		groupFound := false
 		for _, i := range instanceGroups.Items {
 			for _, group := range i.InstanceGroupManagers {
-				if _, ok := instanceGroupsToDelete[group]; !ok && strings.Index(inst.Name, group.BaseInstanceName+"-") == 0 {
+				if strings.Index(inst.Name, group.BaseInstanceName+"-") == 0 {
 					log.Debugf("[GCP] Found instance group for instance %s : %s", inst.GetName(), group.Name)
-					instanceGroupsToDelete[group], groupFound = true, true
+					instanceGroupsToDelete[group], groupFound = append(instanceGroupsToDelete[group], inst), true
 				}
 			}
 		}
@@ -416,21 +472,28 @@ func (p gcpProvider) TerminateInstances(instances *types.InstanceContainer) []er
 	log.Debugf("[GCP] Instance groups to terminate (%d) : [%v]", len(instanceGroupsToDelete), instanceGroupsToDelete)
 	wg := sync.WaitGroup{}
 	wg.Add(len(instanceGroupsToDelete))
-	for g := range instanceGroupsToDelete {
-		go func(group *compute.InstanceGroupManager) {
+	errChan := make(chan error)
+	for g, i := range instanceGroupsToDelete {
+		go func(group *compute.InstanceGroupManager, groupInstances []*types.Instance) {
 			defer wg.Done()
 
 			zone := getZone(group.Zone)
+			if ctx.IsShuttingDown() {
+				log.Warnf("[GCP] Shutting down, instance group is not deleted: %s", group.Name)
+				sendInstanceErrors(errChan, groupInstances, errShuttingDown)
+				return
+			}
 			log.Infof("[GCP] Deleting instance group %s in zone %s", group.Name, zone)
 			if ctx.DryRun {
 				log.Info("[GCP] Skipping group termination on dry run session")
 			} else {
-				_, err := p.computeClient.InstanceGroupManagers.Delete(p.projectID, zone, group.Name).Do()
+				_, err := getGroupDeleteAggregator(zone, group.Name).Do()
 				if err != nil {
 					log.Errorf("[GCP] Failed to delete instance group %s, err: %s", group.Name, err.Error())
+					sendInstanceErrors(errChan, groupInstances, err)
 				}
 			}
-		}(g)
+		}(g, i)
 	}
 	log.Debugf("[GCP] Instances to terminate (%d): [%v]", len(instancesToDelete), instancesToDelete)
 	wg.Add(len(instancesToDelete))
@@ -439,19 +502,40 @@ func (p gcpProvider) TerminateInstances(instances *types.InstanceContainer) []er
 			defer wg.Done()
 
 			zone := inst.Metadata["zone"]
+			if ctx.IsShuttingDown() {
+				log.Warnf("[GCP] Shutting down, instance is not deleted: %s", inst.Name)
+				errChan <- types.NewResourceError(inst.ID, inst.Region, errShuttingDown)
+				return
+			}
 			log.Infof("[GCP] Deleting instance %s in zone %s", inst.Name, zone)
 			if ctx.DryRun {
 				log.Info("[GCP] Skipping instance termination on dry run session")
 			} else {
-				_, err := p.computeClient.Instances.Delete(p.projectID, zone, inst.Name).Do()
+				_, err := getInstanceDeleteAggregator(zone, inst.Name).Do()
 				if err != nil {
 					log.Errorf("[GCP] Failed to delete instance %s, err: %s", inst.Name, err.Error())
+					errChan <- types.NewResourceError(inst.ID, inst.Region, err)
 				}
 			}
 		}(i)
 	}
-	wg.Wait()
-	return nil
+
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func sendInstanceErrors(errChan chan error, instances []*types.Instance, err error) {
+	for _, inst := range instances {
+		errChan <- types.NewResourceError(inst.ID, inst.Region, err)
+	}
 }
 
 func (p gcpProvider) TerminateStacks(stacks *types.StackContainer) []error {
@@ -510,7 +594,7 @@ func (p gcpProvider) terminateInstancesAndDbInStack(stack *types.Stack, errChan
 			err := p.doAndPollComputeCall(p.computeClient.Instances.Delete(p.projectID, zone, instanceName))
 			if err != nil {
 				log.Errorf("[GCP] Failed to terminate instance %s of stack %s, err: %s", instanceName, stack.Name, err)
-				errChan <- err
+				errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 			}
 			resultChan <- err == nil
 		}(i, zone)
@@ -527,7 +611,7 @@ func (p gcpProvider) terminateInstancesAndDbInStack(stack *types.Stack, errChan
 			err := p.doAndPollSqlCall(p.sqlClient.Instances.Delete(p.projectID, dbName))
 			if err != nil {
 				log.Errorf("[GCP] Failed to terminate db %s of stack %s, err: %s", dbName, stack.Name, err)
-				errChan <- err
+				errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 			}
 			resultChan <- err == nil
 		}(database)
@@ -563,7 +647,7 @@ func (p gcpProvider) terminateNetworkRelatedResourcesInStack(stack *types.Stack,
 
 			if _, err := p.computeClient.Addresses.Delete(p.projectID, region, ip).Do(); err != nil {
 				log.Errorf("[GCP] Failed to terminate ip %s of stack %s, err: %s", ip, stack.Name, err)
-				errChan <- err
+				errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 			}
 		}(i, stack.Region)
 	}
@@ -585,7 +669,7 @@ func (p gcpProvider) terminateNetworkRelatedResourcesInStack(stack *types.Stack,
 				err := p.doAndPollComputeCall(p.computeClient.Firewalls.Delete(p.projectID, firewallName))
 				if err != nil {
 					log.Errorf("[GCP] Failed to delete firewall %s, skipping network delete", firewallName)
-					errChan <- err
+					errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 					return
 				}
 			}(firewallName)
@@ -599,7 +683,7 @@ func (p gcpProvider) terminateNetworkRelatedResourcesInStack(stack *types.Stack,
 					err := p.doAndPollComputeCall(p.computeClient.Subnetworks.Delete(p.projectID, region, subnetName))
 					if err != nil {
 						log.Errorf("[GCP] Failed to terminate subnet %s of stack %s, err: %s", subnetName, stack.Name, err)
-						errChan <- err
+						errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 					}
 				}(s, stack.Region)
 			}
@@ -611,7 +695,7 @@ func (p gcpProvider) terminateNetworkRelatedResourcesInStack(stack *types.Stack,
 			err := p.doAndPollComputeCall(p.computeClient.Networks.Delete(p.projectID, networkName))
 			if err != nil {
 				log.Errorf("[GCP] Failed to terminate network %s of stack %s, err: %s", networkName, stack.Name, err)
-				errChan <- err
+				errChan <- types.NewResourceError(stack.ID, stack.Region, err)
 			}
 		}(network, firewall, subnets)
 	}
@@ -711,14 +795,17 @@ func (p gcpProvider) StopInstances(instances *types.InstanceContainer) []error {
 		go func(instance *types.Instance) {
 			defer wg.Done()
 
-			if ctx.DryRun {
+			if ctx.IsShuttingDown() {
+				log.Warnf("[GCP] Shutting down, instance is not stopped: %s", instance.Name)
+				errChan <- types.NewResourceError(instance.ID, instance.Region, errShuttingDown)
+			} else if ctx.DryRun {
 				log.Infof("[GCP] Dry-run set, instance is not stopped: %s", instance.Name)
 			} else {
 				zone := instance.Metadata["zone"]
 				log.Infof("[GCP] Sending request to stop instance in zone %s : %s", zone, instance.Name)
 
 				if _, err := p.computeClient.Instances.Stop(p.projectID, zone, instance.Name).DiscardLocalSsd(true).Do(); err != nil {
-					errChan <- err
+					errChan <- types.NewResourceError(instance.ID, instance.Region, err)
 				}
 			}
 		}(i)
@@ -760,7 +847,7 @@ func (p gcpProvider) StopDatabases(databases *types.DatabaseContainer) []error {
 				log.Infof("[GCP] Sending request to stop instance %s", database.Name)
 
 				if _, err := p.sqlClient.Instances.Patch(p.projectID, database.Name, stopRequest).Do(); err != nil {
-					errChan <- err
+					errChan <- types.NewResourceError(database.ID, database.Region, err)
 				}
 			}
 		}(db)
@@ -792,22 +879,24 @@ func (p gcpProvider) GetDatabases() ([]*types.Database, error) {
 }
 
 type instancesListAggregator interface {
-	Do(...googleapi.CallOption) (*compute.InstanceAggregatedList, error)
+	Pages(ctx context.Context, f func(*compute.InstanceAggregatedList) error) error
 }
 
 func getInstances(aggregator instancesListAggregator) ([]*types.Instance, error) {
 	instances := make([]*types.Instance, 0)
-	instanceList, err := aggregator.Do()
+	err := aggregator.Pages(context.Background(), func(instanceList *compute.InstanceAggregatedList) error {
+		log.Debugf("[GCP] Processing instances (%d): [%v]", len(instanceList.Items), instanceList.Items)
+		for _, items := range instanceList.Items {
+			for _, inst := range items.Instances {
+				instances = append(instances, newInstance(inst))
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		log.Errorf("[GCP] Failed to fetch the running instances, err: %s", err.Error())
 		return nil, err
 	}
-	log.Debugf("[GCP] Processing instances (%d): [%v]", len(instanceList.Items), instanceList.Items)
-	for _, items := range instanceList.Items {
-		for _, inst := range items.Instances {
-			instances = append(instances, newInstance(inst))
-		}
-	}
 	return instances, nil
 }
 
@@ -864,7 +953,7 @@ func deleteImages(getAggregator func(string) imageDeleteAggregator, images []*ty
 				_, err := getAggregator(ID).Do()
 				if err != nil {
 					log.Errorf("[GCP] Unable to delete image: %s because: %s", ID, err.Error())
-					errChan <- errors.New(ID)
+					errChan <- types.NewResourceError(image.ID, image.Region, err)
 				}
 			}
 		}()
@@ -936,6 +1025,23 @@ func newImage(image *compute.Image) *types.Image {
 	}
 }
 
+func newStorage(bucket *storage.Bucket) *types.Storage {
+	created, err := utils.ConvertTimeRFC3339(bucket.TimeCreated)
+	if err != nil {
+		log.Warnf("[GCP] cannot convert time: %s, err: %s", bucket.TimeCreated, err.Error())
+	}
+	return &types.Storage{
+		Name:      bucket.Name,
+		ID:        bucket.Id,
+		Created:   created,
+		CloudType: types.GCP,
+		Tags:      bucket.Labels,
+		Owner:     bucket.Labels[ctx.OwnerLabel],
+		Region:    strings.ToLower(bucket.Location),
+		MetaData:  map[string]string{"locationType": bucket.LocationType, "storageClass": bucket.StorageClass},
+	}
+}
+
 func newInstance(inst *compute.Instance) *types.Instance {
 	created, err := utils.ConvertTimeRFC3339(inst.CreationTimestamp)
 	if err != nil {
@@ -952,6 +1058,7 @@ func newInstance(inst *compute.Instance) *types.Instance {
 		Region:       getRegionFromZoneURL(&inst.Zone),
 		InstanceType: inst.MachineType[strings.LastIndex(inst.MachineType, "/")+1:],
 		State:        getInstanceState(inst),
+		StoppedAt:    getStoppedAt(inst),
 	}
 }
 
@@ -993,6 +1100,19 @@ func getInstanceState(instance *compute.Instance) types.State {
 	}
 }
 
+// Stopped instances are reported in TERMINATED state by GCP
+func getStoppedAt(instance *compute.Instance) *time.Time {
+	if getInstanceState(instance) == types.Running || len(instance.LastStopTimestamp) == 0 {
+		return nil
+	}
+	stoppedAt, err := utils.ConvertTimeRFC3339(instance.LastStopTimestamp)
+	if err != nil {
+		log.Warnf("[GCP] cannot convert last stop time: %s, err: %s", instance.LastStopTimestamp, err.Error())
+		return nil
+	}
+	return &stoppedAt
+}
+
 func getZone(url string) string {
 	parts := strings.Split(url, "/")
 	return parts[len(parts)-1]
@@ -1093,19 +1213,613 @@ func (p gcpProvider) getDatabases(aggregator *sqladmin.InstancesListCall) ([]*ty
 }
 
 func (p gcpProvider) GetAlerts() ([]*types.Alert, error) {
-	return nil, errors.New("[GCP] Getting alerts is not supported yet")
+	log.Debug("[GCP] Fetching alert policies")
+	return getAlerts(p.monitoringClient.Projects.AlertPolicies.List("projects/"+p.projectID), p.getMonitoredResources)
+}
+
+func (p gcpProvider) DeleteAlerts(alerts *types.AlertContainer) []error {
+	log.Debug("[GCP] Delete alert policies")
+	return deleteAlerts(func(name string) alertPolicyDeleteAggregator {
+		return p.monitoringClient.Projects.AlertPolicies.Delete(name)
+	}, alerts.Get(types.GCP))
+}
+
+const (
+	instanceResourceType = "gce_instance"
+	databaseResourceType = "cloudsql_database"
+)
+
+// monitoredResources are the resources the alert policies can refer to. The user labels are stored as key=value by
+// monitored resource type, only the labels of the listed types are known.
+type monitoredResources struct {
+	instanceIDs map[string]bool
+	databaseIDs map[string]bool
+	userLabels  map[string]map[string]bool
+}
+
+func (p gcpProvider) getMonitoredResources() (*monitoredResources, error) {
+	resources := &monitoredResources{
+		instanceIDs: map[string]bool{},
+		databaseIDs: map[string]bool{},
+		userLabels:  map[string]map[string]bool{instanceResourceType: {}, databaseResourceType: {}},
+	}
+	instances, err := getInstances(p.computeClient.Instances.AggregatedList(p.projectID))
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		resources.instanceIDs[instance.ID] = true
+		for k, v := range instance.Tags {
+			resources.userLabels[instanceResourceType][k+"="+v] = true
+		}
+	}
+	err = p.sqlClient.Instances.List(p.projectID).Pages(context.Background(), func(databases *sqladmin.InstancesListResponse) error {
+		for _, database := range databases.Items {
+			// the database_id of the monitored resource is project:instance
+			resources.databaseIDs[p.projectID+":"+database.Name] = true
+			if database.Settings != nil {
+				for k, v := range database.Settings.UserLabels {
+					resources.userLabels[databaseResourceType][k+"="+v] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+type alertPoliciesListAggregator interface {
+	Pages(ctx context.Context, f func(*monitoring.ListAlertPoliciesResponse) error) error
+}
+
+// the instance and database IDs and the user labels are matched only with equality in the filters of the conditions
+var alertFilterReferencePattern = regexp.MustCompile(`(resource\.labels?\.instance_id|resource\.labels?\.database_id|metadata\.user_labels?\.(?:"([^"]+)"|([\w-]+)))\s*==?\s*"([^"]*)"`)
+
+var alertFilterResourceTypePattern = regexp.MustCompile(`resource\.type\s*==?\s*"([^"]*)"`)
+
+// getAlerts lists the alert policies of the project. A policy is unused if its filters refer to instances, databases or user
+// labels and none of them exist anymore, the resources are fetched only if any policy refers to them. The state is unknown
+// if a policy refers only to user labels of resource types that are not listed, or if it has a condition without a parsed
+// filter, e.g. an MQL or a log match condition, as the resources referred by that condition are not known.
+func getAlerts(aggregator alertPoliciesListAggregator, getMonitoredResources func() (*monitoredResources, error)) ([]*types.Alert, error) {
+	alerts := make([]*types.Alert, 0)
+	references := map[*types.Alert][]alertFilterReference{}
+	err := aggregator.Pages(context.Background(), func(response *monitoring.ListAlertPoliciesResponse) error {
+		log.Debugf("[GCP] Processing alert policies (%d)", len(response.AlertPolicies))
+		for _, policy := range response.AlertPolicies {
+			alert := newAlert(policy)
+			alerts = append(alerts, alert)
+			if refs, parsed := getAlertFilterReferences(policy); !parsed {
+				alert.State = types.Unknown
+			} else if len(refs) != 0 {
+				references[alert] = refs
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[GCP] Failed to fetch the alert policies, err: %s", err.Error())
+		return nil, err
+	}
+	if len(references) == 0 {
+		return alerts, nil
+	}
+
+	resources, err := getMonitoredResources()
+	if err != nil {
+		log.Warnf("[GCP] Failed to fetch the monitored resources, the state of the alert policies is unknown, err: %s", err.Error())
+		for alert := range references {
+			alert.State = types.Unknown
+		}
+		return alerts, nil
+	}
+	for alert, refs := range references {
+		alert.State = types.Unused
+		for _, ref := range refs {
+			exists, known := ref.exists(resources)
+			if exists {
+				alert.State = types.InUse
+				break
+			}
+			if !known {
+				alert.State = types.Unknown
+			}
+		}
+	}
+	return alerts, nil
+}
+
+type alertFilterReference struct {
+	kind         string
+	resourceType string
+	key          string
+	value        string
+}
+
+// exists returns whether the referred resource exists and whether it could be checked at all
+func (r alertFilterReference) exists(resources *monitoredResources) (bool, bool) {
+	switch r.kind {
+	case "instance_id":
+		return resources.instanceIDs[r.value], true
+	case "database_id":
+		return resources.databaseIDs[r.value], true
+	default:
+		labels, ok := resources.userLabels[r.resourceType]
+		return labels[r.key+"="+r.value], ok
+	}
 }
 
-func (p gcpProvider) DeleteAlerts(*types.AlertContainer) []error {
-	return []error{errors.New("[GCP] Deleting alerts is not supported yet")}
+// getAlertFilterReferences returns the references of the filters of the conditions, and false if any condition is not a threshold
+// or an absence condition, so its references cannot be parsed
+func getAlertFilterReferences(policy *monitoring.AlertPolicy) ([]alertFilterReference, bool) {
+	var filters []string
+	for _, condition := range policy.Conditions {
+		switch {
+		case condition.ConditionThreshold != nil:
+			filters = append(filters, condition.ConditionThreshold.Filter, condition.ConditionThreshold.DenominatorFilter)
+		case condition.ConditionAbsent != nil:
+			filters = append(filters, condition.ConditionAbsent.Filter)
+		default:
+			return nil, false
+		}
+	}
+	var references []alertFilterReference
+	for _, filter := range filters {
+		var resourceType string
+		if match := alertFilterResourceTypePattern.FindStringSubmatch(filter); match != nil {
+			resourceType = match[1]
+		}
+		for _, match := range alertFilterReferencePattern.FindAllStringSubmatch(filter, -1) {
+			switch {
+			case strings.HasSuffix(match[1], ".instance_id"):
+				references = append(references, alertFilterReference{kind: "instance_id", value: match[4]})
+			case strings.HasSuffix(match[1], ".database_id"):
+				references = append(references, alertFilterReference{kind: "database_id", value: match[4]})
+			default:
+				references = append(references, alertFilterReference{kind: "user_label", resourceType: resourceType, key: match[2] + match[3], value: match[4]})
+			}
+		}
+	}
+	return references, true
+}
+
+func newAlert(policy *monitoring.AlertPolicy) *types.Alert {
+	var created time.Time
+	if policy.CreationRecord != nil {
+		var err error
+		if created, err = utils.ConvertTimeRFC3339(policy.CreationRecord.MutateTime); err != nil {
+			log.Warnf("[GCP] cannot convert time: %s, err: %s", policy.CreationRecord.MutateTime, err.Error())
+		}
+	}
+	return &types.Alert{
+		ID:        policy.Name,
+		Name:      policy.DisplayName,
+		Created:   created,
+		CloudType: types.GCP,
+		Region:    "global",
+		Owner:     policy.UserLabels[ctx.OwnerLabel],
+		State:     types.InUse,
+		Tags:      policy.UserLabels,
+		Metadata:  map[string]string{"enabled": strconv.FormatBool(policy.Enabled)},
+	}
+}
+
+type alertPolicyDeleteAggregator interface {
+	Do(opts ...googleapi.CallOption) (*monitoring.Empty, error)
+}
+
+func deleteAlerts(getAggregator func(string) alertPolicyDeleteAggregator, alerts []*types.Alert) []error {
+	log.Infof("[GCP] Deleting %d alert policies", len(alerts))
+	wg := sync.WaitGroup{}
+	wg.Add(len(alerts))
+	errChan := make(chan error)
+	sem := make(chan bool, 10)
+
+	for _, a := range alerts {
+		go func(alert *types.Alert) {
+			sem <- true
+			defer func() {
+				wg.Done()
+				<-sem
+			}()
+
+			if ctx.IsShuttingDown() {
+				log.Warnf("[GCP] Shutting down, alert policy is not deleted: %s (%s)", alert.Name, alert.ID)
+				errChan <- types.NewResourceError(alert.ID, alert.Region, errShuttingDown)
+			} else if ctx.DryRun {
+				log.Infof("[GCP] Dry-run set, alert policy is not deleted: %s (%s)", alert.Name, alert.ID)
+			} else {
+				log.Infof("[GCP] Delete alert policy: %s (%s)", alert.Name, alert.ID)
+				if _, err := getAggregator(alert.ID).Do(); err != nil {
+					log.Errorf("[GCP] Unable to delete alert policy: %s because: %s", alert.ID, err.Error())
+					errChan <- types.NewResourceError(alert.ID, alert.Region, err)
+				}
+			}
+		}(a)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 func (p gcpProvider) GetStorages() ([]*types.Storage, error) {
-	return nil, errors.New("[GCP] Getting storages is not supported yet")
+	log.Debug("[GCP] Fetching buckets")
+	return getStorages(p.storageClient.Buckets.List(p.projectID))
 }
 
 func (p gcpProvider) CleanupStorages(storageContainer *types.StorageContainer, retentionDays int) []error {
-	return []error{errors.New("[GCP] Cleanup storages is not supported yet")}
+	getObjectsAggregator := func(bucket string) objectsListAggregator {
+		// every generation is listed, so the noncurrent versions of the versioned buckets are cleaned up too
+		call := p.storageClient.Objects.List(bucket).Versions(true)
+		if len(storageCleanupPrefix) != 0 {
+			call = call.Prefix(storageCleanupPrefix)
+		}
+		return call
+	}
+	getDeleteAggregator := func(bucket, object string, generation int64) objectDeleteAggregator {
+		return p.storageClient.Objects.Delete(bucket, object).Generation(generation)
+	}
+	return cleanupStorages(getObjectsAggregator, getDeleteAggregator, storageContainer.Get(types.GCP), retentionDays)
+}
+
+type bucketsListAggregator interface {
+	Pages(ctx context.Context, f func(*storage.Buckets) error) error
+}
+
+func getStorages(aggregator bucketsListAggregator) ([]*types.Storage, error) {
+	storages := make([]*types.Storage, 0)
+	err := aggregator.Pages(context.Background(), func(buckets *storage.Buckets) error {
+		log.Debugf("[GCP] Processing buckets (%d)", len(buckets.Items))
+		for _, bucket := range buckets.Items {
+			storages = append(storages, newStorage(bucket))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[GCP] Failed to fetch the buckets, err: %s", err.Error())
+		return nil, err
+	}
+	return storages, nil
+}
+
+type objectsListAggregator interface {
+	Pages(ctx context.Context, f func(*storage.Objects) error) error
+}
+
+type objectDeleteAggregator interface {
+	Do(opts ...googleapi.CallOption) error
+}
+
+func cleanupStorages(getObjectsAggregator func(string) objectsListAggregator, getDeleteAggregator func(string, string, int64) objectDeleteAggregator,
+	storages []*types.Storage, retentionDays int) []error {
+
+	retentionTime := time.Now().AddDate(0, 0, -retentionDays)
+	log.Debugf("[GCP] Cleaning up buckets older than %s with prefix '%s'", retentionTime, storageCleanupPrefix)
+
+	wg := sync.WaitGroup{}
+	errChan := make(chan error)
+
+	storagesByRegion := map[string][]*types.Storage{}
+	for _, bucket := range storages {
+		storagesByRegion[bucket.Region] = append(storagesByRegion[bucket.Region], bucket)
+	}
+	wg.Add(len(storagesByRegion))
+
+	for r, s := range storagesByRegion {
+		go func(region string, storagesInRegion []*types.Storage) {
+			defer wg.Done()
+
+			for _, bucket := range storagesInRegion {
+				var cleanedUpStorage int64
+				err := getObjectsAggregator(bucket.Name).Pages(context.Background(), func(objects *storage.Objects) error {
+					for _, object := range objects.Items {
+						created, err := utils.ConvertTimeRFC3339(object.TimeCreated)
+						if err != nil {
+							log.Warnf("[GCP] cannot convert time: %s, err: %s", object.TimeCreated, err.Error())
+							continue
+						}
+						if !created.Before(retentionTime) {
+							continue
+						}
+						log.Infof("[GCP] Object %s (generation %d) in bucket %s is older than %s", object.Name, object.Generation, bucket.Name, retentionTime)
+						if ctx.DryRun {
+							continue
+						}
+						if err := getDeleteAggregator(bucket.Name, object.Name, object.Generation).Do(); err != nil {
+							log.Errorf("[GCP] Failed to delete object %s in bucket %s, err: %s", object.Name, bucket.Name, err.Error())
+							errChan <- types.NewResourceError(bucket.ID, region, err)
+						} else {
+							cleanedUpStorage += int64(object.Size)
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					log.Errorf("[GCP] Failed to list objects in bucket %s, err: %s", bucket.Name, err.Error())
+					errChan <- types.NewResourceError(bucket.ID, region, err)
+				}
+				log.Infof("[GCP] Cleaned up %s worth of files in bucket %s", utils.GetHumanReadableFileSize(cleanedUpStorage), bucket.Name)
+			}
+		}(r, s)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func (p gcpProvider) AddTags(items []types.CloudItem, tags types.Tags) []error {
+	log.Debugf("[GCP] Add labels: %v", tags)
+	return p.updateLabels(items, func(labels map[string]string) {
+		for k, v := range tags {
+			labels[k] = v
+		}
+	})
+}
+
+func (p gcpProvider) RemoveTags(items []types.CloudItem, keys []string) []error {
+	log.Debugf("[GCP] Remove labels: %v", keys)
+	return p.updateLabels(items, func(labels map[string]string) {
+		for _, k := range keys {
+			delete(labels, k)
+		}
+	})
+}
+
+func (p gcpProvider) GetUtilizations(items []types.CloudItem, period time.Duration) (map[string]types.Utilization, []error) {
+	log.Debugf("[GCP] Fetching utilization of %d items for the last %s", len(items), period)
+	return getUtilizations(p.projectID, monitoringTimeSeriesLister{p.projectID, p.monitoringClient}, items, period, time.Now())
+}
+
+type timeSeriesLister interface {
+	ListTimeSeries(metricType, aligner string, period time.Duration, now time.Time) ([]*monitoring.TimeSeries, error)
+}
+
+type monitoringTimeSeriesLister struct {
+	projectID string
+	service   *monitoring.Service
+}
+
+// The whole period is aligned into a single data point per time series
+func (l monitoringTimeSeriesLister) ListTimeSeries(metricType, aligner string, period time.Duration, now time.Time) ([]*monitoring.TimeSeries, error) {
+	var timeSeries []*monitoring.TimeSeries
+	err := l.service.Projects.TimeSeries.List("projects/"+l.projectID).
+		Filter(fmt.Sprintf("metric.type = \"%s\"", metricType)).
+		IntervalStartTime(now.Add(-period).UTC().Format(time.RFC3339)).
+		IntervalEndTime(now.UTC().Format(time.RFC3339)).
+		AggregationAlignmentPeriod(fmt.Sprintf("%ds", int64(period.Seconds()))).
+		AggregationPerSeriesAligner(aligner).
+		Pages(context.Background(), func(page *monitoring.ListTimeSeriesResponse) error {
+			timeSeries = append(timeSeries, page.TimeSeries...)
+			return nil
+		})
+	return timeSeries, err
+}
+
+type utilizationMetric struct {
+	metricType    string
+	resourceLabel string
+	network       bool
+}
+
+// the CPU utilization is reported as a fraction, the network traffic as bytes per second with the rate aligner
+var (
+	instanceUtilizationMetrics = []utilizationMetric{
+		{metricType: "compute.googleapis.com/instance/cpu/utilization", resourceLabel: "instance_id"},
+		{metricType: "compute.googleapis.com/instance/network/received_bytes_count", resourceLabel: "instance_id", network: true},
+		{metricType: "compute.googleapis.com/instance/network/sent_bytes_count", resourceLabel: "instance_id", network: true},
+	}
+	databaseUtilizationMetrics = []utilizationMetric{
+		{metricType: "cloudsql.googleapis.com/database/cpu/utilization", resourceLabel: "database_id"},
+		{metricType: "cloudsql.googleapis.com/database/network/received_bytes_count", resourceLabel: "database_id", network: true},
+		{metricType: "cloudsql.googleapis.com/database/network/sent_bytes_count", resourceLabel: "database_id", network: true},
+	}
+)
+
+// getUtilizations fetches every metric type with a single paginated request for the whole project,
+// which returns the time series of all the instances or databases.
+func getUtilizations(projectID string, lister timeSeriesLister, items []types.CloudItem, period time.Duration, now time.Time) (map[string]types.Utilization, []error) {
+	instanceIDs := map[string]string{}
+	databaseIDs := map[string]string{}
+	for _, item := range items {
+		if item.GetCloudType() != types.GCP {
+			continue
+		}
+		switch item.GetItem().(type) {
+		case types.Instance:
+			instanceIDs[item.GetID()] = item.GetID()
+		case types.Database:
+			databaseIDs[projectID+":"+item.GetName()] = item.GetID()
+		default:
+			log.Debugf("[GCP] Utilization of %s is not supported: %s", item.GetType(), item.GetName())
+		}
+	}
+
+	cpuValues := map[string][]float64{}
+	networkRates := map[string]float64{}
+	var errs []error
+	fetch := func(metrics []utilizationMetric, itemIDs map[string]string) {
+		if len(itemIDs) == 0 {
+			return
+		}
+		for _, metric := range metrics {
+			aligner := "ALIGN_MEAN"
+			if metric.network {
+				aligner = "ALIGN_RATE"
+			}
+			log.Debugf("[GCP] Fetching metric: %s", metric.metricType)
+			timeSeries, err := lister.ListTimeSeries(metric.metricType, aligner, period, now)
+			if err != nil {
+				log.Errorf("[GCP] Failed to fetch metric: %s, err: %s", metric.metricType, err)
+				errs = append(errs, err)
+				return
+			}
+			for _, series := range timeSeries {
+				if series.Resource == nil {
+					continue
+				}
+				itemID, ok := itemIDs[series.Resource.Labels[metric.resourceLabel]]
+				if !ok {
+					continue
+				}
+				var values []float64
+				for _, point := range series.Points {
+					if point.Value != nil && point.Value.DoubleValue != nil {
+						values = append(values, *point.Value.DoubleValue)
+					}
+				}
+				if len(values) == 0 {
+					continue
+				}
+				if metric.network {
+					networkRates[itemID] += average(values)
+				} else {
+					for _, v := range values {
+						cpuValues[itemID] = append(cpuValues[itemID], v*100)
+					}
+				}
+			}
+		}
+	}
+	fetch(instanceUtilizationMetrics, instanceIDs)
+	fetch(databaseUtilizationMetrics, databaseIDs)
+
+	// an item without CPU data points is left out, because its utilization is unknown
+	utilizations := map[string]types.Utilization{}
+	for itemID, values := range cpuValues {
+		utilizations[itemID] = types.Utilization{CPU: average(values), Network: networkRates[itemID]}
+	}
+	return utilizations, errs
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// The labels of a stack are the labels of its instances
+func (p gcpProvider) updateLabels(items []types.CloudItem, update func(map[string]string)) []error {
+	var errs []error
+	for _, item := range items {
+		if item.GetCloudType() != types.GCP {
+			continue
+		}
+		if ctx.DryRun {
+			log.Infof("[GCP] Dry-run set, labels of %s are not updated: %s", item.GetType(), item.GetName())
+			continue
+		}
+		log.Infof("[GCP] Update labels of %s: %s", item.GetType(), item.GetName())
+		var err error
+		switch t := item.GetItem().(type) {
+		case types.Instance:
+			err = p.updateInstanceLabels(t.Metadata["zone"], t.Name, update)
+		case types.Disk:
+			err = p.updateDiskLabels(t.Metadata["zone"], t.Name, update)
+		case types.Database:
+			err = p.updateDatabaseLabels(t.Name, update)
+		case types.Stack:
+			for _, instanceName := range getResourceList(t.Metadata["instances"]) {
+				if err = p.updateInstanceLabels(t.Metadata["zone"], instanceName, update); err != nil {
+					break
+				}
+			}
+		default:
+			err = fmt.Errorf("[GCP] Labeling of %s is not supported", item.GetType())
+		}
+		if err != nil {
+			log.Errorf("[GCP] Failed to update labels of %s: %s, err: %s", item.GetType(), item.GetName(), err)
+			errs = append(errs, types.NewResourceError(item.GetID(), item.GetRegion(), err))
+		}
+	}
+	return errs
+}
+
+func (p gcpProvider) updateInstanceLabels(zone, name string, update func(map[string]string)) error {
+	instance, err := p.computeClient.Instances.Get(p.projectID, zone, name).Do()
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{}
+	for k, v := range instance.Labels {
+		labels[k] = v
+	}
+	update(labels)
+	_, err = p.computeClient.Instances.SetLabels(p.projectID, zone, name, &compute.InstancesSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: instance.LabelFingerprint,
+	}).Do()
+	return err
+}
+
+func (p gcpProvider) updateDiskLabels(zone, name string, update func(map[string]string)) error {
+	disk, err := p.computeClient.Disks.Get(p.projectID, zone, name).Do()
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{}
+	for k, v := range disk.Labels {
+		labels[k] = v
+	}
+	update(labels)
+	_, err = p.computeClient.Disks.SetLabels(p.projectID, zone, name, &compute.ZoneSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: disk.LabelFingerprint,
+	}).Do()
+	return err
+}
+
+func (p gcpProvider) updateDatabaseLabels(name string, update func(map[string]string)) error {
+	database, err := p.sqlClient.Instances.Get(p.projectID, name).Do()
+	if err != nil {
+		return err
+	}
+	if database.Settings == nil {
+		return fmt.Errorf("[GCP] Settings of database %s are missing", name)
+	}
+	labels := map[string]string{}
+	for k, v := range database.Settings.UserLabels {
+		labels[k] = v
+	}
+	update(labels)
+	_, err = p.sqlClient.Instances.Patch(p.projectID, name, getDatabaseLabelsPatch(database.Settings, labels)).Do()
+	return err
+}
+
+// getDatabaseLabelsPatch changes only the labels of the database. The labels are merged by patch, so the removed ones are sent
+// with null value.
+func getDatabaseLabelsPatch(settings *sqladmin.Settings, labels map[string]string) *sqladmin.DatabaseInstance {
+	patch := &sqladmin.Settings{
+		SettingsVersion: settings.SettingsVersion,
+		UserLabels:      labels,
+		ForceSendFields: []string{"UserLabels"},
+	}
+	for k := range settings.UserLabels {
+		if _, ok := labels[k]; !ok {
+			patch.NullFields = append(patch.NullFields, "UserLabels."+k)
+		}
+	}
+	return &sqladmin.DatabaseInstance{Settings: patch}
 }
 
 func getDatabaseInstanceCreationTimeStamp(opService *sqladmin.OperationsListCall, dbName string) (time.Time, error) {
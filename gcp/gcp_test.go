@@ -1,33 +1,68 @@
 package gcp
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"testing"
 	"time"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/recording"
 	"github.com/hortonworks/cloud-haunter/types"
 	"github.com/stretchr/testify/assert"
 	compute "google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	iam "google.golang.org/api/iam/v1"
+	monitoring "google.golang.org/api/monitoring/v3"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	storage "google.golang.org/api/storage/v1"
 )
 
 func TestProviderInit(t *testing.T) {
 	provider := gcpProvider{}
 
-	provider.init("project-id", &http.Client{}, &http.Client{}, &http.Client{})
+	provider.init("project-id", &http.Client{}, &http.Client{}, &http.Client{}, &http.Client{}, &http.Client{})
 
 	assert.Equal(t, "project-id", provider.projectID)
 	assert.NotNil(t, provider.computeClient)
 }
 
+// The testdata was created with the -record flag, further incidents can be reproduced the same way
+func TestGetInstancesFromRecording(t *testing.T) {
+	replayer, err := recording.NewReplayer("testdata/recording/instances")
+	assert.Nil(t, err)
+	client := &http.Client{Transport: replayer}
+	provider := gcpProvider{}
+	provider.init("project-id", client, client, client, client, client)
+
+	instances, err := provider.GetInstances()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, "running-instance", instances[0].Name)
+	assert.Equal(t, "1234567890", instances[0].ID)
+	assert.Equal(t, "alice", instances[0].Owner)
+	assert.Equal(t, "europe-west1", instances[0].Region)
+	assert.Equal(t, "n1-standard-1", instances[0].InstanceType)
+	assert.Equal(t, types.Running, instances[0].State)
+	assert.Equal(t, types.Terminated, instances[1].State)
+}
+
 func TestGetInstances(t *testing.T) {
 	instances, _ := getInstances(mockInstancesListAggregator{})
 
 	assert.Equal(t, 1, len(instances))
 }
 
+func TestGetInstancesPaged(t *testing.T) {
+	instances, _ := getInstances(mockPagedInstancesListAggregator{})
+
+	assert.Equal(t, 2, len(instances))
+}
+
 func TestGetAccesses(t *testing.T) {
 	accesses, _ := getAccesses(mockServiceAccountsListAggregator{}, func(string) keysListAggregator {
 		return mockKeysListAggregator{}
@@ -58,6 +93,37 @@ func TestDeleteImages(t *testing.T) {
 	assert.Equal(t, "hdc-hdp--1711170803", <-imageChan)
 }
 
+func TestTerminateInstancesOfInstanceGroup(t *testing.T) {
+	instanceGroups := &compute.InstanceGroupManagerAggregatedList{Items: map[string]compute.InstanceGroupManagersScopedList{
+		"zones/us-west1-a": {InstanceGroupManagers: []*compute.InstanceGroupManager{
+			{Name: "group", BaseInstanceName: "group", Zone: "https://www.googleapis.com/compute/v1/projects/project/zones/us-west1-a"},
+		}},
+	}}
+	var deletedInstances []string
+	getGroupDeleteAggregator := func(zone, name string) instanceDeleteAggregator {
+		return mockFailingDeleteAggregator{}
+	}
+	getInstanceDeleteAggregator := func(zone, name string) instanceDeleteAggregator {
+		deletedInstances = append(deletedInstances, name)
+		return mockImageDeleteAggregator{}
+	}
+	instances := []*types.Instance{
+		{ID: "1", Name: "group-a1b2", Region: "us-west1", CloudType: types.GCP},
+		{ID: "2", Name: "group-c3d4", Region: "us-west1", CloudType: types.GCP},
+	}
+
+	errs := terminateInstances(instanceGroups, getGroupDeleteAggregator, getInstanceDeleteAggregator, instances)
+
+	assert.Empty(t, deletedInstances)
+	assert.Equal(t, 2, len(errs))
+	var failedIDs []string
+	for _, err := range errs {
+		failedIDs = append(failedIDs, err.(types.ResourceError).ID)
+	}
+	sort.Strings(failedIDs)
+	assert.Equal(t, []string{"1", "2"}, failedIDs)
+}
+
 func TestNewInstance(t *testing.T) {
 	instance := newInstance(newTestInstance())
 
@@ -67,11 +133,170 @@ func TestNewInstance(t *testing.T) {
 	assert.Equal(t, "b", instance.Region)
 }
 
+func TestNewInstanceStoppedAt(t *testing.T) {
+	gcpInstance := newTestInstance()
+	gcpInstance.Status = "TERMINATED"
+	gcpInstance.LastStopTimestamp = "2024-03-01T10:00:00.000-07:00"
+
+	instance := newInstance(gcpInstance)
+
+	assert.Equal(t, time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC), instance.StoppedAt.UTC())
+
+	gcpInstance.Status = "RUNNING"
+	assert.Nil(t, newInstance(gcpInstance).StoppedAt)
+}
+
+func TestGetStorages(t *testing.T) {
+	storages, err := getStorages(mockBucketsListAggregator{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(storages))
+	assert.Equal(t, "bucket-1", storages[0].Name)
+	assert.Equal(t, "owner", storages[0].Owner)
+	assert.Equal(t, "europe-west1", storages[0].Region)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), storages[0].Created.UTC())
+	assert.Equal(t, "STANDARD", storages[0].MetaData["storageClass"])
+	assert.Equal(t, "us", storages[1].Region)
+}
+
+func TestCleanupStorages(t *testing.T) {
+	deleteChan := make(chan string, 10)
+	getObjectsAggregator := func(bucket string) objectsListAggregator {
+		return mockObjectsListAggregator{bucket: bucket}
+	}
+	getDeleteAggregator := func(bucket, object string, generation int64) objectDeleteAggregator {
+		deleteChan <- fmt.Sprintf("%s/%s#%d", bucket, object, generation)
+		return mockObjectDeleteAggregator{fail: object == "locked"}
+	}
+	storages := []*types.Storage{
+		{CloudType: types.GCP, ID: "bucket-1", Name: "bucket-1", Region: "europe-west1"},
+		{CloudType: types.GCP, ID: "bucket-2", Name: "bucket-2", Region: "us"},
+	}
+
+	errs := cleanupStorages(getObjectsAggregator, getDeleteAggregator, storages, 7)
+	close(deleteChan)
+
+	var deleted []string
+	for object := range deleteChan {
+		deleted = append(deleted, object)
+	}
+	sort.Strings(deleted)
+	assert.Equal(t, []string{"bucket-1/locked#1", "bucket-1/old#1", "bucket-1/old#2", "bucket-2/locked#1", "bucket-2/old#1", "bucket-2/old#2"}, deleted)
+	assert.Equal(t, 2, len(errs))
+}
+
+func TestCleanupStoragesDryRun(t *testing.T) {
+	ctx.DryRun = true
+	defer func() {
+		ctx.DryRun = false
+	}()
+	getObjectsAggregator := func(bucket string) objectsListAggregator {
+		return mockObjectsListAggregator{bucket: bucket}
+	}
+	getDeleteAggregator := func(bucket, object string, generation int64) objectDeleteAggregator {
+		t.Fatalf("object %s/%s is deleted in dry-run", bucket, object)
+		return nil
+	}
+	storages := []*types.Storage{{CloudType: types.GCP, ID: "bucket-1", Name: "bucket-1", Region: "europe-west1"}}
+
+	errs := cleanupStorages(getObjectsAggregator, getDeleteAggregator, storages, 7)
+
+	assert.Empty(t, errs)
+}
+
+func TestGetAlerts(t *testing.T) {
+	resourcesFetched := 0
+	getMonitoredResources := func() (*monitoredResources, error) {
+		resourcesFetched++
+		return &monitoredResources{
+			instanceIDs: map[string]bool{"1234": true},
+			databaseIDs: map[string]bool{"project-id:db": true},
+			userLabels:  map[string]map[string]bool{instanceResourceType: {"env=prod": true}, databaseResourceType: {}},
+		}, nil
+	}
+
+	alerts, err := getAlerts(mockAlertPoliciesListAggregator{}, getMonitoredResources)
+
+	assert.Nil(t, err)
+	states := map[string]types.State{}
+	for _, alert := range alerts {
+		states[alert.Name] = alert.State
+	}
+	assert.Equal(t, map[string]types.State{
+		"existing-instance": types.InUse,
+		"deleted-instance":  types.Unused,
+		"one-existing":      types.InUse,
+		"deleted-database":  types.Unused,
+		"existing-label":    types.InUse,
+		"deleted-label":     types.Unused,
+		"unlisted-label":    types.Unknown,
+		"untyped-label":     types.Unknown,
+		"project-wide":      types.InUse,
+		"mql-condition":     types.Unknown,
+		"log-condition":     types.Unknown,
+	}, states)
+	assert.Equal(t, 1, resourcesFetched)
+	assert.Equal(t, "projects/project-id/alertPolicies/1", alerts[0].ID)
+	assert.Equal(t, "owner", alerts[0].Owner)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), alerts[0].Created.UTC())
+}
+
+func TestGetAlertsWithoutResources(t *testing.T) {
+	getMonitoredResources := func() (*monitoredResources, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	alerts, err := getAlerts(mockAlertPoliciesListAggregator{}, getMonitoredResources)
+
+	assert.Nil(t, err)
+	for _, alert := range alerts {
+		if alert.Name == "project-wide" {
+			assert.Equal(t, types.InUse, alert.State)
+		} else {
+			assert.Equal(t, types.Unknown, alert.State, alert.Name)
+		}
+	}
+}
+
+func TestGetDatabaseLabelsPatch(t *testing.T) {
+	settings := &sqladmin.Settings{SettingsVersion: 3, Tier: "db-custom-1-3840", UserLabels: map[string]string{"owner": "alice", "marked": "1"}}
+
+	patch := getDatabaseLabelsPatch(settings, map[string]string{"owner": "alice"})
+
+	out, err := patch.MarshalJSON()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"settings":{"settingsVersion":"3","userLabels":{"owner":"alice","marked":null}}}`, string(out))
+}
+
+func TestDeleteAlerts(t *testing.T) {
+	deleteChan := make(chan string, 10)
+	getAggregator := func(name string) alertPolicyDeleteAggregator {
+		deleteChan <- name
+		return mockAlertPolicyDeleteAggregator{fail: name == "projects/project-id/alertPolicies/2"}
+	}
+	alerts := []*types.Alert{
+		{CloudType: types.GCP, ID: "projects/project-id/alertPolicies/1", Region: "global"},
+		{CloudType: types.GCP, ID: "projects/project-id/alertPolicies/2", Region: "global"},
+	}
+
+	errs := deleteAlerts(getAggregator, alerts)
+	close(deleteChan)
+
+	var deleted []string
+	for name := range deleteChan {
+		deleted = append(deleted, name)
+	}
+	sort.Strings(deleted)
+	assert.Equal(t, []string{"projects/project-id/alertPolicies/1", "projects/project-id/alertPolicies/2"}, deleted)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "projects/project-id/alertPolicies/2", errs[0].(types.ResourceError).ID)
+}
+
 type mockInstancesListAggregator struct {
 }
 
-func (m mockInstancesListAggregator) Do(opts ...googleapi.CallOption) (*compute.InstanceAggregatedList, error) {
-	return &compute.InstanceAggregatedList{
+func (m mockInstancesListAggregator) Pages(_ context.Context, f func(*compute.InstanceAggregatedList) error) error {
+	return f(&compute.InstanceAggregatedList{
 		Items: map[string]compute.InstancesScopedList{
 			"key": {
 				Instances: []*compute.Instance{
@@ -79,7 +304,128 @@ func (m mockInstancesListAggregator) Do(opts ...googleapi.CallOption) (*compute.
 				},
 			},
 		},
-	}, nil
+	})
+}
+
+type mockPagedInstancesListAggregator struct {
+}
+
+func (m mockPagedInstancesListAggregator) Pages(ctx context.Context, f func(*compute.InstanceAggregatedList) error) error {
+	if err := (mockInstancesListAggregator{}).Pages(ctx, f); err != nil {
+		return err
+	}
+	return (mockInstancesListAggregator{}).Pages(ctx, f)
+}
+
+type mockBucketsListAggregator struct {
+}
+
+func (m mockBucketsListAggregator) Pages(_ context.Context, f func(*storage.Buckets) error) error {
+	if err := f(&storage.Buckets{Items: []*storage.Bucket{
+		{
+			Name:         "bucket-1",
+			Id:           "bucket-1",
+			Location:     "EUROPE-WEST1",
+			TimeCreated:  "2024-03-01T10:00:00.000Z",
+			StorageClass: "STANDARD",
+			Labels:       map[string]string{ctx.OwnerLabel: "owner"},
+		},
+	}}); err != nil {
+		return err
+	}
+	return f(&storage.Buckets{Items: []*storage.Bucket{{Name: "bucket-2", Id: "bucket-2", Location: "US", TimeCreated: "2024-03-01T10:00:00.000Z"}}})
+}
+
+type mockObjectsListAggregator struct {
+	bucket string
+}
+
+func (m mockObjectsListAggregator) Pages(_ context.Context, f func(*storage.Objects) error) error {
+	old := time.Now().AddDate(0, 0, -10).Format(time.RFC3339)
+	if err := f(&storage.Objects{Items: []*storage.Object{
+		{Name: "old", Generation: 1, TimeCreated: old, Size: 1024},
+		{Name: "old", Generation: 2, TimeCreated: old, Size: 1024},
+	}}); err != nil {
+		return err
+	}
+	return f(&storage.Objects{Items: []*storage.Object{
+		{Name: "new", Generation: 1, TimeCreated: time.Now().Format(time.RFC3339), Size: 1024},
+		{Name: "locked", Generation: 1, TimeCreated: old, Size: 1024},
+	}})
+}
+
+type mockObjectDeleteAggregator struct {
+	fail bool
+}
+
+func (m mockObjectDeleteAggregator) Do(opts ...googleapi.CallOption) error {
+	if m.fail {
+		return errors.New("object is under retention policy")
+	}
+	return nil
+}
+
+type mockAlertPoliciesListAggregator struct {
+}
+
+func (m mockAlertPoliciesListAggregator) Pages(_ context.Context, f func(*monitoring.ListAlertPoliciesResponse) error) error {
+	newPolicy := func(ID, name string, filters ...string) *monitoring.AlertPolicy {
+		policy := &monitoring.AlertPolicy{Name: "projects/project-id/alertPolicies/" + ID, DisplayName: name}
+		for _, filter := range filters {
+			policy.Conditions = append(policy.Conditions, &monitoring.Condition{ConditionThreshold: &monitoring.MetricThreshold{Filter: filter}})
+		}
+		return policy
+	}
+	first := newPolicy("1", "existing-instance", `metric.type = "compute.googleapis.com/instance/cpu/utilization" AND resource.labels.instance_id = "1234"`)
+	first.UserLabels = map[string]string{ctx.OwnerLabel: "owner"}
+	first.CreationRecord = &monitoring.MutationRecord{MutateTime: "2024-03-01T10:00:00.000Z"}
+	if err := f(&monitoring.ListAlertPoliciesResponse{AlertPolicies: []*monitoring.AlertPolicy{
+		first,
+		newPolicy("2", "deleted-instance", `resource.label.instance_id="5678"`),
+		newPolicy("3", "one-existing", `resource.labels.instance_id = "5678"`, `resource.labels.instance_id = "1234"`),
+	}}); err != nil {
+		return err
+	}
+	absent := &monitoring.AlertPolicy{Name: "projects/project-id/alertPolicies/5", DisplayName: "existing-label", Conditions: []*monitoring.Condition{
+		{ConditionAbsent: &monitoring.MetricAbsence{Filter: `resource.type = "gce_instance" AND metadata.user_labels."env" = "prod"`}},
+	}}
+	// the deleted instance of the threshold condition does not make the policies unused, the other condition may refer to live resources
+	mql := newPolicy("10", "mql-condition", `resource.label.instance_id="5678"`)
+	mql.Conditions = append(mql.Conditions, &monitoring.Condition{ConditionMonitoringQueryLanguage: &monitoring.MonitoringQueryLanguageCondition{
+		Query: `fetch gce_instance | filter resource.instance_id == "1234"`,
+	}})
+	logMatch := newPolicy("11", "log-condition", `resource.label.instance_id="5678"`)
+	logMatch.Conditions = append(logMatch.Conditions, &monitoring.Condition{ConditionMatchedLog: &monitoring.LogMatch{
+		Filter: `resource.labels.instance_id="1234"`,
+	}})
+	return f(&monitoring.ListAlertPoliciesResponse{AlertPolicies: []*monitoring.AlertPolicy{
+		mql,
+		logMatch,
+		newPolicy("4", "deleted-database", `resource.type = "cloudsql_database" AND resource.labels.database_id = "project-id:deleted"`),
+		absent,
+		newPolicy("6", "deleted-label", `resource.type="cloudsql_database" AND metadata.user_labels.env = "test"`),
+		newPolicy("8", "unlisted-label", `resource.type = "gcs_bucket" AND metadata.user_labels.env = "test"`),
+		newPolicy("9", "untyped-label", `metadata.user_labels.env = "test"`),
+		newPolicy("7", "project-wide", `metric.type = "compute.googleapis.com/instance/cpu/utilization"`),
+	}})
+}
+
+type mockAlertPolicyDeleteAggregator struct {
+	fail bool
+}
+
+func (m mockAlertPolicyDeleteAggregator) Do(opts ...googleapi.CallOption) (*monitoring.Empty, error) {
+	if m.fail {
+		return nil, errors.New("not found")
+	}
+	return &monitoring.Empty{}, nil
+}
+
+type mockFailingDeleteAggregator struct {
+}
+
+func (m mockFailingDeleteAggregator) Do(opts ...googleapi.CallOption) (*compute.Operation, error) {
+	return nil, errors.New("delete failed")
 }
 
 type mockImageDeleteAggregator struct {
@@ -136,3 +482,47 @@ func newTestInstance() *compute.Instance {
 		MachineType:       "n1-standard-8",
 	}
 }
+
+type mockTimeSeriesLister struct {
+	metricTypes []string
+}
+
+func (l *mockTimeSeriesLister) ListTimeSeries(metricType, aligner string, period time.Duration, now time.Time) ([]*monitoring.TimeSeries, error) {
+	l.metricTypes = append(l.metricTypes, metricType+":"+aligner)
+	newSeries := func(label, value string, values ...float64) *monitoring.TimeSeries {
+		series := &monitoring.TimeSeries{Resource: &monitoring.MonitoredResource{Labels: map[string]string{label: value}}}
+		for i := range values {
+			series.Points = append(series.Points, &monitoring.Point{Value: &monitoring.TypedValue{DoubleValue: &values[i]}})
+		}
+		return series
+	}
+	switch metricType {
+	case "compute.googleapis.com/instance/cpu/utilization":
+		return []*monitoring.TimeSeries{newSeries("instance_id", "1", 0.02, 0.04), newSeries("instance_id", "3", 0.5)}, nil
+	case "compute.googleapis.com/instance/network/received_bytes_count":
+		return []*monitoring.TimeSeries{newSeries("instance_id", "1", 100)}, nil
+	case "cloudsql.googleapis.com/database/cpu/utilization":
+		return []*monitoring.TimeSeries{newSeries("database_id", "project:db", 0.1)}, nil
+	}
+	return nil, nil
+}
+
+func TestGetUtilizations(t *testing.T) {
+	lister := &mockTimeSeriesLister{}
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.GCP, ID: "1"},
+		&types.Instance{CloudType: types.GCP, ID: "2"},
+		&types.Database{CloudType: types.GCP, ID: "etag", Name: "db"},
+		&types.Instance{CloudType: types.AWS, ID: "3"},
+	}
+
+	utilizations, errs := getUtilizations("project", lister, items, time.Hour, time.Now())
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 6, len(lister.metricTypes))
+	assert.Equal(t, "compute.googleapis.com/instance/network/sent_bytes_count:ALIGN_RATE", lister.metricTypes[2])
+	assert.Equal(t, 2, len(utilizations))
+	assert.InDelta(t, 3, utilizations["1"].CPU, 0.0001)
+	assert.Equal(t, float64(100), utilizations["1"].Network)
+	assert.InDelta(t, 10, utilizations["etag"].CPU, 0.0001)
+}
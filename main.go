@@ -1,21 +1,36 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"sort"
+	"strings"
+	"syscall"
 
 	"github.com/hortonworks/cloud-haunter/utils"
 
 	_ "github.com/hortonworks/cloud-haunter/action"
+	"github.com/hortonworks/cloud-haunter/api"
 	_ "github.com/hortonworks/cloud-haunter/aws"
 	_ "github.com/hortonworks/cloud-haunter/azure"
 	ctx "github.com/hortonworks/cloud-haunter/context"
-	_ "github.com/hortonworks/cloud-haunter/filter"
+	_ "github.com/hortonworks/cloud-haunter/dummy"
+	filter "github.com/hortonworks/cloud-haunter/filter"
 	_ "github.com/hortonworks/cloud-haunter/gcp"
 	_ "github.com/hortonworks/cloud-haunter/hipchat"
+	"github.com/hortonworks/cloud-haunter/metrics"
 	_ "github.com/hortonworks/cloud-haunter/operation"
+	"github.com/hortonworks/cloud-haunter/plan"
+	"github.com/hortonworks/cloud-haunter/pricing"
+	"github.com/hortonworks/cloud-haunter/recording"
+	"github.com/hortonworks/cloud-haunter/schedule"
 	_ "github.com/hortonworks/cloud-haunter/slack"
+	"github.com/hortonworks/cloud-haunter/state"
 	"github.com/hortonworks/cloud-haunter/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -38,8 +53,20 @@ func main() {
 	verbose := flag.Bool("v", false, "verbose")
 	ignoreLabelDisabled := flag.Bool("i", false, "disable ignore label")
 	exactMatchOwner := flag.Bool("e", false, "exact match owner")
+	planLoc := flag.String("plan", "", "run plan YAML")
+	reportFormat := flag.String("report", "log", "format of the action report")
+	scheduleLoc := flag.String("schedule", "", "schedule YAML of the serve command")
+	listenAddr := flag.String("listen", "", "listen address of the API of the serve command")
+	recordDir := flag.String("record", "", "record the cloud API responses to a directory")
+	replayDir := flag.String("replay", "", "replay the cloud API responses from a directory")
+	stateLoc := flag.String("state", "", "location of the state store of the item history")
 
-	flag.Parse()
+	args := os.Args[1:]
+	command := ""
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	if *help {
 		printHelp()
@@ -54,6 +81,21 @@ func main() {
 	ctx.IgnoreLabelDisabled = *ignoreLabelDisabled
 	ctx.ExactMatchOwner = *exactMatchOwner
 
+	if len(*recordDir) != 0 {
+		if err := recording.Record(*recordDir); err != nil {
+			panic("Unable to record API responses: " + err.Error())
+		}
+	}
+	if len(*replayDir) != 0 {
+		if err := recording.Replay(*replayDir); err != nil {
+			panic("Unable to replay API responses: " + err.Error())
+		}
+	}
+
+	if command == "validate" {
+		os.Exit(validateFilterConfig(*filterConfigLoc))
+	}
+
 	if filterConfigLoc != nil && len(*filterConfigLoc) != 0 {
 		var err error
 		ctx.FilterConfig, err = utils.LoadFilterConfig(*filterConfigLoc)
@@ -61,64 +103,240 @@ func main() {
 			log.Warnf("[UTIL] Failed to load %s as V1 filter config, trying as V2. Error: %s", *filterConfigLoc, err.Error())
 			ctx.FilterConfig, err = utils.LoadFilterConfigV2(*filterConfigLoc)
 			if err != nil {
-				panic("Unable to parse filter configuration: " + err.Error())
+				panic("Unable to parse filter configuration: " + err.Error() + ", use the validate command for details")
 			}
 		}
 	}
 
-	op := func() *types.OpType {
-		for i := range ctx.Operations {
-			if i.String() == *opType {
-				return &i
-			}
+	if len(*stateLoc) != 0 {
+		if err := state.Init(*stateLoc); err != nil {
+			panic("Unable to open state store: " + err.Error())
 		}
-		return nil
-	}()
-	if op == nil {
-		panic("Operation is not found.")
 	}
 
-	var filters []types.Filter
-	var filterNames []types.FilterType
-	selectedFilters := utils.SplitListToMap(*filterTypes)
-	for f := range ctx.Filters {
-		if _, ok := selectedFilters[f.String()]; ok {
-			filters = append(filters, ctx.Filters[f])
-			filterNames = append(filterNames, f)
+	switch command {
+	case "":
+	case "serve":
+		serve(*scheduleLoc, *listenAddr)
+		state.Close()
+		os.Exit(0)
+	case "explain":
+		explain(createJob(*opType, *filterTypes, *actionType, *cloudTypes))
+		state.Close()
+		os.Exit(0)
+	default:
+		panic("Unknown command: " + command)
+	}
+
+	var runPlan *types.Plan
+	if planLoc != nil && len(*planLoc) != 0 {
+		var err error
+		runPlan, err = utils.LoadPlan(*planLoc)
+		if err != nil {
+			panic("Unable to parse run plan: " + err.Error())
 		}
+	} else {
+		runPlan = &types.Plan{Jobs: []types.Job{createJob(*opType, *filterTypes, *actionType, *cloudTypes)}}
 	}
 
-	action := func() types.Action {
-		for i := range ctx.Actions {
-			if i.String() == *actionType {
-				return ctx.Actions[i]
-			}
+	results := plan.Execute(runPlan)
+	metrics.WriteTextfile()
+	state.Close()
+	switch *reportFormat {
+	case "json":
+		printJSONReport(results)
+	default:
+		logReport(results)
+	}
+	os.Exit(plan.GetExitCode(results))
+}
+
+func serve(scheduleLoc, listenAddr string) {
+	if len(scheduleLoc) == 0 && len(listenAddr) == 0 {
+		panic("Neither schedule nor listen address is specified, use the -schedule or -listen flags")
+	}
+	jobSchedule := &types.Schedule{}
+	if len(scheduleLoc) != 0 {
+		var err error
+		if jobSchedule, err = utils.LoadSchedule(scheduleLoc); err != nil {
+			panic("Unable to parse schedule: " + err.Error())
 		}
-		return nil
+	}
+	scheduler, err := schedule.New(jobSchedule)
+	if err != nil {
+		panic("Invalid schedule: " + err.Error())
+	}
+	var jobs []types.Job
+	for _, job := range jobSchedule.Jobs {
+		jobs = append(jobs, job.Job)
+	}
+
+	var httpServer *http.Server
+	if len(listenAddr) != 0 {
+		apiServer, err := api.NewServer()
+		if err != nil {
+			panic("Unable to create API server: " + err.Error())
+		}
+		apiServer.SetJobStatuses(scheduler.GetStatuses)
+		httpServer = &http.Server{Addr: listenAddr, Handler: apiServer.Handler()}
+		// the API can query any of the clouds
+		jobs = nil
+	}
+	plan.InitCloudProviders(jobs)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	scheduler.Start()
+	if httpServer != nil {
+		go func() {
+			log.Infof("[SERVE] Listening on %s", listenAddr)
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Errorf("[SERVE] API server failed, err: %s", err)
+				signals <- syscall.SIGTERM
+			}
+		}()
+	}
+	log.Infof("[SERVE] Started with %d scheduled jobs", len(jobSchedule.Jobs))
+
+	sig := <-signals
+	log.Infof("[SERVE] Received %s, waiting for the running jobs to finish their current batch", sig)
+	ctx.RequestShutdown()
+	go func() {
+		sig := <-signals
+		log.Warnf("[SERVE] Received %s again, exiting without waiting for the running jobs", sig)
+		os.Exit(plan.ExitFatal)
 	}()
-	if action == nil {
-		panic("Action is not found.")
+	if httpServer != nil {
+		if err := httpServer.Shutdown(context.Background()); err != nil {
+			log.Errorf("[SERVE] Failed to stop API server, err: %s", err)
+		}
 	}
+	scheduler.Stop()
+	log.Info("[SERVE] Stopped")
+}
 
-	var clouds []types.CloudType
-	selectedClouds := utils.SplitListToMap(*cloudTypes)
-	for t := range ctx.CloudProviders {
-		_, ok := selectedClouds[t.String()]
-		if len(selectedClouds) == 0 || ok {
-			clouds = append(clouds, t)
-		} else {
-			delete(ctx.CloudProviders, t)
+func logReport(results []plan.JobResult) {
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			log.Errorf("[PLAN] %s: FAILED, err: %s", result.Name, result.Err.Error())
+		case result.Report.HasFailure():
+			log.Warnf("[PLAN] %s: PARTIALLY FAILED, failed items: %d", result.Name, result.Report.Count(types.OutcomeFailed))
+		case result.Report != nil && result.Report.Count(types.OutcomeInvalid) != 0:
+			log.Warnf("[PLAN] %s: OK, invalid items: %d", result.Name, result.Report.Count(types.OutcomeInvalid))
+		default:
+			log.Infof("[PLAN] %s: OK", result.Name)
+		}
+		if savings := result.Report.GetMonthlySavings(); savings > 0 {
+			log.Infof("[PLAN] %s: estimated monthly savings: %.2f %s", result.Name, savings, pricing.GetCurrency())
 		}
 	}
-	if len(clouds) == 0 {
-		panic("Cloud provider not found.")
+}
+
+func printJSONReport(results []plan.JobResult) {
+	type jobReport struct {
+		Job     string               `json:"Job"`
+		Error   string               `json:"Error,omitempty"`
+		Results []types.ActionResult `json:"Results"`
 	}
+	reports := []jobReport{}
+	for _, result := range results {
+		report := jobReport{Job: result.Name, Results: []types.ActionResult{}}
+		if result.Err != nil {
+			report.Error = result.Err.Error()
+		}
+		if result.Report != nil {
+			report.Results = result.Report.Results
+		}
+		reports = append(reports, report)
+	}
+	out, _ := json.MarshalIndent(reports, "", "  ")
+	fmt.Println(string(out))
+}
 
-	items := ctx.Operations[*op].Execute(clouds)
-	for _, filter := range filters {
-		items = filter.Execute(items)
+// the problems are printed with the location and line number, so editors can jump to them
+func validateFilterConfig(location string) int {
+	if len(location) == 0 {
+		panic("Filter config is not specified, use the -fc flag")
+	}
+	version, problems, err := utils.ValidateFilterConfig(location)
+	if err != nil {
+		panic("Unable to parse filter configuration: " + err.Error())
+	}
+	for _, problem := range problems {
+		fmt.Printf("%s:%d: %s\n", location, problem.Line, problem.Message)
+	}
+	if len(problems) != 0 {
+		return 1
+	}
+	fmt.Printf("%s: valid V%d filter config\n", location, version)
+	return 0
+}
+
+func explain(job types.Job) {
+	explanations, err := plan.ExplainJob(job)
+	if err != nil {
+		panic("Unable to explain job: " + err.Error())
+	}
+	for _, explanation := range explanations {
+		item := explanation.Item
+		outcome := "dropped"
+		if explanation.Selected {
+			outcome = "selected"
+		}
+		fmt.Printf("%s %s %s (%s): %s\n", item.GetCloudType(), item.GetType(), item.GetID(), item.GetName(), outcome)
+		for _, f := range explanation.Filters {
+			outcome = "dropped"
+			if f.Kept {
+				outcome = "kept"
+			}
+			fmt.Printf("  %s: %s\n", f.Filter, outcome)
+			for _, decision := range f.Decisions {
+				fmt.Printf("    %s\n", getDecisionReason(decision))
+			}
+		}
+	}
+}
+
+func getDecisionReason(decision filter.FilterDecision) string {
+	criteria := "meets the criteria of the filter"
+	if !decision.Needed {
+		criteria = "does not meet the criteria of the filter"
+	}
+	var config string
+	switch {
+	case decision.Inclusive && decision.Included:
+		config = "included by the filter config"
+	case decision.Inclusive:
+		config = "not included by the filter config"
+	case decision.Included:
+		config = "not excluded by the filter config"
+	default:
+		config = "excluded by the filter config"
+	}
+	return fmt.Sprintf("[%s] %s, %s: %s", decision.Filter, criteria, config, decision.Reason)
+}
+
+func createJob(opType, filterTypes, actionType, cloudTypes string) types.Job {
+	job := types.Job{Operation: types.OpType(opType), Action: types.ActionType(actionType)}
+	if len(strings.TrimSpace(filterTypes)) != 0 {
+		selectedFilters, err := plan.ParseFilterExpression(filterTypes)
+		if err != nil {
+			panic("Invalid filters: " + err.Error())
+		}
+		for _, f := range selectedFilters {
+			job.Filters = append(job.Filters, types.JobFilter{Type: f})
+		}
+		if strings.ContainsAny(filterTypes, "&|!()") {
+			job.FilterExpression = filterTypes
+		}
+	}
+	for _, c := range strings.Split(cloudTypes, ",") {
+		if trimmed := strings.TrimSpace(c); len(trimmed) != 0 {
+			job.Clouds = append(job.Clouds, types.CloudType(trimmed))
+		}
 	}
-	action.Execute(*op, filterNames, items)
+	return job
 }
 
 // should be kept in sync with README.md
@@ -127,6 +345,10 @@ func printHelp() {
    Cloud Haunter
 USAGE:
    ch -o=operation -a=action [-f=filter1,filter2] [-c=cloud1,cloud2]
+   ch -plan=/location/of/plan.yml
+   ch serve [-schedule=/location/of/schedule.yml] [-listen=:8080]
+   ch validate -fc=/location/of/filter/config.yml
+   ch explain -o=operation [-f=filter1,filter2] [-c=cloud1,cloud2] [-fc=/location/of/filter/config.yml]
 VERSION:`)
 	println("   " + ctx.Version)
 	println(`
@@ -148,7 +370,15 @@ OPERATIONS:`)
 	println("\t-c AWS")
 	println("\t-c AZURE")
 	println("\t-c GCP")
+	println("\t-c DUMMY")
 	println("FILTER_CONFIG:\n\t-fc=/location/of/filter/config.yml")
+	println("RUN_PLAN:\n\t-plan=/location/of/plan.yml")
+	println("SCHEDULE:\n\t-schedule=/location/of/schedule.yml")
+	println("LISTEN:\n\t-listen=:8080")
+	println("RECORD:\n\t-record=/location/of/recording")
+	println("REPLAY:\n\t-replay=/location/of/recording")
+	println("STATE:\n\t-state=/location/of/state.db")
+	println("REPORT_FORMAT:\n\t-report=log\n\t-report=json")
 	println("DRY RUN:\n\t-d")
 	println("VERBOSE:\n\t-v")
 	println("DISABLE_IGNORE_LABEL:\n\t-i")
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/dummy"
+	"github.com/hortonworks/cloud-haunter/plan"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateJob(t *testing.T) {
+	job := createJob("getInstances", "longrunning,ownerless", "stop", "AWS, GCP")
+
+	assert.Equal(t, types.Instances, job.Operation)
+	assert.Equal(t, types.StopAction, job.Action)
+	assert.Equal(t, []types.JobFilter{{Type: types.LongRunningFilter}, {Type: types.OwnerlessFilter}}, job.Filters)
+	assert.Empty(t, job.FilterExpression)
+	assert.Equal(t, []types.CloudType{types.AWS, types.GCP}, job.Clouds)
+
+	job = createJob("getInstances", "longrunning & !ownerless", "log", "")
+
+	assert.Equal(t, "longrunning & !ownerless", job.FilterExpression)
+	assert.Empty(t, job.Clouds)
+}
+
+func TestExecuteJobOnDummy(t *testing.T) {
+	dummy.Register("dummy/testdata/fixture.json")
+	defer delete(ctx.CloudProviders, types.DUMMY)
+
+	report, err := plan.ExecuteJob(createJob("getInstances", "longstopped", "termination", "DUMMY"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Count(types.OutcomeSucceeded))
+	assert.Equal(t, "i-3", report.Results[0].ID)
+	assert.Equal(t, types.DUMMY, report.Results[0].CloudType)
+
+	instances, err := ctx.CloudProviders[types.DUMMY]().GetInstances()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(instances))
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const namespace = "cloud_haunter"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	items = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "items",
+		Help:      "Number of cloud items returned by the operation and the filters at the last execution of the metrics action.",
+	}, []string{"operation", "filters", "cloud", "account", "region", "type", "state", "owner"})
+
+	actionResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "action_results_total",
+		Help:      "Number of cloud items processed by the actions, by outcome.",
+	}, []string{"action", "cloud", "type", "outcome"})
+
+	textfilePath string
+)
+
+func init() {
+	registry.MustRegister(items, actionResults)
+	textfilePath = os.Getenv("METRICS_TEXTFILE")
+	if len(textfilePath) > 0 {
+		log.Infof("[METRICS] metrics are written to: %s", textfilePath)
+	}
+}
+
+// SetItems replaces the number of cloud items of the operation and the filters, the previous values of the same operation
+// and filters are removed, so the gauges of the resources that are gone do not remain
+func SetItems(op types.OpType, filters []types.FilterType, cloudItems []types.CloudItem, accounts map[types.CloudType]string) {
+	filterNames := utils.GetFilterNames(filters)
+	items.DeletePartialMatch(prometheus.Labels{"operation": op.String(), "filters": filterNames})
+	for _, item := range cloudItems {
+		owner := item.GetOwner()
+		if len(owner) == 0 || owner == "???" {
+			owner = "unknown"
+		}
+		items.WithLabelValues(op.String(), filterNames, item.GetCloudType().String(), accounts[item.GetCloudType()],
+			item.GetRegion(), item.GetType(), string(types.GetState(item)), owner).Inc()
+	}
+}
+
+// RecordReport counts the results of the action per cloud, item type and outcome
+func RecordReport(report *types.ActionReport) {
+	if report == nil {
+		return
+	}
+	for _, result := range report.Results {
+		actionResults.WithLabelValues(report.Action.String(), result.CloudType.String(), result.ItemType, string(result.Outcome)).Inc()
+	}
+}
+
+// Handler returns the HTTP handler that exposes the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the metrics in the format of the node exporter's textfile collector to METRICS_TEXTFILE, if it is set.
+// The file is replaced atomically, so the collector never reads a partially written file.
+func WriteTextfile() {
+	if len(textfilePath) == 0 {
+		return
+	}
+	if err := prometheus.WriteToTextfile(textfilePath, registry); err != nil {
+		log.Errorf("[METRICS] Failed to write metrics to %s, err: %s", textfilePath, err)
+		return
+	}
+	log.Debugf("[METRICS] Metrics are written to: %s", textfilePath)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func getMetrics(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestSetItems(t *testing.T) {
+	accounts := map[types.CloudType]string{types.AWS: "account"}
+	filters := []types.FilterType{types.LongRunningFilter}
+
+	SetItems(types.Instances, filters, []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, Region: "eu-west-1", State: types.Running, Owner: "alice"},
+		&types.Instance{CloudType: types.AWS, Region: "eu-west-1", State: types.Running, Owner: "alice"},
+		&types.Instance{CloudType: types.AWS, Region: "eu-west-1", State: types.Stopped},
+	}, accounts)
+
+	metrics := getMetrics(t)
+	assert.Contains(t, metrics, `cloud_haunter_items{account="account",cloud="AWS",filters="longrunning",operation="getInstances",owner="alice",region="eu-west-1",state="running",type="instance"} 2`)
+	assert.Contains(t, metrics, `cloud_haunter_items{account="account",cloud="AWS",filters="longrunning",operation="getInstances",owner="unknown",region="eu-west-1",state="stopped",type="instance"} 1`)
+
+	SetItems(types.Instances, filters, []types.CloudItem{
+		&types.Instance{CloudType: types.AWS, Region: "eu-west-1", State: types.Running, Owner: "bob"},
+	}, accounts)
+
+	metrics = getMetrics(t)
+	assert.NotContains(t, metrics, `owner="alice"`)
+	assert.Contains(t, metrics, `cloud_haunter_items{account="account",cloud="AWS",filters="longrunning",operation="getInstances",owner="bob",region="eu-west-1",state="running",type="instance"} 1`)
+}
+
+func TestRecordReport(t *testing.T) {
+	report := types.NewActionReport(types.TerminationAction)
+	report.Add(&types.Disk{CloudType: types.GCP}, types.OutcomeSucceeded, nil)
+	report.Add(&types.Disk{CloudType: types.GCP}, types.OutcomeSucceeded, nil)
+	report.Add(&types.Disk{CloudType: types.GCP}, types.OutcomeFailed, errors.New("failed"))
+
+	RecordReport(report)
+	RecordReport(nil)
+
+	metrics := getMetrics(t)
+	assert.Contains(t, metrics, `cloud_haunter_action_results_total{action="termination",cloud="GCP",outcome="succeeded",type="disk"} 2`)
+	assert.Contains(t, metrics, `cloud_haunter_action_results_total{action="termination",cloud="GCP",outcome="failed",type="disk"} 1`)
+}
+
+func TestWriteTextfile(t *testing.T) {
+	textfilePath = filepath.Join(t.TempDir(), "cloud_haunter.prom")
+	defer func() {
+		textfilePath = ""
+	}()
+	SetItems(types.Disks, nil, []types.CloudItem{&types.Disk{CloudType: types.AZURE, State: types.Unused}}, nil)
+
+	WriteTextfile()
+
+	content, err := os.ReadFile(textfilePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `# TYPE cloud_haunter_items gauge`)
+	assert.Contains(t, string(content), `cloud_haunter_items{account="",cloud="AZURE",filters="noFilter",operation="getDisks",owner="unknown",region="",state="unused",type="disk"} 1`)
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/pricing"
 	"github.com/hortonworks/cloud-haunter/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,6 +23,7 @@ func collect(clouds []types.CloudType, getter func(types.CloudProvider) ([]types
 			if items, err := getter(providers[cloud]()); err != nil {
 				errChan <- err
 			} else {
+				pricing.SetCosts(items)
 				itemsChan <- items
 			}
 		}(c)
@@ -128,3 +128,15 @@ func (p dummyProvider) GetStorages() ([]*types.Storage, error) {
 func (p dummyProvider) CleanupStorages(storageContainer *types.StorageContainer, retentionDays int) []error {
 	return nil
 }
+
+func (p dummyProvider) AddTags([]types.CloudItem, types.Tags) []error {
+	return nil
+}
+
+func (p dummyProvider) RemoveTags([]types.CloudItem, []string) []error {
+	return nil
+}
+
+func (p dummyProvider) GetUtilizations([]types.CloudItem, time.Duration) (map[string]types.Utilization, []error) {
+	return nil, nil
+}
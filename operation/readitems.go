@@ -0,0 +1,65 @@
+package operation
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	ctx.Operations[types.ReadItems] = readItems{location: os.Getenv("READ_ITEMS_FILE")}
+}
+
+// readItems returns the items printed by the json action, so a reviewed (or hand-edited) export can be passed to the filters and actions.
+// The items are read from READ_ITEMS_FILE, or from the standard input if it is not set.
+type readItems struct {
+	location string
+}
+
+func (o readItems) Execute(clouds []types.CloudType) []types.CloudItem {
+	log.Debugf("[READ_ITEMS] Collecting items from: [%s]", clouds)
+
+	raw, err := o.read()
+	if err != nil {
+		panic(err)
+	}
+	if len(raw) == 0 {
+		panic("[READ_ITEMS] input is empty")
+	}
+	items, err := types.UnmarshalItems(raw)
+	if err != nil {
+		panic("[READ_ITEMS] Failed to parse items: " + err.Error())
+	}
+
+	selectedClouds := map[types.CloudType]bool{}
+	for _, cloud := range clouds {
+		selectedClouds[cloud] = true
+	}
+	var selectedItems []types.CloudItem
+	for _, item := range items {
+		if !selectedClouds[item.GetCloudType()] {
+			log.Warnf("[READ_ITEMS] Skipping %s %s, because cloud %s is not selected", item.GetType(), item.GetName(), item.GetCloudType())
+			continue
+		}
+		selectedItems = append(selectedItems, item)
+	}
+	log.Infof("[READ_ITEMS] Number of items read: %d, selected: %d", len(items), len(selectedItems))
+	return selectedItems
+}
+
+func (o readItems) read() ([]byte, error) {
+	if len(o.location) != 0 && o.location != "-" {
+		return os.ReadFile(o.location)
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, err
+	} else if info.Mode()&os.ModeCharDevice != 0 {
+		return nil, errors.New("[READ_ITEMS] standard input is a terminal, pipe the items or set READ_ITEMS_FILE")
+	}
+	return io.ReadAll(os.Stdin)
+}
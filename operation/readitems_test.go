@@ -0,0 +1,64 @@
+package operation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeItems(t *testing.T, content []byte) string {
+	location := filepath.Join(t.TempDir(), "items.json")
+	assert.Nil(t, os.WriteFile(location, content, 0600))
+	return location
+}
+
+func TestReadItems(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	raw, err := types.MarshalItems([]types.CloudItem{
+		&types.Instance{ID: "i-1", Name: "instance", Created: created, CloudType: types.AWS, State: types.Running, Tags: types.Tags{"owner": "alice"}},
+		&types.Disk{ID: "d-1", Name: "disk", CloudType: types.AWS, Type: "gp2", Size: 10, State: types.Unused},
+		&types.Stack{ID: "s-1", Name: "stack", CloudType: types.AWS},
+		&types.Storage{ID: "st-1", Name: "storage", CloudType: types.AWS},
+		&types.Access{Name: "access", CloudType: types.AWS},
+		&types.Image{ID: "img-1", Name: "image", CloudType: types.GCP},
+	})
+	assert.Nil(t, err)
+
+	items := readItems{location: writeItems(t, raw)}.Execute([]types.CloudType{types.AWS})
+
+	assert.Equal(t, 5, len(items))
+	instance, ok := items[0].(*types.Instance)
+	assert.True(t, ok)
+	assert.Equal(t, types.Instance{ID: "i-1", Name: "instance", Created: created, CloudType: types.AWS, State: types.Running, Tags: types.Tags{"owner": "alice"}}, *instance)
+	disk, ok := items[1].(*types.Disk)
+	assert.True(t, ok)
+	assert.Equal(t, "gp2", disk.Type)
+	assert.Equal(t, types.Unused, disk.State)
+	assert.Equal(t, "stack", items[2].GetType())
+	assert.Equal(t, "storage", items[3].GetType())
+	assert.Equal(t, "access", items[4].GetType())
+}
+
+func TestReadItemsInvalidInput(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":           ``,
+		"not an array":    `{"ItemType": "instance", "CloudType": "AWS"}`,
+		"missing type":    `[{"Id": "i-1", "CloudType": "AWS"}]`,
+		"unknown type":    `[{"ItemType": "vpc", "Id": "i-1", "CloudType": "AWS"}]`,
+		"missing cloud":   `[{"ItemType": "instance", "Id": "i-1"}]`,
+		"invalid field":   `[{"ItemType": "instance", "Id": 1, "CloudType": "AWS"}]`,
+		"unreadable file": ``,
+	} {
+		location := writeItems(t, []byte(content))
+		if name == "unreadable file" {
+			location = filepath.Join(t.TempDir(), "missing.json")
+		}
+		assert.Panics(t, func() {
+			readItems{location: location}.Execute([]types.CloudType{types.AWS})
+		}, name)
+	}
+}
@@ -0,0 +1,117 @@
+package plan
+
+import (
+	"fmt"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	filter "github.com/hortonworks/cloud-haunter/filter"
+	"github.com/hortonworks/cloud-haunter/types"
+)
+
+// ItemExplanation tells whether an item of the operation is selected by the filters of the job and what each filter decided
+type ItemExplanation struct {
+	Item     types.CloudItem
+	Selected bool
+	Filters  []FilterExplanation
+}
+
+// FilterExplanation is the decision of a filter on an item. The decisions of the filter config are present only for the filters
+// that use it.
+type FilterExplanation struct {
+	Filter    types.FilterType
+	Kept      bool
+	Decisions []filter.FilterDecision
+}
+
+// ExplainJob runs the operation of the job and explains the decision of every filter on every item. Each filter is applied on
+// all the items of the operation once, so an item is explained by a filter even if an earlier filter dropped it, and the selected
+// items are decided by these results without querying the clouds again. The action of the job is not executed and the items
+// are not recorded in the state store.
+func ExplainJob(job types.Job) (explanations []ItemExplanation, err error) {
+	operation, ok := ctx.Operations[job.Operation]
+	if !ok {
+		return nil, fmt.Errorf("operation is not found: %s", job.Operation)
+	}
+	expression, filterTypes, filters, err := getExplainedFilters(job)
+	if err != nil {
+		return nil, err
+	}
+	clouds, err := GetClouds(job.Clouds)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			explanations, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	items := operation.Execute(clouds)
+	for _, item := range items {
+		explanations = append(explanations, ItemExplanation{Item: item})
+	}
+	recordedFilters := make([]types.Filter, len(filters))
+	for i, f := range filters {
+		stopRecording := filter.RecordDecisions()
+		keptKeys := getItemKeys(f.Execute(items))
+		recordedFilters[i] = recordedFilter{kept: keptKeys}
+		decisions := map[string][]filter.FilterDecision{}
+		for _, decision := range stopRecording() {
+			key := types.GetItemKey(decision.Item)
+			decisions[key] = append(decisions[key], decision)
+		}
+		for j, item := range items {
+			key := types.GetItemKey(item)
+			explanations[j].Filters = append(explanations[j].Filters, FilterExplanation{Filter: filterTypes[i], Kept: keptKeys[key], Decisions: decisions[key]})
+		}
+	}
+	selectedKeys := getSelectedKeys(expression, items, filterTypes, recordedFilters)
+	for j, item := range items {
+		explanations[j].Selected = selectedKeys[types.GetItemKey(item)]
+	}
+	return explanations, nil
+}
+
+// recordedFilter keeps the items a filter kept of all the items of the operation, so the filter is not executed again
+type recordedFilter struct {
+	kept map[string]bool
+}
+
+func (f recordedFilter) Execute(items []types.CloudItem) []types.CloudItem {
+	return retainItems(items, f.kept, true)
+}
+
+// The recorded filters are applied one after the other, or combined by the filter expression of the job
+func getSelectedKeys(expression filterExpression, items []types.CloudItem, filterTypes []types.FilterType, recordedFilters []types.Filter) map[string]bool {
+	if expression != nil {
+		filtersByType := map[types.FilterType]types.Filter{}
+		for i, filterType := range filterTypes {
+			filtersByType[filterType] = recordedFilters[i]
+		}
+		selected, _ := expression.evaluate(items, filtersByType)
+		return getItemKeys(selected)
+	}
+	selected := items
+	for _, f := range recordedFilters {
+		selected = f.Execute(selected)
+	}
+	return getItemKeys(selected)
+}
+
+// The expression is nil if the job has no filter expression
+func getExplainedFilters(job types.Job) (filterExpression, []types.FilterType, []types.Filter, error) {
+	if len(job.FilterExpression) == 0 {
+		filters, err := getFilters(job.Filters)
+		return nil, job.GetFilterTypes(), filters, err
+	}
+	expression, filterTypes, filtersByType, err := getFilterExpression(job)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var filters []types.Filter
+	for _, filterType := range filterTypes {
+		filters = append(filters, filtersByType[filterType])
+	}
+	return expression, filterTypes, filters, nil
+}
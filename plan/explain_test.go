@@ -0,0 +1,73 @@
+package plan
+
+import (
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainJob(t *testing.T) {
+	explanations, err := ExplainJob(types.Job{Operation: expressionOperation, FilterExpression: "runningFilter & !ownedFilter"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(explanations))
+	var selected []string
+	for _, explanation := range explanations {
+		if explanation.Selected {
+			selected = append(selected, explanation.Item.GetID())
+		}
+	}
+	assert.Equal(t, []string{"2"}, selected)
+	// every filter is applied on every item, even if an earlier filter dropped it
+	assert.Equal(t, []FilterExplanation{{Filter: runningFilter, Kept: false}, {Filter: ownedFilter, Kept: true}}, explanations[2].Filters)
+}
+
+const countingFilter = types.FilterType("countingFilter")
+
+var countingFilterCalls int
+
+// countingFilterImpl counts its executions, as the filters calling the cloud APIs should be executed only once per explain
+type countingFilterImpl struct {
+	runningFilterImpl
+}
+
+func (f countingFilterImpl) Execute(items []types.CloudItem) []types.CloudItem {
+	countingFilterCalls++
+	return f.runningFilterImpl.Execute(items)
+}
+
+func init() {
+	ctx.Filters[countingFilter] = countingFilterImpl{}
+}
+
+func TestExplainJobExecutesFiltersOnce(t *testing.T) {
+	for _, tc := range []struct {
+		job      types.Job
+		selected []string
+	}{
+		{types.Job{Operation: expressionOperation, Filters: []types.JobFilter{{Type: countingFilter}, {Type: ownedFilter}}}, []string{"1"}},
+		{types.Job{Operation: expressionOperation, FilterExpression: "countingFilter & !ownedFilter"}, []string{"2"}},
+	} {
+		countingFilterCalls = 0
+
+		explanations, err := ExplainJob(tc.job)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, countingFilterCalls)
+		var selected []string
+		for _, explanation := range explanations {
+			if explanation.Selected {
+				selected = append(selected, explanation.Item.GetID())
+			}
+		}
+		assert.Equal(t, tc.selected, selected)
+	}
+}
+
+func TestExplainInvalidJob(t *testing.T) {
+	_, err := ExplainJob(types.Job{Operation: expressionOperation, FilterExpression: "runningFilter &"})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,276 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	filter "github.com/hortonworks/cloud-haunter/filter"
+	"github.com/hortonworks/cloud-haunter/types"
+)
+
+// filterExpression is a node of a parsed filter expression. The nodes return the subset of the given items in their original order.
+type filterExpression interface {
+	evaluate(items []types.CloudItem, filters map[types.FilterType]types.Filter) ([]types.CloudItem, []types.InvalidItem)
+}
+
+type filterNode struct {
+	filterType types.FilterType
+}
+
+type andNode struct {
+	left, right filterExpression
+}
+
+type orNode struct {
+	left, right filterExpression
+}
+
+type notNode struct {
+	operand filterExpression
+}
+
+func (n filterNode) evaluate(items []types.CloudItem, filters map[types.FilterType]types.Filter) ([]types.CloudItem, []types.InvalidItem) {
+	filter := filters[n.filterType]
+	var invalidItems []types.InvalidItem
+	if validating, ok := filter.(types.ValidatingFilter); ok {
+		invalidItems = validating.Validate(items)
+	}
+	return filter.Execute(items), invalidItems
+}
+
+// The right side is applied on the result of the left side, the same way as the filters of a job without an expression
+func (n andNode) evaluate(items []types.CloudItem, filters map[types.FilterType]types.Filter) ([]types.CloudItem, []types.InvalidItem) {
+	left, leftInvalid := n.left.evaluate(items, filters)
+	right, rightInvalid := n.right.evaluate(left, filters)
+	return right, append(leftInvalid, rightInvalid...)
+}
+
+func (n orNode) evaluate(items []types.CloudItem, filters map[types.FilterType]types.Filter) ([]types.CloudItem, []types.InvalidItem) {
+	left, leftInvalid := n.left.evaluate(items, filters)
+	right, rightInvalid := n.right.evaluate(items, filters)
+	selected := getItemKeys(left)
+	for key := range getItemKeys(right) {
+		selected[key] = true
+	}
+	return retainItems(items, selected, true), append(leftInvalid, rightInvalid...)
+}
+
+// The operand drops the items protected by the ignore label or the filter config, so they are dropped again after the negation,
+// otherwise the negation would select them
+func (n notNode) evaluate(items []types.CloudItem, filters map[types.FilterType]types.Filter) ([]types.CloudItem, []types.InvalidItem) {
+	matching, invalidItems := n.operand.evaluate(items, filters)
+	return filter.RemoveExcluded("NOT", retainItems(items, getItemKeys(matching), false)), invalidItems
+}
+
+func getItemKeys(items []types.CloudItem) map[string]bool {
+	keys := map[string]bool{}
+	for _, item := range items {
+		keys[types.GetItemKey(item)] = true
+	}
+	return keys
+}
+
+func retainItems(items []types.CloudItem, keys map[string]bool, selected bool) []types.CloudItem {
+	var retained []types.CloudItem
+	for _, item := range items {
+		if keys[types.GetItemKey(item)] == selected {
+			retained = append(retained, item)
+		}
+	}
+	return retained
+}
+
+// ParseFilterExpression checks the filter expression and returns the filters it refers to in the order of their first appearance.
+//
+// The expression consists of filter names, & (and), | (or), ! (not) and parentheses, e.g. (longrunning & ownerless) | (stopped & !match).
+// The comma is the same as &, so the usual comma separated list of filters is a valid expression too. The precedence is ! > & > |,
+// the operators of the same precedence are evaluated from left to right.
+func ParseFilterExpression(expression string) ([]types.FilterType, error) {
+	p := &expressionParser{}
+	if _, err := p.parse(expression); err != nil {
+		return nil, err
+	}
+	return p.filterTypes, nil
+}
+
+// The filters of the job provide the parameters of the filters referred by the expression, every filter can be listed only once
+func getFilterExpression(job types.Job) (filterExpression, []types.FilterType, map[types.FilterType]types.Filter, error) {
+	p := &expressionParser{}
+	expression, err := p.parse(job.FilterExpression)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	jobFilters := map[types.FilterType]types.JobFilter{}
+	for _, jobFilter := range job.Filters {
+		if !containsFilterType(p.filterTypes, jobFilter.Type) {
+			return nil, nil, nil, fmt.Errorf("filter %s is not used by the filter expression: %s", jobFilter.Type, job.FilterExpression)
+		}
+		if _, ok := jobFilters[jobFilter.Type]; ok {
+			return nil, nil, nil, fmt.Errorf("filter %s is listed more than once, a filter can have only one set of parameters in a filter expression", jobFilter.Type)
+		}
+		jobFilters[jobFilter.Type] = jobFilter
+	}
+	filters := map[types.FilterType]types.Filter{}
+	for _, filterType := range p.filterTypes {
+		jobFilter, ok := jobFilters[filterType]
+		if !ok {
+			jobFilter = types.JobFilter{Type: filterType}
+		}
+		if filters[filterType], err = getFilter(jobFilter); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return expression, p.filterTypes, filters, nil
+}
+
+type expressionParser struct {
+	tokens      []token
+	position    int
+	filterTypes []types.FilterType
+}
+
+type token struct {
+	value    string
+	position int
+}
+
+func (p *expressionParser) parse(expression string) (filterExpression, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter expression is empty")
+	}
+	p.tokens = tokens
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next != nil {
+		return nil, fmt.Errorf("unexpected %q at position %d of filter expression", next.value, next.position)
+	}
+	return node, nil
+}
+
+func (p *expressionParser) parseOr() (filterExpression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for next := p.peek(); next != nil && next.value == "|"; next = p.peek() {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (filterExpression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for next := p.peek(); next != nil && (next.value == "&" || next.value == ","); next = p.peek() {
+		p.position++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (filterExpression, error) {
+	next := p.peek()
+	if next != nil && next.value == "!" {
+		p.position++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (filterExpression, error) {
+	next := p.peek()
+	if next == nil {
+		return nil, fmt.Errorf("unexpected end of filter expression, a filter name or ( is missing")
+	}
+	p.position++
+	switch next.value {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing := p.peek()
+		if closing == nil || closing.value != ")" {
+			return nil, fmt.Errorf("missing ) of the ( at position %d of filter expression", next.position)
+		}
+		p.position++
+		return node, nil
+	case ")", "&", ",", "|":
+		return nil, fmt.Errorf("unexpected %q at position %d of filter expression, a filter name or ( is expected", next.value, next.position)
+	}
+	filterType := types.FilterType(next.value)
+	if _, ok := ctx.Filters[filterType]; !ok {
+		return nil, fmt.Errorf("unknown filter %q at position %d of filter expression", next.value, next.position)
+	}
+	if !containsFilterType(p.filterTypes, filterType) {
+		p.filterTypes = append(p.filterTypes, filterType)
+	}
+	return filterNode{filterType: filterType}, nil
+}
+
+func (p *expressionParser) peek() *token {
+	if p.position < len(p.tokens) {
+		return &p.tokens[p.position]
+	}
+	return nil
+}
+
+// The positions are 1-based, so they can be shown to the user as they are
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			i++
+		case strings.ContainsRune("&|!(),", r):
+			tokens = append(tokens, token{value: string(r), position: i + 1})
+			i++
+		case isNameRune(r):
+			start := i
+			for i < len(runes) && isNameRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{value: string(runes[start:i]), position: start + 1})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d of filter expression", r, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.'
+}
+
+func containsFilterType(filterTypes []types.FilterType, filterType types.FilterType) bool {
+	for _, f := range filterTypes {
+		if f == filterType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package plan
+
+import (
+	"testing"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	expressionOperation = types.OpType("expressionOperation")
+	runningFilter       = types.FilterType("runningFilter")
+	ownedFilter         = types.FilterType("ownedFilter")
+)
+
+type expressionOperationImpl struct {
+}
+
+func (o expressionOperationImpl) Execute(clouds []types.CloudType) []types.CloudItem {
+	return []types.CloudItem{
+		&types.Instance{ID: "1", Name: "keep", CloudType: types.DUMMY, State: types.Running, Owner: "alice"},
+		&types.Instance{ID: "2", Name: "drop", CloudType: types.DUMMY, State: types.Running},
+		&types.Instance{ID: "3", Name: "keep", CloudType: types.DUMMY, State: types.Stopped, Owner: "bob"},
+		&types.Instance{ID: "4", Name: "drop", CloudType: types.DUMMY, State: types.Stopped},
+	}
+}
+
+type runningFilterImpl struct {
+}
+
+func (f runningFilterImpl) Execute(items []types.CloudItem) []types.CloudItem {
+	var filtered []types.CloudItem
+	for _, item := range items {
+		if types.GetState(item) == types.Running {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+type ownedFilterImpl struct {
+}
+
+func (f ownedFilterImpl) Execute(items []types.CloudItem) []types.CloudItem {
+	var filtered []types.CloudItem
+	for _, item := range items {
+		if len(item.GetItem().(types.Instance).Owner) != 0 {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func init() {
+	ctx.Operations[expressionOperation] = expressionOperationImpl{}
+	ctx.Filters[runningFilter] = runningFilterImpl{}
+	ctx.Filters[ownedFilter] = ownedFilterImpl{}
+}
+
+func getFilteredIDs(t *testing.T, job types.Job) []string {
+	job.Operation = expressionOperation
+	items, err := GetItems(job)
+	assert.Nil(t, err)
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.GetID())
+	}
+	return ids
+}
+
+func TestFilterExpression(t *testing.T) {
+	assert.Equal(t, []string{"1"}, getFilteredIDs(t, types.Job{FilterExpression: "runningFilter & ownedFilter"}))
+	assert.Equal(t, []string{"1"}, getFilteredIDs(t, types.Job{FilterExpression: "runningFilter, ownedFilter"}))
+	assert.Equal(t, []string{"1", "2", "3"}, getFilteredIDs(t, types.Job{FilterExpression: "runningFilter | ownedFilter"}))
+	assert.Equal(t, []string{"3", "4"}, getFilteredIDs(t, types.Job{FilterExpression: "!runningFilter"}))
+	assert.Equal(t, []string{"1", "4"}, getFilteredIDs(t, types.Job{FilterExpression: "(runningFilter & ownedFilter) | (!runningFilter & !ownedFilter)"}))
+	assert.Equal(t, []string{"2", "3", "4"}, getFilteredIDs(t, types.Job{FilterExpression: "!(runningFilter & ownedFilter)"}))
+	assert.Equal(t, []string{"1", "2"}, getFilteredIDs(t, types.Job{FilterExpression: "!!runningFilter"}))
+}
+
+func TestFilterExpressionPrecedence(t *testing.T) {
+	// & binds stronger than |, so the stopped items with an owner are selected too
+	assert.Equal(t, []string{"1", "2", "3"}, getFilteredIDs(t, types.Job{FilterExpression: "runningFilter | ownedFilter & !runningFilter"}))
+	assert.Equal(t, []string{"1", "2"}, getFilteredIDs(t, types.Job{FilterExpression: "(runningFilter | ownedFilter) & !(!runningFilter)"}))
+}
+
+func TestNegatedFilterExpressionKeepsProtectedItems(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{ID: "1", Name: "stopped", CloudType: types.DUMMY, State: types.Stopped},
+		&types.Instance{ID: "2", Name: "ignored", CloudType: types.DUMMY, State: types.Stopped, Tags: types.Tags{ctx.IgnoreLabel: "true"}},
+		&types.Instance{ID: "3", Name: "excluded", CloudType: types.DUMMY, State: types.Stopped},
+		&types.Instance{ID: "4", Name: "running", CloudType: types.DUMMY, State: types.Running},
+	}
+	ctx.FilterConfig = types.FilterConfigV2{Filters: []types.FilterConfigV2Filter{
+		{Types: []types.FilterEntityType{types.ExcludeInstance}, CloudTypes: []types.CloudType{"dummy"}, Properties: []types.FilterConfigProperty{types.Name}, Values: []string{"excluded"}},
+	}}
+	defer func() {
+		ctx.FilterConfig = nil
+	}()
+	expression, _, filters, err := getFilterExpression(types.Job{FilterExpression: "!runningFilter"})
+	assert.Nil(t, err)
+
+	selected, _ := expression.evaluate(items, filters)
+
+	assert.Equal(t, []types.CloudItem{items[0]}, selected)
+}
+
+func TestFilterExpressionWithParameters(t *testing.T) {
+	job := types.Job{
+		FilterExpression: "testFilter | runningFilter",
+		Filters:          []types.JobFilter{{Type: testFilter, Parameters: map[string]string{"name": "drop"}}},
+	}
+
+	assert.Equal(t, []string{"1", "2", "4"}, getFilteredIDs(t, job))
+}
+
+func TestFilterExpressionReportsInvalidItems(t *testing.T) {
+	executedActions = nil
+
+	report, err := ExecuteJob(types.Job{Operation: expressionOperation, FilterExpression: "validateFilter | ownedFilter", Action: testAction})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []types.FilterType{validateFilter, ownedFilter}, executedActions[0].filters)
+	assert.Equal(t, 2, len(executedActions[0].items))
+	assert.Equal(t, 2, len(report.GetInvalid()))
+}
+
+func TestInvalidFilterExpressionJob(t *testing.T) {
+	assert.EqualError(t, Validate(types.Job{Operation: expressionOperation, Action: testAction, FilterExpression: "runningFilter",
+		Filters: []types.JobFilter{{Type: ownedFilter}}}), "filter ownedFilter is not used by the filter expression: runningFilter")
+	assert.NotNil(t, Validate(types.Job{Operation: expressionOperation, Action: testAction, FilterExpression: "testFilter",
+		Filters: []types.JobFilter{{Type: testFilter}, {Type: testFilter}}}))
+	assert.NotNil(t, Validate(types.Job{Operation: expressionOperation, Action: testAction, FilterExpression: "testFilter",
+		Filters: []types.JobFilter{{Type: testFilter, Parameters: map[string]string{"unknown": "value"}}}}))
+	assert.Nil(t, Validate(types.Job{Operation: expressionOperation, Action: testAction, FilterExpression: "testFilter | !ownedFilter",
+		Filters: []types.JobFilter{{Type: testFilter, Parameters: map[string]string{"name": "drop"}}}}))
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	filterTypes, err := ParseFilterExpression(" ownedFilter,runningFilter | (!testFilter & ownedFilter)")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []types.FilterType{ownedFilter, runningFilter, testFilter}, filterTypes)
+}
+
+func TestParseFilterExpressionErrors(t *testing.T) {
+	for expression, expected := range map[string]string{
+		"":                              "filter expression is empty",
+		"runningFilter & unknown":       `unknown filter "unknown" at position 17 of filter expression`,
+		"runningFilter &":               "unexpected end of filter expression, a filter name or ( is missing",
+		"runningFilter | | ownedFilter": `unexpected "|" at position 17 of filter expression, a filter name or ( is expected`,
+		"(runningFilter | ownedFilter":  "missing ) of the ( at position 1 of filter expression",
+		"runningFilter) ":               `unexpected ")" at position 14 of filter expression`,
+		"runningFilter ownedFilter":     `unexpected "ownedFilter" at position 15 of filter expression`,
+		"runningFilter + ownedFilter":   `unexpected character '+' at position 15 of filter expression`,
+		"()":                            `unexpected ")" at position 2 of filter expression, a filter name or ( is expected`,
+	} {
+		_, err := ParseFilterExpression(expression)
+		assert.EqualError(t, err, expected, expression)
+	}
+}
@@ -0,0 +1,317 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/metrics"
+	"github.com/hortonworks/cloud-haunter/pricing"
+	"github.com/hortonworks/cloud-haunter/state"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/hortonworks/cloud-haunter/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ExitSuccess every job was executed and the actions succeeded on all the cloud items
+	ExitSuccess = 0
+
+	// ExitFatal at least one of the jobs could not be executed
+	ExitFatal = 1
+
+	// ExitPartialFailure every job was executed, but some actions failed on some of the cloud items
+	ExitPartialFailure = 2
+)
+
+// JobResult is the outcome of a job execution. The report is nil if the job failed or its action does not change the cloud items.
+type JobResult struct {
+	Name   string
+	Report *types.ActionReport
+	Err    error
+}
+
+// Execute runs the jobs of the plan one after the other. A failing job is reported and does not stop the remaining ones.
+func Execute(plan *types.Plan) []JobResult {
+	retainCloudProviders(plan.Jobs)
+
+	var results []JobResult
+	for i, job := range plan.Jobs {
+		results = append(results, RunJob(job, GetJobName(job, i)))
+	}
+	return results
+}
+
+// RunJob executes the job and logs its outcome
+func RunJob(job types.Job, name string) JobResult {
+	log.Infof("[PLAN] Executing job: %s", name)
+	report, err := ExecuteJob(job)
+	if err != nil {
+		log.Errorf("[PLAN] Job %s failed, err: %s", name, err.Error())
+	} else if report != nil {
+		log.Infof("[PLAN] Job %s finished, succeeded: %d, failed: %d, dry-run: %d, invalid: %d, estimated monthly savings: %.2f %s", name,
+			report.Count(types.OutcomeSucceeded), report.Count(types.OutcomeFailed), report.Count(types.OutcomeDryRun), report.Count(types.OutcomeInvalid),
+			report.GetMonthlySavings(), pricing.GetCurrency())
+	} else {
+		log.Infof("[PLAN] Job %s finished successfully", name)
+	}
+	return JobResult{Name: name, Report: report, Err: err}
+}
+
+// InitCloudProviders removes the cloud providers that are not used by any of the jobs and initializes the remaining ones,
+// so their clients are reused by every execution of the jobs. All the providers are kept if there are no jobs.
+func InitCloudProviders(jobs []types.Job) {
+	if len(jobs) != 0 {
+		retainCloudProviders(jobs)
+	}
+	for t, provider := range ctx.CloudProviders {
+		log.Infof("[PLAN] Initializing cloud provider: %s", t)
+		provider()
+	}
+}
+
+// Validate checks that the operation, the filters with their parameters, the action and the clouds of the job exist
+func Validate(job types.Job) error {
+	if _, ok := ctx.Operations[job.Operation]; !ok {
+		return fmt.Errorf("operation is not found: %s", job.Operation)
+	}
+	if _, err := getFilterItems(job); err != nil {
+		return err
+	}
+	if _, ok := ctx.Actions[getActionType(job)]; !ok {
+		return fmt.Errorf("action is not found: %s", getActionType(job))
+	}
+	_, err := GetClouds(job.Clouds)
+	return err
+}
+
+// GetExitCode returns the exit code of the process based on the results of the jobs
+func GetExitCode(results []JobResult) int {
+	exitCode := ExitSuccess
+	for _, result := range results {
+		if result.Err != nil {
+			return ExitFatal
+		}
+		if result.Report.HasFailure() {
+			exitCode = ExitPartialFailure
+		}
+	}
+	return exitCode
+}
+
+// ExecuteJob runs the operation of the job, applies the filters on its result and passes the remaining items to the action.
+// The report of the action is sent to the dispatchers that support it.
+func ExecuteJob(job types.Job) (*types.ActionReport, error) {
+	actionType := getActionType(job)
+	if _, ok := ctx.Actions[actionType]; !ok {
+		return nil, fmt.Errorf("action is not found: %s", actionType)
+	}
+	clouds, items, invalidItems, err := collectItems(job)
+	if err != nil {
+		return nil, err
+	}
+	return executeAction(actionType, job.Operation, GetFilterTypes(job), clouds, items, invalidItems)
+}
+
+// GetItems runs the operation of the job and applies the filters on its result, the action of the job is not executed
+func GetItems(job types.Job) ([]types.CloudItem, error) {
+	_, items, _, err := collectItems(job)
+	return items, err
+}
+
+// ExecuteAction passes the cloud items to the action, as if they were the result of the operation and the filters executed on
+// the clouds of the items. The report of the action is sent to the dispatchers that support it.
+func ExecuteAction(actionType types.ActionType, op types.OpType, filterTypes []types.FilterType, items []types.CloudItem) (*types.ActionReport, error) {
+	if _, ok := ctx.Actions[actionType]; !ok {
+		return nil, fmt.Errorf("action is not found: %s", actionType)
+	}
+	return executeAction(actionType, op, filterTypes, utils.GetCloudTypes(items), items, nil)
+}
+
+func collectItems(job types.Job) (clouds []types.CloudType, items []types.CloudItem, invalidItems []types.InvalidItem, err error) {
+	operation, ok := ctx.Operations[job.Operation]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("operation is not found: %s", job.Operation)
+	}
+	filterItems, err := getFilterItems(job)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	clouds, err = GetClouds(job.Clouds)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			clouds, items, invalidItems, err = nil, nil, nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	items = operation.Execute(clouds)
+	// the items read back from an export were not seen on the clouds now, recording them would falsify their history
+	if job.Operation != types.ReadItems {
+		state.RecordItems(items)
+	}
+	items, invalidItems = filterItems(items)
+	return clouds, items, invalidItems, nil
+}
+
+// The filters of the job are applied one after the other, or combined by the filter expression of the job
+func getFilterItems(job types.Job) (func([]types.CloudItem) ([]types.CloudItem, []types.InvalidItem), error) {
+	if len(job.FilterExpression) != 0 {
+		expression, _, filters, err := getFilterExpression(job)
+		if err != nil {
+			return nil, err
+		}
+		return func(items []types.CloudItem) ([]types.CloudItem, []types.InvalidItem) {
+			return expression.evaluate(items, filters)
+		}, nil
+	}
+	filters, err := getFilters(job.Filters)
+	if err != nil {
+		return nil, err
+	}
+	return func(items []types.CloudItem) (filtered []types.CloudItem, invalidItems []types.InvalidItem) {
+		filtered = items
+		for _, filter := range filters {
+			if validating, ok := filter.(types.ValidatingFilter); ok {
+				invalidItems = append(invalidItems, validating.Validate(filtered)...)
+			}
+			filtered = filter.Execute(filtered)
+		}
+		return filtered, invalidItems
+	}, nil
+}
+
+func executeAction(actionType types.ActionType, op types.OpType, filterTypes []types.FilterType, clouds []types.CloudType, items []types.CloudItem,
+	invalidItems []types.InvalidItem) (report *types.ActionReport, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			report, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	if scoped, ok := ctx.Actions[actionType].(types.ScopedAction); ok {
+		report = scoped.ExecuteOnClouds(clouds, op, filterTypes, items)
+	} else {
+		report = ctx.Actions[actionType].Execute(op, filterTypes, items)
+	}
+	if len(invalidItems) != 0 {
+		if report == nil {
+			report = types.NewActionReport(actionType)
+		}
+		for _, invalid := range invalidItems {
+			report.Add(invalid.Item, types.OutcomeInvalid, invalid.Err)
+		}
+	}
+	metrics.RecordReport(report)
+	state.RecordReport(report)
+	// the notification action has already sent the items to the dispatchers
+	if report != nil && len(report.Results) != 0 && actionType != types.NotificationAction {
+		dispatchReport(op, filterTypes, report)
+	}
+	return report, nil
+}
+
+func getActionType(job types.Job) types.ActionType {
+	if len(job.Action) == 0 {
+		return types.LogAction
+	}
+	return job.Action
+}
+
+func dispatchReport(op types.OpType, filterTypes []types.FilterType, report *types.ActionReport) {
+	for name, d := range ctx.Dispatchers {
+		if dispatcher, ok := d.(types.ReportDispatcher); ok {
+			if err := dispatcher.SendReport(op, filterTypes, report); err != nil {
+				log.Errorf("[%s] Failed to send report, err: %s", name, err.Error())
+			}
+		}
+	}
+}
+
+// GetJobName returns the name of the job, or generates one from its position and operation if it is not named
+func GetJobName(job types.Job, index int) string {
+	if len(job.Name) != 0 {
+		return job.Name
+	}
+	return fmt.Sprintf("%d-%s", index+1, job.Operation)
+}
+
+// GetClouds returns the selected cloud types that have a registered provider, or all of them if none is selected
+func GetClouds(selected []types.CloudType) ([]types.CloudType, error) {
+	var clouds []types.CloudType
+	for t := range ctx.CloudProviders {
+		if len(selected) == 0 || containsCloud(selected, t) {
+			clouds = append(clouds, t)
+		}
+	}
+	if len(clouds) == 0 {
+		return nil, fmt.Errorf("cloud provider not found: %s", selected)
+	}
+	return clouds, nil
+}
+
+// GetFilterTypes returns the types of the filters of the job. If the job has a filter expression the filters referred by the
+// expression are returned in the order of their first appearance.
+func GetFilterTypes(job types.Job) []types.FilterType {
+	if len(job.FilterExpression) != 0 {
+		if filterTypes, err := ParseFilterExpression(job.FilterExpression); err == nil {
+			return filterTypes
+		}
+	}
+	return job.GetFilterTypes()
+}
+
+func getFilters(jobFilters []types.JobFilter) ([]types.Filter, error) {
+	var filters []types.Filter
+	for _, jobFilter := range jobFilters {
+		filter, err := getFilter(jobFilter)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func getFilter(jobFilter types.JobFilter) (types.Filter, error) {
+	filter, ok := ctx.Filters[jobFilter.Type]
+	if !ok {
+		return nil, fmt.Errorf("filter is not found: %s", jobFilter.Type)
+	}
+	if len(jobFilter.Parameters) != 0 {
+		parameterized, ok := filter.(types.ParameterizedFilter)
+		if !ok {
+			return nil, fmt.Errorf("filter %s does not accept parameters", jobFilter.Type)
+		}
+		return parameterized.WithParameters(jobFilter.Parameters)
+	}
+	return filter, nil
+}
+
+// The providers that are not used by any of the jobs are removed, so the actions do not initialize them
+func retainCloudProviders(jobs []types.Job) {
+	var selected []types.CloudType
+	for _, job := range jobs {
+		if len(job.Clouds) == 0 {
+			return
+		}
+		selected = append(selected, job.Clouds...)
+	}
+	for t := range ctx.CloudProviders {
+		if !containsCloud(selected, t) {
+			delete(ctx.CloudProviders, t)
+		}
+	}
+}
+
+func containsCloud(clouds []types.CloudType, cloud types.CloudType) bool {
+	for _, c := range clouds {
+		if strings.EqualFold(string(c), string(cloud)) {
+			return true
+		}
+	}
+	return false
+}
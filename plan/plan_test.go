@@ -0,0 +1,266 @@
+package plan
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/hortonworks/cloud-haunter/action"
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/state"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testOperation  = types.OpType("testOperation")
+	panicOperation = types.OpType("panicOperation")
+	testFilter     = types.FilterType("testFilter")
+	validateFilter = types.FilterType("validateFilter")
+	testAction     = types.ActionType("testAction")
+)
+
+var executedActions []testActionCall
+
+type testActionCall struct {
+	op      types.OpType
+	filters []types.FilterType
+	items   []types.CloudItem
+	clouds  []types.CloudType
+}
+
+type testOperationImpl struct {
+}
+
+func (o testOperationImpl) Execute(clouds []types.CloudType) []types.CloudItem {
+	return []types.CloudItem{&types.Instance{Name: "keep", CloudType: clouds[0]}, &types.Instance{Name: "drop", CloudType: clouds[0]}}
+}
+
+type panicOperationImpl struct {
+}
+
+func (o panicOperationImpl) Execute(clouds []types.CloudType) []types.CloudItem {
+	panic(errors.New("operation failed"))
+}
+
+type testFilterImpl struct {
+	name string
+}
+
+func (f testFilterImpl) Execute(items []types.CloudItem) []types.CloudItem {
+	var filtered []types.CloudItem
+	for _, item := range items {
+		if item.GetName() == f.name {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func (f testFilterImpl) WithParameters(parameters map[string]string) (types.Filter, error) {
+	if name, ok := parameters["name"]; ok {
+		return testFilterImpl{name: name}, nil
+	}
+	return nil, errors.New("unknown parameter")
+}
+
+type validateFilterImpl struct {
+	testFilterImpl
+}
+
+func (f validateFilterImpl) Validate(items []types.CloudItem) []types.InvalidItem {
+	var invalidItems []types.InvalidItem
+	for _, item := range items {
+		if item.GetName() != f.name {
+			invalidItems = append(invalidItems, types.InvalidItem{Item: item, Err: errors.New("invalid item")})
+		}
+	}
+	return invalidItems
+}
+
+type testActionImpl struct {
+}
+
+func (a testActionImpl) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	var clouds []types.CloudType
+	for _, item := range items {
+		clouds = append(clouds, item.GetCloudType())
+	}
+	executedActions = append(executedActions, testActionCall{op: op, filters: filters, items: items, clouds: clouds})
+	report := types.NewActionReport(testAction)
+	for _, item := range items {
+		if item.GetName() == "drop" {
+			report.Add(item, types.OutcomeFailed, errors.New("failed to drop"))
+		} else {
+			report.Add(item, types.OutcomeSucceeded, nil)
+		}
+	}
+	return report
+}
+
+func init() {
+	ctx.Operations[testOperation] = testOperationImpl{}
+	ctx.Operations[panicOperation] = panicOperationImpl{}
+	ctx.Filters[testFilter] = testFilterImpl{name: "keep"}
+	ctx.Filters[validateFilter] = validateFilterImpl{testFilterImpl{name: "keep"}}
+	ctx.Actions[testAction] = testActionImpl{}
+	ctx.CloudProviders[types.DUMMY] = func() types.CloudProvider {
+		return nil
+	}
+}
+
+func TestExecuteJob(t *testing.T) {
+	executedActions = nil
+
+	report, err := ExecuteJob(types.Job{Operation: testOperation, Filters: []types.JobFilter{{Type: testFilter}}, Action: testAction, Clouds: []types.CloudType{"dummy"}})
+
+	assert.Nil(t, err)
+	assert.False(t, report.HasFailure())
+	assert.Equal(t, 1, len(executedActions))
+	assert.Equal(t, testOperation, executedActions[0].op)
+	assert.Equal(t, []types.FilterType{testFilter}, executedActions[0].filters)
+	assert.Equal(t, 1, len(executedActions[0].items))
+	assert.Equal(t, "keep", executedActions[0].items[0].GetName())
+	assert.Equal(t, []types.CloudType{types.DUMMY}, executedActions[0].clouds)
+}
+
+func TestExecuteJobWithFilterParameters(t *testing.T) {
+	executedActions = nil
+
+	report, err := ExecuteJob(types.Job{Operation: testOperation, Filters: []types.JobFilter{{Type: testFilter, Parameters: map[string]string{"name": "drop"}}}, Action: testAction})
+
+	assert.Nil(t, err)
+	assert.True(t, report.HasFailure())
+	assert.Equal(t, "drop", executedActions[0].items[0].GetName())
+}
+
+func TestExecuteJobReportsInvalidItems(t *testing.T) {
+	executedActions = nil
+
+	report, err := ExecuteJob(types.Job{Operation: testOperation, Filters: []types.JobFilter{{Type: validateFilter}}, Action: testAction})
+
+	assert.Nil(t, err)
+	assert.False(t, report.HasFailure())
+	assert.Equal(t, 1, len(executedActions[0].items))
+	assert.Equal(t, 1, report.Count(types.OutcomeSucceeded))
+	assert.Equal(t, 1, len(report.GetInvalid()))
+	assert.Equal(t, "drop", report.GetInvalid()[0].Name)
+	assert.Equal(t, "invalid item", report.GetInvalid()[0].Error)
+}
+
+type countingDispatcher struct {
+	sent    int
+	reports int
+}
+
+func (d *countingDispatcher) GetName() string {
+	return "counting"
+}
+
+func (d *countingDispatcher) Send(op types.OpType, filters []types.FilterType, items []types.CloudItem) error {
+	d.sent++
+	return nil
+}
+
+func (d *countingDispatcher) SendReport(op types.OpType, filters []types.FilterType, report *types.ActionReport) error {
+	d.reports++
+	return nil
+}
+
+func TestExecuteJobDispatchesNotificationOnce(t *testing.T) {
+	dispatchers := ctx.Dispatchers
+	defer func() { ctx.Dispatchers = dispatchers }()
+	dispatcher := &countingDispatcher{}
+	ctx.Dispatchers = map[string]types.Dispatcher{"counting": dispatcher}
+
+	_, err := ExecuteJob(types.Job{Operation: testOperation, Filters: []types.JobFilter{{Type: testFilter}}, Action: types.NotificationAction})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, dispatcher.sent)
+	assert.Equal(t, 0, dispatcher.reports)
+
+	_, err = ExecuteJob(types.Job{Operation: testOperation, Filters: []types.JobFilter{{Type: testFilter}}, Action: testAction})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, dispatcher.sent)
+	assert.Equal(t, 1, dispatcher.reports)
+}
+
+type readItemsImpl struct {
+	state types.State
+}
+
+func (o readItemsImpl) Execute(clouds []types.CloudType) []types.CloudItem {
+	return []types.CloudItem{&types.Instance{ID: "i-1", Name: "keep", CloudType: types.DUMMY, State: o.state}}
+}
+
+func TestExecuteJobDoesNotRecordReadItems(t *testing.T) {
+	assert.Nil(t, state.Init(filepath.Join(t.TempDir(), "state.db")))
+	defer state.Close()
+	operation := ctx.Operations[types.ReadItems]
+	defer func() { ctx.Operations[types.ReadItems] = operation }()
+	ctx.Operations[types.ReadItems] = readItemsImpl{state: types.Stopped}
+	item := readItemsImpl{state: types.Running}.Execute(nil)
+	state.RecordItems(item)
+	before, err := state.GetHistory(item[0])
+	assert.Nil(t, err)
+
+	_, err = ExecuteJob(types.Job{Operation: types.ReadItems, Action: testAction, Clouds: []types.CloudType{types.DUMMY}})
+
+	assert.Nil(t, err)
+	after, err := state.GetHistory(item[0])
+	assert.Nil(t, err)
+	assert.Equal(t, types.Running, after.State)
+	assert.Empty(t, after.Transitions)
+	assert.Equal(t, before.StateSince, after.StateSince)
+	assert.Equal(t, before.LastSeen, after.LastSeen)
+	// the action is recorded, as it was executed on the item
+	assert.Equal(t, 1, len(after.Actions))
+}
+
+func TestExecuteJobInvalidJob(t *testing.T) {
+	_, err := ExecuteJob(types.Job{Operation: "unknown", Action: testAction})
+	assert.NotNil(t, err)
+	_, err = ExecuteJob(types.Job{Operation: testOperation, Action: "unknown"})
+	assert.NotNil(t, err)
+	_, err = ExecuteJob(types.Job{Operation: testOperation, Action: testAction, Filters: []types.JobFilter{{Type: "unknown"}}})
+	assert.NotNil(t, err)
+	_, err = ExecuteJob(types.Job{Operation: testOperation, Action: testAction, Filters: []types.JobFilter{{Type: testFilter, Parameters: map[string]string{"unknown": "value"}}}})
+	assert.NotNil(t, err)
+	_, err = ExecuteJob(types.Job{Operation: testOperation, Action: testAction, Clouds: []types.CloudType{"unknown"}})
+	assert.NotNil(t, err)
+}
+
+func TestExecuteJobRecoversFromPanic(t *testing.T) {
+	_, err := ExecuteJob(types.Job{Operation: panicOperation, Action: testAction})
+
+	assert.EqualError(t, err, "operation failed")
+}
+
+func TestExecuteContinuesAfterFailedJob(t *testing.T) {
+	executedActions = nil
+
+	results := Execute(&types.Plan{Jobs: []types.Job{
+		{Name: "failing", Operation: panicOperation, Action: testAction},
+		{Operation: testOperation, Action: testAction},
+	}})
+
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, "failing", results[0].Name)
+	assert.NotNil(t, results[0].Err)
+	assert.Equal(t, "2-testOperation", results[1].Name)
+	assert.Nil(t, results[1].Err)
+	assert.Equal(t, 1, len(executedActions))
+	assert.Equal(t, ExitFatal, GetExitCode(results))
+}
+
+func TestGetExitCode(t *testing.T) {
+	succeeded := types.NewActionReport(testAction)
+	succeeded.Add(&types.Instance{Name: "keep"}, types.OutcomeSucceeded, nil)
+	failed := types.NewActionReport(testAction)
+	failed.Add(&types.Instance{Name: "drop"}, types.OutcomeFailed, errors.New("failed to drop"))
+
+	assert.Equal(t, ExitSuccess, GetExitCode([]JobResult{{Name: "log"}, {Name: "succeeded", Report: succeeded}}))
+	assert.Equal(t, ExitPartialFailure, GetExitCode([]JobResult{{Name: "succeeded", Report: succeeded}, {Name: "failed", Report: failed}}))
+	assert.Equal(t, ExitFatal, GetExitCode([]JobResult{{Name: "failed", Report: failed}, {Name: "error", Err: errors.New("error")}}))
+}
@@ -0,0 +1,168 @@
+package pricing
+
+import (
+	// the default price catalog is shipped with the binary
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultRegion is the region of the catalog that is used if a region is not listed
+const DefaultRegion = "default"
+
+//go:embed catalog.yml
+var defaultCatalog []byte
+
+var catalog *types.PriceCatalog
+
+func init() {
+	var err error
+	if catalog, err = ParseCatalog(defaultCatalog); err != nil {
+		panic("[PRICING] Failed to parse the default price catalog: " + err.Error())
+	}
+	if location := os.Getenv("PRICING_CATALOG"); len(location) > 0 {
+		custom, err := LoadCatalog(location)
+		if err != nil {
+			log.Errorf("[PRICING] Failed to load price catalog: %s, using the default one, err: %s", location, err)
+			return
+		}
+		catalog = custom
+	}
+	log.Infof("[PRICING] Price catalog version: %s", catalog.Version)
+}
+
+// LoadCatalog reads the price catalog from the given location
+func LoadCatalog(location string) (*types.PriceCatalog, error) {
+	raw, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCatalog(raw)
+}
+
+// ParseCatalog parses the price catalog from YAML or JSON
+func ParseCatalog(raw []byte) (*types.PriceCatalog, error) {
+	priceCatalog := &types.PriceCatalog{}
+	if err := yaml.UnmarshalStrict(raw, priceCatalog); err != nil {
+		return nil, err
+	}
+	if len(priceCatalog.Version) == 0 {
+		return nil, fmt.Errorf("version of the price catalog is missing")
+	}
+	return priceCatalog, nil
+}
+
+// GetCurrency returns the currency of the prices in the catalog
+func GetCurrency() string {
+	return catalog.Currency
+}
+
+// SetCatalog replaces the price catalog used by the estimations
+func SetCatalog(priceCatalog *types.PriceCatalog) {
+	catalog = priceCatalog
+}
+
+// SetCosts estimates the cost of the instances, databases and disks based on the price catalog.
+// The items are updated in place, the ones without a price remain without cost.
+func SetCosts(items []types.CloudItem) {
+	now := time.Now()
+	for _, item := range items {
+		switch t := item.(type) {
+		case *types.Instance:
+			t.Cost = estimateCompute(t, t.State, t.StoppedAt, getPrice(t.CloudType, t.Region, t.InstanceType, func(p *types.CloudPrices) map[string]map[string]float64 {
+				return p.Instances
+			}), now)
+		case *types.Database:
+			t.Cost = estimateCompute(t, t.State, nil, getPrice(t.CloudType, t.Region, t.InstanceType, func(p *types.CloudPrices) map[string]map[string]float64 {
+				return p.Databases
+			}), now)
+		case *types.Disk:
+			t.Cost = estimate(item, getDiskHourlyPrice(t), now)
+		}
+	}
+}
+
+// SumPerOwner returns the summarized cost of the cloud items per owner, the items without cost are left out
+func SumPerOwner(items []types.CloudItem) map[string]types.Cost {
+	costs := map[string]types.Cost{}
+	for _, item := range items {
+		if cost := types.GetCost(item); cost != nil {
+			costs[item.GetOwner()] = costs[item.GetOwner()].Add(*cost)
+		}
+	}
+	return costs
+}
+
+// Sum returns the summarized cost of the cloud items, the items without cost are left out
+func Sum(items []types.CloudItem) types.Cost {
+	total := types.Cost{}
+	for _, item := range items {
+		if cost := types.GetCost(item); cost != nil {
+			total = total.Add(*cost)
+		}
+	}
+	return total
+}
+
+func estimate(item types.CloudItem, hourlyPrice *float64, now time.Time) *types.Cost {
+	if hourlyPrice == nil {
+		log.Debugf("[PRICING] Price of %s is not found: %s", item.GetType(), item.GetName())
+		return nil
+	}
+	accrued := 0.0
+	if created := item.GetCreated(); !created.IsZero() && created.Before(now) {
+		accrued = now.Sub(created).Hours() * *hourlyPrice
+	}
+	return &types.Cost{Hourly: *hourlyPrice, Accrued: accrued, Currency: catalog.Currency}
+}
+
+// The compute of the instances and databases that are not running is not charged, the accrued cost lasts until the item was stopped
+// if it is known. The disks of the instances are estimated separately.
+func estimateCompute(item types.CloudItem, state types.State, stoppedAt *time.Time, hourlyPrice *float64, now time.Time) *types.Cost {
+	if state != types.Stopped && state != types.Terminated {
+		return estimate(item, hourlyPrice, now)
+	}
+	if hourlyPrice == nil {
+		log.Debugf("[PRICING] Price of %s is not found: %s", item.GetType(), item.GetName())
+		return nil
+	}
+	accrued := 0.0
+	if created := item.GetCreated(); stoppedAt != nil && !created.IsZero() && created.Before(*stoppedAt) {
+		accrued = stoppedAt.Sub(created).Hours() * *hourlyPrice
+	}
+	return &types.Cost{Hourly: 0, Accrued: accrued, Currency: catalog.Currency}
+}
+
+func getPrice(cloudType types.CloudType, region, resourceType string, getPrices func(*types.CloudPrices) map[string]map[string]float64) *float64 {
+	cloudPrices, ok := catalog.Clouds[cloudType]
+	if !ok || cloudPrices == nil {
+		return nil
+	}
+	prices := getPrices(cloudPrices)
+	for _, r := range []string{region, DefaultRegion} {
+		if price, ok := prices[r][resourceType]; ok {
+			return &price
+		}
+	}
+	return nil
+}
+
+// The type of GCP disks is an URL, the disk type is the last part of it
+func getDiskHourlyPrice(disk *types.Disk) *float64 {
+	diskType := disk.Type[strings.LastIndex(disk.Type, "/")+1:]
+	monthlyPricePerGB := getPrice(disk.CloudType, disk.Region, diskType, func(p *types.CloudPrices) map[string]map[string]float64 {
+		return p.Disks
+	})
+	if monthlyPricePerGB == nil {
+		return nil
+	}
+	hourly := *monthlyPricePerGB * float64(disk.Size) / types.HoursPerMonth
+	return &hourly
+}
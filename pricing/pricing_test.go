@@ -0,0 +1,80 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	defaultPrices, err := ParseCatalog(defaultCatalog)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "USD", defaultPrices.Currency)
+	for _, cloud := range []types.CloudType{types.AWS, types.AZURE, types.GCP} {
+		assert.NotEmpty(t, defaultPrices.Clouds[cloud].Instances[DefaultRegion], cloud)
+	}
+}
+
+func TestParseCatalogWithoutVersion(t *testing.T) {
+	_, err := ParseCatalog([]byte("currency: USD"))
+
+	assert.NotNil(t, err)
+}
+
+func TestSetCosts(t *testing.T) {
+	original := catalog
+	defer SetCatalog(original)
+	testCatalog, err := LoadCatalog("testdata/catalog.json")
+	assert.Nil(t, err)
+	SetCatalog(testCatalog)
+
+	now := time.Now()
+	instance := &types.Instance{CloudType: types.AWS, Region: "us-east-1", InstanceType: "m5.large", Created: now.Add(-10 * time.Hour), Owner: "owner"}
+	regionalInstance := &types.Instance{CloudType: types.AWS, Region: "eu-west-1", InstanceType: "m5.large", Created: now.Add(-10 * time.Hour), Owner: "owner"}
+	disk := &types.Disk{CloudType: types.AWS, Region: "us-east-1", Type: "gp2", Size: 100, Owner: "other"}
+	unknown := &types.Instance{CloudType: types.AWS, Region: "us-east-1", InstanceType: "x1.32xlarge"}
+	database := &types.Database{CloudType: types.GCP, InstanceType: "db-n1-standard-1"}
+	stoppedAt := now.Add(-6 * time.Hour)
+	stopped := &types.Instance{CloudType: types.AWS, Region: "us-east-1", InstanceType: "m5.large", Created: now.Add(-10 * time.Hour), State: types.Stopped,
+		StoppedAt: &stoppedAt}
+	stoppedSinceUnknown := &types.Instance{CloudType: types.AWS, Region: "us-east-1", InstanceType: "m5.large", Created: now.Add(-10 * time.Hour), State: types.Stopped}
+	runningDatabase := &types.Database{CloudType: types.AWS, Region: "us-east-1", InstanceType: "db.m5.large", Created: now.Add(-10 * time.Hour), State: types.Running}
+	stoppedDatabase := &types.Database{CloudType: types.AWS, Region: "us-east-1", InstanceType: "db.m5.large", Created: now.Add(-10 * time.Hour), State: types.Stopped}
+
+	SetCosts([]types.CloudItem{instance, regionalInstance, disk, unknown, database, stopped, stoppedSinceUnknown, runningDatabase, stoppedDatabase})
+
+	assert.Equal(t, 0.1, instance.Cost.Hourly)
+	assert.InDelta(t, 1, instance.Cost.Accrued, 0.001)
+	assert.Equal(t, "EUR", instance.Cost.Currency)
+	assert.Equal(t, 0.2, regionalInstance.Cost.Hourly)
+	assert.InDelta(t, 0.01, disk.Cost.Hourly, 0.0001)
+	assert.Equal(t, 0.0, disk.Cost.Accrued)
+	assert.Nil(t, unknown.Cost)
+	assert.Nil(t, database.Cost)
+	assert.Equal(t, 0.0, stopped.Cost.Hourly)
+	assert.InDelta(t, 0.4, stopped.Cost.Accrued, 0.001)
+	assert.Equal(t, 0.0, stoppedSinceUnknown.Cost.Hourly)
+	assert.Equal(t, 0.0, stoppedSinceUnknown.Cost.Accrued)
+	assert.Equal(t, 0.3, runningDatabase.Cost.Hourly)
+	assert.InDelta(t, 3, runningDatabase.Cost.Accrued, 0.001)
+	assert.Equal(t, 0.0, stoppedDatabase.Cost.Hourly)
+	assert.Equal(t, 0.0, stoppedDatabase.Cost.Accrued)
+
+	costs := SumPerOwner([]types.CloudItem{instance, regionalInstance, disk, unknown})
+
+	assert.Equal(t, 2, len(costs))
+	assert.InDelta(t, 0.3, costs["owner"].Hourly, 0.0001)
+	assert.InDelta(t, 3, costs["owner"].Accrued, 0.001)
+	assert.InDelta(t, 0.31, Sum([]types.CloudItem{instance, regionalInstance, disk, unknown}).Hourly, 0.0001)
+}
+
+func TestGcpDiskType(t *testing.T) {
+	disk := &types.Disk{CloudType: types.GCP, Region: "us-central1", Type: "https://www.googleapis.com/compute/v1/projects/project/zones/us-central1-a/diskTypes/pd-ssd", Size: 730}
+
+	SetCosts([]types.CloudItem{disk})
+
+	assert.InDelta(t, 0.17, disk.Cost.Hourly, 0.0001)
+}
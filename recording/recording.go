@@ -0,0 +1,392 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Redacted replaces the secrets in the recorded interactions. It is valid base64, because some clients decode the keys they receive (e.g. storage account keys).
+const Redacted = "UkVEQUNURUQ="
+
+var (
+	// the credentials in the token requests and in the responses of the token and key endpoints
+	sensitiveFields = map[string]bool{
+		"access_token":     true,
+		"refresh_token":    true,
+		"id_token":         true,
+		"client_secret":    true,
+		"client_assertion": true,
+		"assertion":        true,
+		"password":         true,
+		"accessToken":      true,
+		"privateKeyData":   true,
+	}
+	sensitiveXMLElements = regexp.MustCompile(`<(SecretAccessKey|SessionToken|AccessKeyId)>[^<]*</(SecretAccessKey|SessionToken|AccessKeyId)>`)
+
+	lock      sync.Mutex
+	recordDir string
+	replayer  *Replayer
+
+	// the last sequence number per directory, shared by the recorders of the same directory
+	sequences = map[string]*int64{}
+)
+
+// Interaction is a recorded HTTP request and the response of the cloud API
+type Interaction struct {
+	Method      string      `json:"Method"`
+	URL         string      `json:"URL"`
+	Operation   string      `json:"Operation,omitempty"`
+	RequestBody string      `json:"RequestBody,omitempty"`
+	StatusCode  int         `json:"StatusCode"`
+	Header      http.Header `json:"Header,omitempty"`
+	Body        string      `json:"Body"`
+}
+
+// Record saves every API response of the cloud providers into the directory
+func Record(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if replayer != nil {
+		return errors.New("recording and replaying cannot be enabled at the same time")
+	}
+	recordDir = dir
+	log.Infof("[RECORDING] API responses are recorded to: %s", dir)
+	return nil
+}
+
+// Replay serves the API responses of the cloud providers from a directory created by Record, so no network connection is needed
+func Replay(dir string) error {
+	r, err := NewReplayer(dir)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(recordDir) != 0 {
+		return errors.New("recording and replaying cannot be enabled at the same time")
+	}
+	replayer = r
+	log.Infof("[RECORDING] API responses are replayed from: %s", dir)
+	return nil
+}
+
+// IsEnabled returns true if either recording or replaying is enabled
+func IsEnabled() bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return len(recordDir) != 0 || replayer != nil
+}
+
+// IsReplaying returns true if the API responses are served from a recording, the providers should not authenticate in this case
+func IsReplaying() bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return replayer != nil
+}
+
+// WrapTransport returns the transport the cloud providers have to use for their API calls. It returns the base transport if neither
+// recording nor replaying is enabled and the replayer if the responses are replayed, in which case the base transport is not used at all.
+func WrapTransport(base http.RoundTripper) http.RoundTripper {
+	lock.Lock()
+	defer lock.Unlock()
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	switch {
+	case replayer != nil:
+		return replayer
+	case len(recordDir) != 0:
+		return NewRecorder(recordDir, base)
+	default:
+		return base
+	}
+}
+
+// Recorder is a transport that saves the interactions to a directory, each of them into a separate file in the order of the responses
+type Recorder struct {
+	dir      string
+	base     http.RoundTripper
+	sequence *int64
+}
+
+// NewRecorder creates a transport that records the interactions of the base transport. The interactions are numbered after the
+// ones already in the directory, so an earlier recording is extended instead of overwritten.
+func NewRecorder(dir string, base http.RoundTripper) *Recorder {
+	lock.Lock()
+	defer lock.Unlock()
+	sequence, ok := sequences[dir]
+	if !ok {
+		last := getLastSequence(dir)
+		sequence = &last
+		sequences[dir] = sequence
+	}
+	return &Recorder{dir: dir, base: base, sequence: sequence}
+}
+
+func getLastSequence(dir string) int64 {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0
+	}
+	var last int64
+	for _, name := range names {
+		if current, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), ".json"), 10, 64); err == nil && current > last {
+			last = current
+		}
+	}
+	return last
+}
+
+// RoundTrip executes the request with the base transport and records the response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	interaction := Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		Operation:   getOperation(req, requestBody),
+		RequestBody: redactForm(string(requestBody)),
+		StatusCode:  resp.StatusCode,
+		Header:      header,
+		Body:        redactBody(string(body)),
+	}
+	if err := r.save(interaction); err != nil {
+		log.Errorf("[RECORDING] Failed to record response of %s %s, err: %s", req.Method, req.URL.Redacted(), err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(interaction Interaction) error {
+	out, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(r.dir, fmt.Sprintf("%06d.json", atomic.AddInt64(r.sequence, 1)))
+	log.Debugf("[RECORDING] Recording %s %s to %s", interaction.Method, interaction.URL, name)
+	return os.WriteFile(name, out, 0600)
+}
+
+// Replayer is a transport that serves the recorded responses. The requests are matched by method, URL and body first, if there is
+// no such recording (e.g. the request contains a timestamp), then by method, URL without the query and the API operation.
+// The responses of the same request are served in the recorded order, the last one is repeated (e.g. polling until a state is reached).
+type Replayer struct {
+	lock     sync.Mutex
+	exact    map[string]*queue
+	fallback map[string]*queue
+}
+
+type queue struct {
+	interactions []*Interaction
+	next         int
+}
+
+func (q *queue) pop() *Interaction {
+	interaction := q.interactions[q.next]
+	if q.next < len(q.interactions)-1 {
+		q.next++
+	}
+	return interaction
+}
+
+// NewReplayer loads the interactions recorded to the directory
+func NewReplayer(dir string) (*Replayer, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no recorded interactions found in %s", dir)
+	}
+	sort.Strings(names)
+	r := &Replayer{exact: map[string]*queue{}, fallback: map[string]*queue{}}
+	for _, name := range names {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(content, &interaction); err != nil {
+			return nil, fmt.Errorf("invalid recorded interaction %s, err: %s", name, err)
+		}
+		u, err := url.Parse(interaction.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL in recorded interaction %s, err: %s", name, err)
+		}
+		add(r.exact, exactKey(interaction.Method, interaction.URL, interaction.RequestBody), &interaction)
+		add(r.fallback, fallbackKey(interaction.Method, u, interaction.Operation), &interaction)
+	}
+	log.Debugf("[RECORDING] Loaded %d recorded interactions from %s", len(names), dir)
+	return r, nil
+}
+
+func add(queues map[string]*queue, key string, interaction *Interaction) {
+	if _, ok := queues[key]; !ok {
+		queues[key] = &queue{}
+	}
+	queues[key].interactions = append(queues[key].interactions, interaction)
+}
+
+// RoundTrip serves the recorded response of the request, it returns an error if the request was not recorded
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	r.lock.Lock()
+	q, ok := r.exact[exactKey(req.Method, req.URL.String(), redactForm(string(requestBody)))]
+	if !ok {
+		q, ok = r.fallback[fallbackKey(req.Method, req.URL, getOperation(req, requestBody))]
+	}
+	var interaction *Interaction
+	if ok {
+		interaction = q.pop()
+	}
+	r.lock.Unlock()
+
+	if interaction == nil {
+		log.Errorf("[RECORDING] No recorded response for %s %s", req.Method, req.URL.Redacted())
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.Redacted())
+	}
+	log.Debugf("[RECORDING] Replaying %s %s", req.Method, req.URL.Redacted())
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}, nil
+}
+
+func exactKey(method, rawURL, body string) string {
+	return method + " " + rawURL + "\n" + body
+}
+
+func fallbackKey(method string, u *url.URL, operation string) string {
+	return method + " " + u.Scheme + "://" + u.Host + u.Path + " " + operation
+}
+
+// The AWS APIs share the same URL, the operation is either in the Action parameter (query protocol) or in the X-Amz-Target header (JSON protocol)
+func getOperation(req *http.Request, body []byte) string {
+	if target := req.Header.Get("X-Amz-Target"); len(target) != 0 {
+		return target
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return values.Get("Action")
+		}
+	}
+	return ""
+}
+
+// Only the forms with credentials are re-encoded, so the other requests are recorded as they were sent
+func redactForm(body string) string {
+	if len(body) == 0 || strings.HasPrefix(body, "{") || strings.HasPrefix(body, "<") {
+		return body
+	}
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return body
+	}
+	redacted := false
+	for key := range values {
+		if sensitiveFields[key] {
+			values.Set(key, Redacted)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	return values.Encode()
+}
+
+func redactBody(body string) string {
+	body = sensitiveXMLElements.ReplaceAllString(body, "<$1>"+Redacted+"</$2>")
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return body
+	}
+	var content interface{}
+	if err := json.Unmarshal([]byte(body), &content); err != nil {
+		return body
+	}
+	if !redactJSON(content) {
+		return body
+	}
+	out, err := json.Marshal(content)
+	if err != nil {
+		return body
+	}
+	return string(out)
+}
+
+// The storage account keys are returned as {"keyName": "key1", "value": "..."}
+func redactJSON(content interface{}) bool {
+	redacted := false
+	switch value := content.(type) {
+	case map[string]interface{}:
+		_, isKey := value["keyName"]
+		for k, v := range value {
+			if _, ok := v.(string); ok && (sensitiveFields[k] || (isKey && k == "value")) {
+				value[k] = Redacted
+				redacted = true
+			} else if redactJSON(v) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, v := range value {
+			if redactJSON(v) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,136 @@
+package recording
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer() *httptest.Server {
+	calls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token": "secret", "token_type": "Bearer"}`)
+		case "/keys":
+			fmt.Fprint(w, `{"keys": [{"keyName": "key1", "value": "secret"}]}`)
+		default:
+			fmt.Fprintf(w, "call %d: %s %s", calls, r.URL.RawQuery, body)
+		}
+	}))
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func postForm(t *testing.T, client *http.Client, url string, values url.Values) string {
+	resp, err := client.PostForm(url, values)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newServer()
+	defer server.Close()
+	dir := t.TempDir()
+	recorder := &http.Client{Transport: NewRecorder(dir, http.DefaultTransport)}
+
+	assert.Equal(t, "call 1: page=1 ", get(t, recorder, server.URL+"/items?page=1"))
+	assert.Equal(t, "call 2: page=2 ", get(t, recorder, server.URL+"/items?page=2"))
+	assert.Equal(t, "call 3:  Action=DescribeInstances", postForm(t, recorder, server.URL+"/", url.Values{"Action": {"DescribeInstances"}}))
+	assert.Equal(t, "call 4:  Action=DescribeVolumes", postForm(t, recorder, server.URL+"/", url.Values{"Action": {"DescribeVolumes"}}))
+	assert.Equal(t, "call 5:  Action=DescribeVolumes", postForm(t, recorder, server.URL+"/", url.Values{"Action": {"DescribeVolumes"}}))
+	server.Close()
+
+	replayer, err := NewReplayer(dir)
+	assert.Nil(t, err)
+	client := &http.Client{Transport: replayer}
+
+	assert.Equal(t, "call 2: page=2 ", get(t, client, server.URL+"/items?page=2"))
+	assert.Equal(t, "call 1: page=1 ", get(t, client, server.URL+"/items?page=1"))
+	// the responses of the same request are replayed in order, the last one is repeated
+	assert.Equal(t, "call 4:  Action=DescribeVolumes", postForm(t, client, server.URL+"/", url.Values{"Action": {"DescribeVolumes"}}))
+	assert.Equal(t, "call 5:  Action=DescribeVolumes", postForm(t, client, server.URL+"/", url.Values{"Action": {"DescribeVolumes"}}))
+	assert.Equal(t, "call 5:  Action=DescribeVolumes", postForm(t, client, server.URL+"/", url.Values{"Action": {"DescribeVolumes"}}))
+	// the requests that contain e.g. timestamps are matched by the operation
+	assert.Equal(t, "call 3:  Action=DescribeInstances", postForm(t, client, server.URL+"/", url.Values{"Action": {"DescribeInstances"}, "Time": {"now"}}))
+	assert.Equal(t, "call 1: page=1 ", get(t, client, server.URL+"/items?page=3"))
+
+	_, err = client.Get(server.URL + "/unknown")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no recorded response for GET")
+}
+
+func TestRecordContinuesExistingRecording(t *testing.T) {
+	server := newServer()
+	defer server.Close()
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "000041.json"), []byte(`{"Method": "GET", "URL": "http://localhost/old", "StatusCode": 200}`), 0600))
+
+	get(t, &http.Client{Transport: NewRecorder(dir, http.DefaultTransport)}, server.URL+"/items?page=1")
+	get(t, &http.Client{Transport: NewRecorder(dir, http.DefaultTransport)}, server.URL+"/items?page=2")
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	assert.Equal(t, []string{"000041.json", "000042.json", "000043.json"}, names)
+}
+
+func TestRecordRedactsCredentials(t *testing.T) {
+	server := newServer()
+	defer server.Close()
+	dir := t.TempDir()
+	recorder := &http.Client{Transport: NewRecorder(dir, http.DefaultTransport)}
+
+	assert.Contains(t, postForm(t, recorder, server.URL+"/token", url.Values{"grant_type": {"client_credentials"}, "client_secret": {"secret"}}), `"access_token": "secret"`)
+	assert.Contains(t, get(t, recorder, server.URL+"/keys"), `"value": "secret"`)
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Equal(t, 2, len(names))
+	for _, name := range names {
+		content, err := os.ReadFile(name)
+		assert.Nil(t, err)
+		assert.NotContains(t, strings.ReplaceAll(string(content), "client_secret", ""), "secret")
+		assert.Contains(t, string(content), Redacted)
+	}
+
+	replayer, err := NewReplayer(dir)
+	assert.Nil(t, err)
+	client := &http.Client{Transport: replayer}
+	assert.Contains(t, postForm(t, client, server.URL+"/token", url.Values{"grant_type": {"client_credentials"}, "client_secret": {"other"}}), Redacted)
+}
+
+func TestRedactXML(t *testing.T) {
+	body := redactBody(`<Credentials><AccessKeyId>id</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><Expiration>now</Expiration></Credentials>`)
+
+	assert.Equal(t, `<Credentials><AccessKeyId>`+Redacted+`</AccessKeyId><SecretAccessKey>`+Redacted+`</SecretAccessKey><Expiration>now</Expiration></Credentials>`, body)
+}
+
+func TestNewReplayerWithoutRecording(t *testing.T) {
+	_, err := NewReplayer(t.TempDir())
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,161 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/plan"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ResultSucceeded the job was executed and its action succeeded on all the cloud items
+	ResultSucceeded = "succeeded"
+
+	// ResultPartiallyFailed the job was executed, but its action failed on some of the cloud items
+	ResultPartiallyFailed = "partially failed"
+
+	// ResultFailed the job could not be executed
+	ResultFailed = "failed"
+)
+
+// Scheduler executes the jobs of a schedule by their cron expressions. A job is not started while its previous execution is running.
+type Scheduler struct {
+	cron *cron.Cron
+	jobs []*scheduledJob
+}
+
+// JobStatus is the state of a scheduled job, the fields of the last run are empty until the job is executed for the first time
+type JobStatus struct {
+	Name         string              `json:"Name"`
+	Cron         string              `json:"Cron"`
+	Running      bool                `json:"Running"`
+	NextRun      time.Time           `json:"NextRun"`
+	LastRun      *time.Time          `json:"LastRun,omitempty"`
+	LastDuration string              `json:"LastDuration,omitempty"`
+	LastResult   string              `json:"LastResult,omitempty"`
+	LastError    string              `json:"LastError,omitempty"`
+	LastReport   *types.ActionReport `json:"LastReport,omitempty"`
+	SkippedRuns  int                 `json:"SkippedRuns"`
+}
+
+type scheduledJob struct {
+	name    string
+	job     types.ScheduledJob
+	entryID cron.EntryID
+	running atomic.Bool
+
+	lock   sync.Mutex
+	status JobStatus
+}
+
+// New validates the jobs of the schedule and registers them by their cron expressions, the jobs are started by Start
+func New(schedule *types.Schedule) (*Scheduler, error) {
+	s := &Scheduler{
+		cron: cron.New(cron.WithChain(cron.Recover(cron.PrintfLogger(log.StandardLogger())))),
+	}
+	names := map[string]bool{}
+	for i, job := range schedule.Jobs {
+		name := plan.GetJobName(job.Job, i)
+		if names[name] {
+			return nil, fmt.Errorf("job name is not unique: %s", name)
+		}
+		names[name] = true
+		if err := plan.Validate(job.Job); err != nil {
+			return nil, fmt.Errorf("invalid job %s: %s", name, err)
+		}
+
+		j := &scheduledJob{name: name, job: job, status: JobStatus{Name: name, Cron: job.Cron}}
+		entryID, err := s.cron.AddFunc(job.Cron, j.run)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression of job %s: %s, err: %s", name, job.Cron, err)
+		}
+		j.entryID = entryID
+		s.jobs = append(s.jobs, j)
+	}
+	return s, nil
+}
+
+// Start starts the scheduling of the jobs in the background
+func (s *Scheduler) Start() {
+	s.cron.Start()
+	for _, status := range s.GetStatuses() {
+		log.Infof("[SCHEDULE] Job %s is scheduled by: %s, next run: %s", status.Name, status.Cron, status.NextRun.Format(time.RFC3339))
+	}
+}
+
+// Stop stops the scheduling of the jobs and waits for the running ones to finish
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// GetStatuses returns the state of the jobs ordered by their names
+func (s *Scheduler) GetStatuses() []JobStatus {
+	var statuses []JobStatus
+	for _, j := range s.jobs {
+		status := j.getStatus()
+		status.NextRun = s.cron.Entry(j.entryID).Next
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].Name < statuses[k].Name
+	})
+	return statuses
+}
+
+func (j *scheduledJob) run() {
+	if !j.running.CompareAndSwap(false, true) {
+		log.Warnf("[SCHEDULE] Job %s is still running, skipping its execution", j.name)
+		j.lock.Lock()
+		j.status.SkippedRuns++
+		j.lock.Unlock()
+		return
+	}
+	defer j.running.Store(false)
+
+	if ctx.IsShuttingDown() {
+		log.Infof("[SCHEDULE] Shutting down, job %s is not executed", j.name)
+		return
+	}
+
+	start := time.Now()
+	result := plan.RunJob(j.job.Job, j.name)
+	duration := time.Since(start).Round(time.Second)
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.status.LastRun = &start
+	j.status.LastDuration = duration.String()
+	j.status.LastResult = getResult(result)
+	j.status.LastError = ""
+	if result.Err != nil {
+		j.status.LastError = result.Err.Error()
+	}
+	j.status.LastReport = result.Report
+	log.Infof("[SCHEDULE] Job %s %s in %s", j.name, j.status.LastResult, duration)
+}
+
+func (j *scheduledJob) getStatus() JobStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	status := j.status
+	status.Running = j.running.Load()
+	return status
+}
+
+func getResult(result plan.JobResult) string {
+	switch plan.GetExitCode([]plan.JobResult{result}) {
+	case plan.ExitFatal:
+		return ResultFailed
+	case plan.ExitPartialFailure:
+		return ResultPartiallyFailed
+	default:
+		return ResultSucceeded
+	}
+}
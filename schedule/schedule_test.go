@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testOperation = types.OpType("testOperation")
+	testAction    = types.ActionType("testAction")
+	failingAction = types.ActionType("failingAction")
+)
+
+type testOperationImpl struct {
+}
+
+func (o testOperationImpl) Execute(clouds []types.CloudType) []types.CloudItem {
+	return []types.CloudItem{&types.Instance{Name: "instance", CloudType: types.DUMMY}}
+}
+
+// testActionImpl blocks until the release channel is closed, if there is one
+type testActionImpl struct {
+	lock    *sync.Mutex
+	started chan bool
+	release chan bool
+	calls   *int
+}
+
+func (a testActionImpl) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	a.lock.Lock()
+	*a.calls++
+	a.lock.Unlock()
+	if a.release != nil {
+		a.started <- true
+		<-a.release
+	}
+	report := types.NewActionReport(testAction)
+	for _, item := range items {
+		report.Add(item, types.OutcomeSucceeded, nil)
+	}
+	return report
+}
+
+type failingActionImpl struct {
+}
+
+func (a failingActionImpl) Execute(op types.OpType, filters []types.FilterType, items []types.CloudItem) *types.ActionReport {
+	panic("action failed")
+}
+
+func init() {
+	ctx.Operations[testOperation] = testOperationImpl{}
+	ctx.Actions[failingAction] = failingActionImpl{}
+	ctx.CloudProviders[types.DUMMY] = func() types.CloudProvider {
+		return nil
+	}
+}
+
+func newTestAction(blocking bool) testActionImpl {
+	action := testActionImpl{lock: &sync.Mutex{}, calls: new(int)}
+	if blocking {
+		action.started = make(chan bool)
+		action.release = make(chan bool)
+	}
+	ctx.Actions[testAction] = action
+	return action
+}
+
+func newTestSchedule(jobs ...types.ScheduledJob) *types.Schedule {
+	return &types.Schedule{Jobs: jobs}
+}
+
+func newTestJob(name string, action types.ActionType) types.ScheduledJob {
+	return types.ScheduledJob{Job: types.Job{Name: name, Operation: testOperation, Action: action}, Cron: "@hourly"}
+}
+
+func TestNew(t *testing.T) {
+	newTestAction(false)
+
+	scheduler, err := New(newTestSchedule(newTestJob("b", testAction), newTestJob("a", testAction)))
+
+	assert.Nil(t, err)
+	statuses := scheduler.GetStatuses()
+	assert.Equal(t, 2, len(statuses))
+	assert.Equal(t, "a", statuses[0].Name)
+	assert.Equal(t, "@hourly", statuses[0].Cron)
+	assert.Nil(t, statuses[0].LastRun)
+}
+
+func TestNewInvalidSchedule(t *testing.T) {
+	newTestAction(false)
+
+	_, err := New(newTestSchedule(newTestJob("a", testAction), newTestJob("a", testAction)))
+	assert.EqualError(t, err, "job name is not unique: a")
+
+	invalidCron := newTestJob("a", testAction)
+	invalidCron.Cron = "every hour"
+	_, err = New(newTestSchedule(invalidCron))
+	assert.NotNil(t, err)
+
+	_, err = New(newTestSchedule(newTestJob("a", types.ActionType("unknown"))))
+	assert.EqualError(t, err, "invalid job a: action is not found: unknown")
+}
+
+func TestRun(t *testing.T) {
+	action := newTestAction(false)
+	scheduler, _ := New(newTestSchedule(newTestJob("succeeding", testAction), newTestJob("failing", failingAction)))
+
+	for _, j := range scheduler.jobs {
+		j.run()
+	}
+
+	statuses := scheduler.GetStatuses()
+	assert.Equal(t, 1, *action.calls)
+	assert.Equal(t, "failing", statuses[0].Name)
+	assert.Equal(t, ResultFailed, statuses[0].LastResult)
+	assert.Equal(t, "action failed", statuses[0].LastError)
+	assert.NotNil(t, statuses[0].LastRun)
+	assert.Equal(t, "succeeding", statuses[1].Name)
+	assert.Equal(t, ResultSucceeded, statuses[1].LastResult)
+	assert.Equal(t, 1, statuses[1].LastReport.Count(types.OutcomeSucceeded))
+	assert.False(t, statuses[1].Running)
+}
+
+func TestRunSkipsOverlappingExecution(t *testing.T) {
+	action := newTestAction(true)
+	scheduler, _ := New(newTestSchedule(newTestJob("blocking", testAction)))
+	job := scheduler.jobs[0]
+
+	done := make(chan bool)
+	go func() {
+		job.run()
+		done <- true
+	}()
+	<-action.started
+
+	assert.True(t, scheduler.GetStatuses()[0].Running)
+	job.run()
+	close(action.release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+	}
+
+	status := scheduler.GetStatuses()[0]
+	assert.Equal(t, 1, *action.calls)
+	assert.Equal(t, 1, status.SkippedRuns)
+	assert.Equal(t, ResultSucceeded, status.LastResult)
+	assert.False(t, status.Running)
+}
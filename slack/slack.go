@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	ctx "github.com/hortonworks/cloud-haunter/context"
+	"github.com/hortonworks/cloud-haunter/pricing"
 	"github.com/hortonworks/cloud-haunter/types"
 	"github.com/hortonworks/cloud-haunter/utils"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 	"os"
+	"strings"
 )
 
 const (
@@ -17,6 +19,9 @@ const (
 
 	// GreenColor Hex code of color green
 	GreenColor = "#008000"
+
+	// YellowColor Hex code of color yellow
+	YellowColor = "#FFD700"
 )
 
 type slackDispatcher struct {
@@ -76,6 +81,20 @@ func (d slackDispatcher) Send(op types.OpType, filters []types.FilterType, items
 	return nil
 }
 
+func (d slackDispatcher) SendReport(op types.OpType, filters []types.FilterType, report *types.ActionReport) error {
+	message := d.generateReportMessage(op, filters, report)
+	if ctx.DryRun {
+		json, err := utils.CovertJsonToString(message)
+		if err != nil {
+			return err
+		}
+		log.Infof("[SLACK] Skipping report on dry run session, generated message: %s", *json)
+	} else {
+		return d.send(message)
+	}
+	return nil
+}
+
 func (d slackDispatcher) send(message slackMessage) error {
 	json, err := utils.CovertJsonToString(message)
 	if err != nil {
@@ -121,34 +140,18 @@ func (d slackDispatcher) generateMessage(op types.OpType, filters []types.Filter
 	var buffer bytes.Buffer
 
 	for owner, items := range itemsPerOwner {
-		buffer.WriteString(fmt.Sprintf("\n*Owner*: %s *items*: %d\n", owner, len(items)))
+		buffer.WriteString(fmt.Sprintf("\n*Owner*: %s *items*: %d", owner, len(items)))
+		if hasCost(items) {
+			buffer.WriteString(fmt.Sprintf(" *cost*: %s", pricing.Sum(items)))
+		}
+		buffer.WriteString("\n")
 		summaryAttach.Fields = append(summaryAttach.Fields, field{
 			Title: fmt.Sprintf("*%s*: %d", owner, len(items)),
 			Short: true,
 		})
 
 		for _, item := range items {
-			displayTime := item.GetCreated().Format("2006-01-02 15:04:05")
-			switch item.GetItem().(type) {
-			case types.Instance:
-				inst := item.GetItem().(types.Instance)
-				msg := fmt.Sprintf("*[%s]* *%s*: %s *type*: %s *created*: %s *region*: %s", item.GetCloudType(), item.GetType(), item.GetName(), inst.InstanceType, displayTime, inst.Region)
-				if len(inst.Metadata) > 0 {
-					msg += fmt.Sprintf(" metadata: %s", inst.Metadata)
-				}
-				msg += "\n"
-				buffer.WriteString(msg)
-			case types.Database:
-				db := item.GetItem().(types.Database)
-				msg := fmt.Sprintf("*[%s]* *%s*: %s *type*: %s *created*: %s *region*: %s", item.GetCloudType(), item.GetType(), item.GetName(), db.InstanceType, displayTime, db.Region)
-				if len(db.Metadata) > 0 {
-					msg += fmt.Sprintf(" metadata: %s", db.Metadata)
-				}
-				msg += "\n"
-				buffer.WriteString(msg)
-			default:
-				buffer.WriteString(fmt.Sprintf("*[%s]* *%s*: %s\n", item.GetCloudType(), item.GetType(), item.GetName()))
-			}
+			buffer.WriteString(formatItem(item) + "\n")
 		}
 
 		buffer.WriteString("\n")
@@ -159,3 +162,163 @@ func (d slackDispatcher) generateMessage(op types.OpType, filters []types.Filter
 
 	return message
 }
+
+// The total is shown if any item has an estimated cost, even if it is zero per hour, e.g. because the items are stopped
+func hasCost(items []types.CloudItem) bool {
+	for _, item := range items {
+		if types.GetCost(item) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func formatItem(item types.CloudItem) string {
+	displayTime := item.GetCreated().Format("2006-01-02 15:04:05")
+	switch item.GetItem().(type) {
+	case types.Instance:
+		inst := item.GetItem().(types.Instance)
+		msg := fmt.Sprintf("*[%s]* *%s*: %s *type*: %s *created*: %s *region*: %s", item.GetCloudType(), item.GetType(), item.GetName(), inst.InstanceType, displayTime, inst.Region)
+		if inst.Cost != nil {
+			msg += fmt.Sprintf(" *cost*: %s", inst.Cost)
+		}
+		if len(inst.Metadata) > 0 {
+			msg += fmt.Sprintf(" metadata: %s", inst.Metadata)
+		}
+		return msg
+	case types.Database:
+		db := item.GetItem().(types.Database)
+		msg := fmt.Sprintf("*[%s]* *%s*: %s *type*: %s *created*: %s *region*: %s", item.GetCloudType(), item.GetType(), item.GetName(), db.InstanceType, displayTime, db.Region)
+		if db.Cost != nil {
+			msg += fmt.Sprintf(" *cost*: %s", db.Cost)
+		}
+		if len(db.Metadata) > 0 {
+			msg += fmt.Sprintf(" metadata: %s", db.Metadata)
+		}
+		return msg
+	case types.Disk:
+		disk := item.GetItem().(types.Disk)
+		msg := fmt.Sprintf("*[%s]* *%s*: %s *type*: %s *size*: %d GB *created*: %s *region*: %s", item.GetCloudType(), item.GetType(), item.GetName(), disk.Type, disk.Size, displayTime, disk.Region)
+		if disk.Cost != nil {
+			msg += fmt.Sprintf(" *cost*: %s", disk.Cost)
+		}
+		return msg
+	default:
+		return fmt.Sprintf("*[%s]* *%s*: %s", item.GetCloudType(), item.GetType(), item.GetName())
+	}
+}
+
+func (d slackDispatcher) SendDiff(op types.OpType, filters []types.FilterType, diff *types.Diff) error {
+	message := d.generateDiffMessage(op, filters, diff)
+	if ctx.DryRun {
+		json, err := utils.CovertJsonToString(message)
+		if err != nil {
+			return err
+		}
+		log.Infof("[SLACK] Skipping diff on dry run session, generated message: %s", *json)
+	} else {
+		return d.send(message)
+	}
+	return nil
+}
+
+// The new items without owner are highlighted, the removed ones are only listed
+func (d slackDispatcher) generateDiffMessage(op types.OpType, filters []types.FilterType, diff *types.Diff) slackMessage {
+	message := slackMessage{}
+	message.Text = fmt.Sprintf("*Changes since the previous run* *Operation*: %s *Filters*: %s *Accounts*: %s\n", op, utils.GetFilterNames(filters), utils.GetCloudAccountNames())
+
+	if len(diff.Added) != 0 {
+		var buffer bytes.Buffer
+		color := YellowColor
+		for _, item := range diff.Added {
+			if owner := item.GetOwner(); len(owner) == 0 || owner == "???" {
+				color = RedColor
+			}
+			buffer.WriteString(formatItem(item) + "\n")
+		}
+		message.Attachments = append(message.Attachments, attachment{
+			MarkdownIn: []string{"text", "pretext"},
+			Color:      color,
+			Pretext:    fmt.Sprintf("*Added*: %d", len(diff.Added)),
+			Text:       buffer.String(),
+		})
+	}
+	if len(diff.Removed) != 0 {
+		var buffer bytes.Buffer
+		for _, item := range diff.Removed {
+			buffer.WriteString(formatItem(item) + "\n")
+		}
+		message.Attachments = append(message.Attachments, attachment{
+			MarkdownIn: []string{"text", "pretext"},
+			Color:      GreenColor,
+			Pretext:    fmt.Sprintf("*Removed*: %d", len(diff.Removed)),
+			Text:       buffer.String(),
+		})
+	}
+	if len(diff.Changed) != 0 {
+		var buffer bytes.Buffer
+		for _, change := range diff.Changed {
+			buffer.WriteString(formatItem(change.Item))
+			for _, fieldChange := range change.Changes {
+				buffer.WriteString(fmt.Sprintf(" *%s*: %s -> %s", strings.ToLower(fieldChange.Field), fieldChange.Previous, fieldChange.Current))
+			}
+			buffer.WriteString("\n")
+		}
+		message.Attachments = append(message.Attachments, attachment{
+			MarkdownIn: []string{"text", "pretext"},
+			Color:      YellowColor,
+			Pretext:    fmt.Sprintf("*Changed*: %d", len(diff.Changed)),
+			Text:       buffer.String(),
+		})
+	}
+	return message
+}
+
+func (d slackDispatcher) generateReportMessage(op types.OpType, filters []types.FilterType, report *types.ActionReport) slackMessage {
+	message := slackMessage{}
+	message.Text = fmt.Sprintf("*Action*: %s *Operation*: %s *Filters*: %s *Accounts*: %s\n", report.Action, op, utils.GetFilterNames(filters), utils.GetCloudAccountNames())
+
+	color := GreenColor
+	if report.HasFailure() {
+		color = RedColor
+	}
+
+	summaryAttach := attachment{
+		MarkdownIn: []string{"text", "pretext"},
+		Color:      color,
+	}
+	for _, outcome := range []types.ActionOutcome{types.OutcomeSucceeded, types.OutcomeFailed, types.OutcomeDryRun, types.OutcomeInvalid} {
+		summaryAttach.Fields = append(summaryAttach.Fields, field{
+			Title: fmt.Sprintf("*%s*: %d", outcome, report.Count(outcome)),
+			Short: true,
+		})
+	}
+	if savings := report.GetMonthlySavings(); savings > 0 {
+		summaryAttach.Fields = append(summaryAttach.Fields, field{
+			Title: fmt.Sprintf("*estimated monthly savings*: %.2f %s", savings, pricing.GetCurrency()),
+			Short: true,
+		})
+	}
+	message.Attachments = []attachment{summaryAttach}
+
+	if failed := report.GetFailed(); len(failed) != 0 {
+		message.Attachments = append(message.Attachments, generateResultsAttachment(failed, color))
+	}
+	if invalid := report.GetInvalid(); len(invalid) != 0 {
+		message.Attachments = append(message.Attachments, generateResultsAttachment(invalid, YellowColor))
+	}
+
+	return message
+}
+
+func generateResultsAttachment(results []types.ActionResult, color string) attachment {
+	var buffer bytes.Buffer
+	for _, result := range results {
+		buffer.WriteString(fmt.Sprintf("*[%s]* *%s*: %s *id*: %s *region*: %s *error*: %s\n", result.CloudType, result.ItemType, result.Name, result.ID, result.Region, result.Error))
+	}
+	return attachment{
+		MarkdownIn: []string{"text", "pretext"},
+		Color:      color,
+		Text:       buffer.String(),
+	}
+}
@@ -0,0 +1,290 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// the histories are limited, so the items that change frequently do not grow the store without bounds
+	maxTransitions = 50
+	maxActions     = 50
+
+	defaultRetention = 90 * 24 * time.Hour
+)
+
+var (
+	itemsBucket = []byte("items")
+
+	lock    sync.RWMutex
+	current *Store
+)
+
+// ItemHistory is what the store knows about a cloud item from the previous runs
+type ItemHistory struct {
+	CloudType  types.CloudType `json:"CloudType"`
+	ItemType   string          `json:"ItemType"`
+	ID         string          `json:"Id"`
+	Name       string          `json:"Name"`
+	FirstSeen  time.Time       `json:"FirstSeen"`
+	LastSeen   time.Time       `json:"LastSeen"`
+	State      types.State     `json:"State"`
+	StateSince time.Time       `json:"StateSince"`
+	Owner      string          `json:"Owner"`
+	OwnerSince time.Time       `json:"OwnerSince"`
+
+	Transitions []StateTransition `json:"Transitions,omitempty"`
+	Actions     []ActionRecord    `json:"Actions,omitempty"`
+}
+
+// StateTransition is a change of the state of an item between two runs, the time is when the change was first seen
+type StateTransition struct {
+	From types.State `json:"From"`
+	To   types.State `json:"To"`
+	At   time.Time   `json:"At"`
+}
+
+// ActionRecord is the result of an action executed on an item
+type ActionRecord struct {
+	Action  types.ActionType    `json:"Action"`
+	Outcome types.ActionOutcome `json:"Outcome"`
+	Error   string              `json:"Error,omitempty"`
+	At      time.Time           `json:"At"`
+}
+
+// Store keeps the history of the items in a local BoltDB file
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+	now       func() time.Time
+}
+
+// Open opens or creates the store. The items that are not seen for longer than the retention are removed.
+func Open(path string, retention time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store %s, err: %s", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(itemsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, retention: retention, now: time.Now}, nil
+}
+
+// Init opens the store used by the plan and the filters. The retention is read from STATE_RETENTION (default: 2160h).
+func Init(path string) error {
+	retention := defaultRetention
+	if retentionEnv := os.Getenv("STATE_RETENTION"); len(retentionEnv) > 0 {
+		var err error
+		if retention, err = time.ParseDuration(retentionEnv); err != nil {
+			return fmt.Errorf("invalid state retention: %s, err: %s", retentionEnv, err)
+		}
+	}
+	store, err := Open(path, retention)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	current = store
+	log.Infof("[STATE] Item history is stored in: %s", path)
+	return nil
+}
+
+// Close closes the store opened by Init
+func Close() {
+	lock.Lock()
+	defer lock.Unlock()
+	if current != nil {
+		if err := current.Close(); err != nil {
+			log.Errorf("[STATE] Failed to close state store, err: %s", err)
+		}
+		current = nil
+	}
+}
+
+// IsEnabled returns true if the state store is configured
+func IsEnabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current != nil
+}
+
+// RecordItems records the items seen by an operation, if the state store is configured
+func RecordItems(items []types.CloudItem) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if current == nil {
+		return
+	}
+	if err := current.RecordItems(items); err != nil {
+		log.Errorf("[STATE] Failed to record items, err: %s", err)
+	}
+}
+
+// RecordReport records the results of an action, if the state store is configured
+func RecordReport(report *types.ActionReport) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if current == nil || report == nil {
+		return
+	}
+	if err := current.RecordReport(report); err != nil {
+		log.Errorf("[STATE] Failed to record report, err: %s", err)
+	}
+}
+
+// GetHistory returns the history of the item, nil is returned if the state store is not configured or the item was never seen
+func GetHistory(item types.CloudItem) (*ItemHistory, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if current == nil {
+		return nil, nil
+	}
+	return current.GetHistory(item)
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordItems updates the last seen time, the state and the owner of the items. The first time an item is seen its state and owner
+// are considered to be set at that time, as the store cannot know what happened before.
+func (s *Store) RecordItems(items []types.CloudItem) error {
+	now := s.now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(itemsBucket)
+		for _, item := range items {
+			key := []byte(types.GetItemKey(item))
+			history, err := getHistory(bucket, key)
+			if err != nil {
+				return err
+			}
+			state, owner := types.GetState(item), item.GetOwner()
+			if history == nil {
+				history = &ItemHistory{
+					CloudType:  item.GetCloudType(),
+					ItemType:   item.GetType(),
+					ID:         item.GetID(),
+					FirstSeen:  now,
+					State:      state,
+					StateSince: now,
+					Owner:      owner,
+					OwnerSince: now,
+				}
+			}
+			if history.State != state {
+				history.Transitions = append(history.Transitions, StateTransition{From: history.State, To: state, At: now})
+				if len(history.Transitions) > maxTransitions {
+					history.Transitions = history.Transitions[len(history.Transitions)-maxTransitions:]
+				}
+				history.State = state
+				history.StateSince = now
+			}
+			if history.Owner != owner {
+				history.Owner = owner
+				history.OwnerSince = now
+			}
+			history.Name = item.GetName()
+			history.LastSeen = now
+			if err := putHistory(bucket, key, history); err != nil {
+				return err
+			}
+		}
+		return s.prune(bucket, now)
+	})
+}
+
+// RecordReport appends the results of the action to the history of the items
+func (s *Store) RecordReport(report *types.ActionReport) error {
+	now := s.now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(itemsBucket)
+		for _, result := range report.Results {
+			key := []byte(result.CloudType.String() + "/" + result.ItemType + "/" + result.ID)
+			history, err := getHistory(bucket, key)
+			if err != nil {
+				return err
+			}
+			if history == nil {
+				history = &ItemHistory{CloudType: result.CloudType, ItemType: result.ItemType, ID: result.ID, Name: result.Name,
+					FirstSeen: now, LastSeen: now, StateSince: now, OwnerSince: now}
+			}
+			history.Actions = append(history.Actions, ActionRecord{Action: report.Action, Outcome: result.Outcome, Error: result.Error, At: now})
+			if len(history.Actions) > maxActions {
+				history.Actions = history.Actions[len(history.Actions)-maxActions:]
+			}
+			if err := putHistory(bucket, key, history); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetHistory returns the history of the item, or nil if it was never seen
+func (s *Store) GetHistory(item types.CloudItem) (history *ItemHistory, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		history, err = getHistory(tx.Bucket(itemsBucket), []byte(types.GetItemKey(item)))
+		return err
+	})
+	return
+}
+
+func (s *Store) prune(bucket *bolt.Bucket, now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	var expired [][]byte
+	err := bucket.ForEach(func(key, value []byte) error {
+		var history ItemHistory
+		if err := json.Unmarshal(value, &history); err != nil {
+			return err
+		}
+		if now.Sub(history.LastSeen) > s.retention {
+			expired = append(expired, append([]byte{}, key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getHistory(bucket *bolt.Bucket, key []byte) (*ItemHistory, error) {
+	value := bucket.Get(key)
+	if value == nil {
+		return nil, nil
+	}
+	var history ItemHistory
+	if err := json.Unmarshal(value, &history); err != nil {
+		return nil, fmt.Errorf("invalid history of %s, err: %s", key, err)
+	}
+	return &history, nil
+}
+
+func putHistory(bucket *bolt.Bucket, key []byte, history *ItemHistory) error {
+	value, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, value)
+}
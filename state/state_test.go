@@ -0,0 +1,113 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type stateSuite struct {
+	suite.Suite
+	store *Store
+	now   time.Time
+}
+
+func (s *stateSuite) SetupTest() {
+	var err error
+	s.store, err = Open(filepath.Join(s.T().TempDir(), "state.db"), 30*24*time.Hour)
+	s.Require().Nil(err)
+	s.now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.store.now = func() time.Time { return s.now }
+}
+
+func (s *stateSuite) TearDownTest() {
+	s.store.Close()
+}
+
+func (s *stateSuite) getHistory(item types.CloudItem) *ItemHistory {
+	history, err := s.store.GetHistory(item)
+	s.Require().Nil(err)
+	return history
+}
+
+func (s *stateSuite) TestRecordItems() {
+	first := s.now
+	s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", Name: "instance", CloudType: types.AWS, State: types.Running}}))
+	s.now = s.now.Add(24 * time.Hour)
+	s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", Name: "instance", CloudType: types.AWS, State: types.Running, Owner: "alice"}}))
+	s.now = s.now.Add(24 * time.Hour)
+	s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", Name: "renamed", CloudType: types.AWS, State: types.Stopped, Owner: "alice"}}))
+
+	history := s.getHistory(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	s.Equal("renamed", history.Name)
+	s.Equal(first, history.FirstSeen.UTC())
+	s.Equal(s.now, history.LastSeen.UTC())
+	s.Equal(types.Stopped, history.State)
+	s.Equal(s.now, history.StateSince.UTC())
+	s.Equal("alice", history.Owner)
+	s.Equal(first.Add(24*time.Hour), history.OwnerSince.UTC())
+	s.Equal(1, len(history.Transitions))
+	s.Equal(types.Running, history.Transitions[0].From)
+	s.Equal(types.Stopped, history.Transitions[0].To)
+
+	s.Nil(s.getHistory(&types.Instance{ID: "i-1", CloudType: types.GCP}))
+	s.Nil(s.getHistory(&types.Disk{ID: "i-1", CloudType: types.AWS}))
+}
+
+func (s *stateSuite) TestRecordReport() {
+	disk := &types.Disk{ID: "d-1", Name: "disk", CloudType: types.AWS, State: types.Unused}
+	s.Nil(s.store.RecordItems([]types.CloudItem{disk}))
+	report := types.NewActionReport(types.TerminationAction)
+	report.Add(disk, types.OutcomeFailed, errors.New("in use"))
+
+	s.Nil(s.store.RecordReport(report))
+
+	history := s.getHistory(disk)
+	s.Equal([]ActionRecord{{Action: types.TerminationAction, Outcome: types.OutcomeFailed, Error: "in use", At: s.now}}, normalize(history.Actions))
+}
+
+func (s *stateSuite) TestPrune() {
+	s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", CloudType: types.AWS}}))
+	s.now = s.now.Add(31 * 24 * time.Hour)
+
+	s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-2", CloudType: types.AWS}}))
+
+	s.Nil(s.getHistory(&types.Instance{ID: "i-1", CloudType: types.AWS}))
+	s.NotNil(s.getHistory(&types.Instance{ID: "i-2", CloudType: types.AWS}))
+}
+
+func (s *stateSuite) TestTransitionsAreLimited() {
+	for i := 0; i < maxTransitions+10; i++ {
+		itemState := types.Running
+		if i%2 == 1 {
+			itemState = types.Stopped
+		}
+		s.Nil(s.store.RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", CloudType: types.AWS, State: itemState}}))
+	}
+
+	s.Equal(maxTransitions, len(s.getHistory(&types.Instance{ID: "i-1", CloudType: types.AWS}).Transitions))
+}
+
+func (s *stateSuite) TestNotConfigured() {
+	RecordItems([]types.CloudItem{&types.Instance{ID: "i-1", CloudType: types.AWS}})
+
+	history, err := GetHistory(&types.Instance{ID: "i-1", CloudType: types.AWS})
+	s.Nil(err)
+	s.Nil(history)
+	s.False(IsEnabled())
+}
+
+func normalize(actions []ActionRecord) []ActionRecord {
+	for i := range actions {
+		actions[i].At = actions[i].At.UTC()
+	}
+	return actions
+}
+
+func TestStateSuite(t *testing.T) {
+	suite.Run(t, new(stateSuite))
+}
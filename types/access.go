@@ -44,6 +44,16 @@ func (a Access) GetType() string {
 	return "access"
 }
 
+// GetID returns the identifier of the access cloud object, which is its name
+func (a Access) GetID() string {
+	return a.Name
+}
+
+// GetRegion returns an empty string as the access cloud object is global
+func (a Access) GetRegion() string {
+	return ""
+}
+
 func (a Access) GetTags() Tags {
 	return a.Tags
 }
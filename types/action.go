@@ -25,9 +25,29 @@ const (
 
 	// CleanupAction cleans up the cloud item  if the item supports such operation
 	CleanupAction = ActionType("cleanup")
+
+	// MarkAction tags the cloud item with the time of the marking, and removes the mark from the items that do not match anymore
+	MarkAction = ActionType("mark")
+
+	// UnmarkAction removes the tags of the mark action from the cloud item
+	UnmarkAction = ActionType("unmark")
+
+	// DiffAction compares the cloud items with the snapshot of the previous execution and sends only the difference
+	DiffAction = ActionType("diff")
+
+	// MetricsAction updates the Prometheus metrics of the number of cloud items
+	MetricsAction = ActionType("metrics")
 )
 
-// Action to execute on the cloud items
+// Action to execute on the cloud items. Actions that change the cloud items return the result per item,
+// the others return nil.
 type Action interface {
-	Execute(OpType, []FilterType, []CloudItem)
+	Execute(OpType, []FilterType, []CloudItem) *ActionReport
+}
+
+// ScopedAction is an action that depends on the clouds the operation was executed on, e.g. because it executes the operation again.
+// Jobs pass their clouds to it, otherwise the clouds of the cloud items are used.
+type ScopedAction interface {
+	Action
+	ExecuteOnClouds([]CloudType, OpType, []FilterType, []CloudItem) *ActionReport
 }
@@ -65,6 +65,16 @@ func (a Alert) GetType() string {
 	return "alert"
 }
 
+// GetID returns the identifier of the alert
+func (a Alert) GetID() string {
+	return a.ID
+}
+
+// GetRegion returns the region of the alert
+func (a Alert) GetRegion() string {
+	return a.Region
+}
+
 func (a Alert) GetTags() Tags {
 	return a.Tags
 }
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // CloudType type of the cloud
 type CloudType string
 
@@ -43,4 +45,7 @@ type CloudProvider interface {
 	GetAlerts() ([]*Alert, error)
 	GetStorages() ([]*Storage, error)
 	CleanupStorages(storageContainer *StorageContainer, retentionDays int) []error
+	AddTags(items []CloudItem, tags Tags) []error
+	RemoveTags(items []CloudItem, keys []string) []error
+	GetUtilizations(items []CloudItem, period time.Duration) (map[string]Utilization, []error)
 }
@@ -0,0 +1,65 @@
+package types
+
+import "fmt"
+
+// HoursPerMonth is the average number of hours in a month used for the monthly estimations
+const HoursPerMonth = 730
+
+// Cost is the estimated cost of a cloud item based on the price catalog
+type Cost struct {
+	Hourly   float64 `json:"Hourly"`
+	Accrued  float64 `json:"Accrued"`
+	Currency string  `json:"Currency"`
+}
+
+func (c Cost) String() string {
+	return fmt.Sprintf("%.2f %s/h, accrued: %.2f %s", c.Hourly, c.Currency, c.Accrued, c.Currency)
+}
+
+// GetMonthly returns the estimated monthly cost
+func (c Cost) GetMonthly() float64 {
+	return c.Hourly * HoursPerMonth
+}
+
+// Add returns the sum of the costs
+func (c Cost) Add(other Cost) Cost {
+	currency := c.Currency
+	if len(currency) == 0 {
+		currency = other.Currency
+	}
+	return Cost{Hourly: c.Hourly + other.Hourly, Accrued: c.Accrued + other.Accrued, Currency: currency}
+}
+
+// GetCost returns the estimated cost of the cloud item, or nil if it's not known
+func GetCost(item CloudItem) *Cost {
+	switch t := item.GetItem().(type) {
+	case Instance:
+		return t.Cost
+	case Database:
+		return t.Cost
+	case Disk:
+		return t.Cost
+	default:
+		return nil
+	}
+}
+
+// PriceCatalog contains the prices of the cloud resources per cloud and region. The prices of the "default" region are used
+// if a region is not listed.
+type PriceCatalog struct {
+	Version  string                     `yaml:"version"`
+	Currency string                     `yaml:"currency"`
+	Clouds   map[CloudType]*CloudPrices `yaml:"clouds"`
+}
+
+// CloudPrices contains the prices of a single cloud by region
+type CloudPrices struct {
+	// Instances hourly price per instance type
+	Instances map[string]map[string]float64 `yaml:"instances"`
+
+	// Databases hourly price per database instance type
+	Databases map[string]map[string]float64 `yaml:"databases"`
+
+	// Disks monthly price of a GB per disk type
+	Disks map[string]map[string]float64 `yaml:"disks"`
+}
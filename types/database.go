@@ -43,6 +43,7 @@ type Database struct {
 	CloudType    CloudType         `json:"CloudType"`
 	Region       string            `json:"Region"`
 	Metadata     map[string]string `json:"Metadata"`
+	Cost         *Cost             `json:"Cost,omitempty"`
 }
 
 // GetName returns the name of the database
@@ -75,6 +76,16 @@ func (d Database) GetType() string {
 	return "database"
 }
 
+// GetID returns the identifier of the database
+func (d Database) GetID() string {
+	return d.ID
+}
+
+// GetRegion returns the region of the database
+func (d Database) GetRegion() string {
+	return d.Region
+}
+
 func (d Database) GetTags() Tags {
 	return d.Tags
 }
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Diff is the difference between the items of a previous snapshot and the current items of the same operation and filters
+type Diff struct {
+	Added   []CloudItem
+	Removed []CloudItem
+	Changed []ItemChange
+}
+
+// ItemChange is an item that is present in both the snapshot and the current items, but some of its fields are different
+type ItemChange struct {
+	Item    CloudItem
+	Changes []FieldChange
+}
+
+// FieldChange is the previous and the current value of a field of an item
+type FieldChange struct {
+	Field    string `json:"Field"`
+	Previous string `json:"Previous"`
+	Current  string `json:"Current"`
+}
+
+// DiffDispatcher is a dispatcher that can send only the difference since the previous execution instead of every item
+type DiffDispatcher interface {
+	Dispatcher
+	SendDiff(op OpType, filters []FilterType, diff *Diff) error
+}
+
+// NewDiff compares the items by cloud, type and ID. The items are changed if their state or owner is different.
+// The items of the diff are ordered by cloud, type and ID, so the result does not depend on the order of the operation.
+func NewDiff(previous, current []CloudItem) *Diff {
+	previousByKey := map[string]CloudItem{}
+	for _, item := range previous {
+		previousByKey[GetItemKey(item)] = item
+	}
+	diff := &Diff{}
+	for _, item := range current {
+		key := GetItemKey(item)
+		prev, ok := previousByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		delete(previousByKey, key)
+		var changes []FieldChange
+		if previousState, currentState := GetState(prev), GetState(item); previousState != currentState {
+			changes = append(changes, FieldChange{Field: "State", Previous: string(previousState), Current: string(currentState)})
+		}
+		if prev.GetOwner() != item.GetOwner() {
+			changes = append(changes, FieldChange{Field: "Owner", Previous: prev.GetOwner(), Current: item.GetOwner()})
+		}
+		if len(changes) != 0 {
+			diff.Changed = append(diff.Changed, ItemChange{Item: item, Changes: changes})
+		}
+	}
+	for _, item := range previous {
+		if _, ok := previousByKey[GetItemKey(item)]; ok {
+			diff.Removed = append(diff.Removed, item)
+		}
+	}
+	sortByDiffKey(diff.Added)
+	sortByDiffKey(diff.Removed)
+	sort.SliceStable(diff.Changed, func(i, j int) bool {
+		return GetItemKey(diff.Changed[i].Item) < GetItemKey(diff.Changed[j].Item)
+	})
+	return diff
+}
+
+func sortByDiffKey(items []CloudItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return GetItemKey(items[i]) < GetItemKey(items[j])
+	})
+}
+
+// IsEmpty returns true if nothing has changed since the snapshot
+func (d *Diff) IsEmpty() bool {
+	return d == nil || (len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0)
+}
+
+// MarshalJSON marshals the items in the format of MarshalItems, so they can be read back by the readItems operation
+func (d Diff) MarshalJSON() ([]byte, error) {
+	type itemChange struct {
+		Item    json.RawMessage `json:"Item"`
+		Changes []FieldChange   `json:"Changes"`
+	}
+	type diff struct {
+		Added   json.RawMessage `json:"Added"`
+		Removed json.RawMessage `json:"Removed"`
+		Changed []itemChange    `json:"Changed"`
+	}
+	var out diff
+	var err error
+	if out.Added, err = MarshalItems(d.Added); err != nil {
+		return nil, err
+	}
+	if out.Removed, err = MarshalItems(d.Removed); err != nil {
+		return nil, err
+	}
+	out.Changed = []itemChange{}
+	for _, change := range d.Changed {
+		item, err := MarshalItems([]CloudItem{change.Item})
+		if err != nil {
+			return nil, err
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(item, &items); err != nil {
+			return nil, err
+		}
+		out.Changed = append(out.Changed, itemChange{Item: items[0], Changes: change.Changes})
+	}
+	return json.Marshal(out)
+}
@@ -33,6 +33,7 @@ type Disk struct {
 	Type      string            `json:"Type"`
 	Metadata  map[string]string `json:"Metadata"`
 	Tags      Tags              `json:"Tags"`
+	Cost      *Cost             `json:"Cost,omitempty"`
 }
 
 // GetName returns the name of the disk
@@ -65,6 +66,16 @@ func (d Disk) GetType() string {
 	return "disk"
 }
 
+// GetID returns the identifier of the disk
+func (d Disk) GetID() string {
+	return d.ID
+}
+
+// GetRegion returns the region of the disk
+func (d Disk) GetRegion() string {
+	return d.Region
+}
+
 func (d Disk) GetTags() Tags {
 	return d.Tags
 }
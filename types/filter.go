@@ -13,6 +13,9 @@ const (
 	// StoppedFilter filters the cloud items that's state is stopped
 	StoppedFilter = FilterType("stopped")
 
+	// LongStoppedFilter filters the instances that are stopped for longer than a certain time
+	LongStoppedFilter = FilterType("longstopped")
+
 	// RunningFilter filters the cloud items that's state is running
 	RunningFilter = FilterType("running")
 
@@ -28,6 +31,21 @@ const (
 	// NoMatchFilter filters the items that do not match the include criteria of the filter config
 	NoMatchFilter = FilterType("nomatch")
 
+	// MarkedFilter filters the cloud items that were marked by the mark action longer than the grace period
+	MarkedFilter = FilterType("marked")
+
+	// ExpiredFilter filters the cloud items that are expired according to their expiry tag or the default running period
+	ExpiredFilter = FilterType("expired")
+
+	// IdleFilter filters the running instances and databases that's CPU and network utilization is below a threshold
+	IdleFilter = FilterType("idle")
+
+	// CostlyFilter filters the cloud items that's estimated cost is above a threshold
+	CostlyFilter = FilterType("costly")
+
+	// UnchangedFilter filters the cloud items that's state has not changed for a certain time according to the state store
+	UnchangedFilter = FilterType("unchanged")
+
 	// InclusiveFilter filter type that will return only the matching entries from the filter's inclusive configuration
 	InclusiveFilter = FilterConfigType("inclusive")
 
@@ -51,6 +69,25 @@ type Filter interface {
 	Execute([]CloudItem) []CloudItem
 }
 
+// ParameterizedFilter is a filter which default settings can be overridden, e.g. by a job of a run plan
+type ParameterizedFilter interface {
+	Filter
+	WithParameters(map[string]string) (Filter, error)
+}
+
+// ValidatingFilter is a filter that can find the cloud items it cannot evaluate, e.g. because of a malformed tag value.
+// The invalid items are not passed by the filter, but they are added to the report of the job.
+type ValidatingFilter interface {
+	Filter
+	Validate([]CloudItem) []InvalidItem
+}
+
+// InvalidItem is a cloud item that a filter cannot evaluate
+type InvalidItem struct {
+	Item CloudItem
+	Err  error
+}
+
 func (f FilterType) String() string {
 	return string(f)
 }
@@ -0,0 +1,260 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConditionOperator is the comparison of a filter condition
+type ConditionOperator string
+
+const (
+	// Equals the attribute is equal to the value
+	Equals = ConditionOperator("equals")
+
+	// Prefix the attribute starts with the value
+	Prefix = ConditionOperator("prefix")
+
+	// Regex the attribute matches the regular expression
+	Regex = ConditionOperator("regex")
+
+	// Glob the attribute matches the shell pattern, e.g. m5.*
+	Glob = ConditionOperator("glob")
+
+	// In the attribute is equal to any of the values
+	In = ConditionOperator("in")
+
+	// GreaterThan the attribute is greater than the value, numbers and durations can be compared
+	GreaterThan = ConditionOperator("greaterThan")
+
+	// LessThan the attribute is less than the value, numbers and durations can be compared
+	LessThan = ConditionOperator("lessThan")
+)
+
+// comparableKind is the kind of the values an attribute can be compared by
+type comparableKind string
+
+const (
+	numberKind   = comparableKind("number")
+	durationKind = comparableKind("duration")
+)
+
+const (
+	tagAttributePrefix      = "tag:"
+	metadataAttributePrefix = "metadata:"
+)
+
+// FilterCondition is a predicate on an attribute of the items. The attributes are id, name, owner, region, state, instanceType, size,
+// age (the time since the item was created, e.g. 720h), tag:<key> and metadata:<key>. The items without the attribute never match.
+type FilterCondition struct {
+	Attribute string            `yaml:"attribute"`
+	Operator  ConditionOperator `yaml:"operator"`
+	Value     string            `yaml:"value"`
+	Values    []string          `yaml:"values"`
+
+	// regex is the compiled pattern of the regex operator, it is set by Validate
+	regex *regexp.Regexp
+}
+
+// ConditionalFilterConfig is a filter config that can match the items by the conditions on their attributes
+type ConditionalFilterConfig interface {
+	// GetConditions returns the condition sets of the entity type and cloud, an item matches a set if every condition of the set matches
+	GetConditions(fType FilterEntityType, cloud CloudType) [][]FilterCondition
+}
+
+// Validate checks the attribute, the operator and the value of the condition, and compiles the regular expression of the regex operator
+func (c *FilterCondition) Validate() error {
+	if !isKnownAttribute(c.Attribute) {
+		return fmt.Errorf("unknown attribute: %s", c.Attribute)
+	}
+	switch c.Operator {
+	case Equals, Prefix, Glob:
+		if c.Operator == Glob {
+			if _, err := path.Match(c.Value, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern of attribute %s: %s, err: %s", c.Attribute, c.Value, err)
+			}
+		}
+	case Regex:
+		regex, err := regexp.Compile(c.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression of attribute %s: %s, err: %s", c.Attribute, c.Value, err)
+		}
+		c.regex = regex
+	case In:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("the values of attribute %s are missing", c.Attribute)
+		}
+		return nil
+	case GreaterThan, LessThan:
+		if _, err := c.getComparableKind(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operator of attribute %s: %s", c.Attribute, c.Operator)
+	}
+	if len(c.Values) != 0 {
+		return fmt.Errorf("operator %s of attribute %s accepts a single value", c.Operator, c.Attribute)
+	}
+	return nil
+}
+
+// Matches returns true if the item has the attribute and it satisfies the condition
+func (c FilterCondition) Matches(item CloudItem) bool {
+	value, ok := GetAttribute(item, c.Attribute)
+	if !ok {
+		return false
+	}
+	switch c.Operator {
+	case Equals:
+		return value == c.Value
+	case Prefix:
+		return strings.HasPrefix(value, c.Value)
+	case Regex:
+		regex := c.regex
+		if regex == nil {
+			var err error
+			if regex, err = regexp.Compile(c.Value); err != nil {
+				log.Warnf("Invalid regular expression of attribute %s: %s, err: %s", c.Attribute, c.Value, err)
+				return false
+			}
+		}
+		return regex.MatchString(value)
+	case Glob:
+		matched, err := path.Match(c.Value, value)
+		return err == nil && matched
+	case In:
+		for _, v := range c.Values {
+			if value == v {
+				return true
+			}
+		}
+	case GreaterThan, LessThan:
+		kind, err := c.getComparableKind()
+		if err != nil {
+			return false
+		}
+		actual, ok := parseComparable(value, kind)
+		if !ok {
+			return false
+		}
+		expected, _ := parseComparable(c.Value, kind)
+		if c.Operator == GreaterThan {
+			return actual > expected
+		}
+		return actual < expected
+	}
+	return false
+}
+
+// GetAttribute returns the value of the attribute of the item, false is returned if the item does not have it
+func GetAttribute(item CloudItem, attribute string) (string, bool) {
+	if strings.HasPrefix(attribute, tagAttributePrefix) {
+		value, ok := item.GetTags()[strings.TrimPrefix(attribute, tagAttributePrefix)]
+		return value, ok
+	}
+	if strings.HasPrefix(attribute, metadataAttributePrefix) {
+		value, ok := getMetadata(item)[strings.TrimPrefix(attribute, metadataAttributePrefix)]
+		return value, ok
+	}
+	switch attribute {
+	case "id":
+		return item.GetID(), true
+	case "name":
+		return item.GetName(), true
+	case "owner":
+		return item.GetOwner(), true
+	case "region":
+		return item.GetRegion(), true
+	case "state":
+		state := GetState(item)
+		return string(state), len(state) != 0
+	case "instanceType":
+		switch t := item.GetItem().(type) {
+		case Instance:
+			return t.InstanceType, true
+		case Database:
+			return t.InstanceType, true
+		}
+	case "size":
+		if disk, ok := item.GetItem().(Disk); ok {
+			return strconv.FormatInt(disk.Size, 10), true
+		}
+	case "age":
+		if created := item.GetCreated(); !created.IsZero() {
+			return time.Since(created).String(), true
+		}
+	}
+	return "", false
+}
+
+func isKnownAttribute(attribute string) bool {
+	switch attribute {
+	case "id", "name", "owner", "region", "state", "instanceType", "size", "age":
+		return true
+	}
+	return (strings.HasPrefix(attribute, tagAttributePrefix) && len(attribute) > len(tagAttributePrefix)) ||
+		(strings.HasPrefix(attribute, metadataAttributePrefix) && len(attribute) > len(metadataAttributePrefix))
+}
+
+func getMetadata(item CloudItem) map[string]string {
+	switch t := item.GetItem().(type) {
+	case Instance:
+		return t.Metadata
+	case Database:
+		return t.Metadata
+	case Disk:
+		return t.Metadata
+	case Stack:
+		return t.Metadata
+	case Alert:
+		return t.Metadata
+	}
+	return nil
+}
+
+// The age is compared as a duration and the size as a number. The tags and the metadata are compared as a number or a duration,
+// depending on the value of the condition, the other attributes cannot be compared.
+func (c FilterCondition) getComparableKind() (comparableKind, error) {
+	var kind comparableKind
+	switch {
+	case c.Attribute == "age":
+		kind = durationKind
+	case c.Attribute == "size":
+		kind = numberKind
+	case strings.HasPrefix(c.Attribute, tagAttributePrefix), strings.HasPrefix(c.Attribute, metadataAttributePrefix):
+		if _, ok := parseComparable(c.Value, numberKind); ok {
+			return numberKind, nil
+		}
+		if _, ok := parseComparable(c.Value, durationKind); ok {
+			return durationKind, nil
+		}
+		return "", fmt.Errorf("value of attribute %s is not a number or a duration: %s", c.Attribute, c.Value)
+	default:
+		return "", fmt.Errorf("attribute %s cannot be compared with operator %s", c.Attribute, c.Operator)
+	}
+	if _, ok := parseComparable(c.Value, kind); !ok {
+		return "", fmt.Errorf("value of attribute %s is not a %s: %s", c.Attribute, kind, c.Value)
+	}
+	return kind, nil
+}
+
+// The numbers are compared as they are, the durations in nanoseconds
+func parseComparable(value string, kind comparableKind) (float64, bool) {
+	switch kind {
+	case numberKind:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number, true
+		}
+	case durationKind:
+		if duration, err := time.ParseDuration(value); err == nil {
+			return float64(duration), true
+		}
+	}
+	return 0, false
+}
@@ -17,6 +17,21 @@ const (
 	IncludeAccess   = FilterEntityType("includeAccess")
 	ExcludeInstance = FilterEntityType("excludeInstance")
 	IncludeInstance = FilterEntityType("includeInstance")
+
+	// the entity types below are supported by the V2 config only, the items of these types are matched by the instance rules too,
+	// except for the images
+	ExcludeDisk     = FilterEntityType("excludeDisk")
+	IncludeDisk     = FilterEntityType("includeDisk")
+	ExcludeImage    = FilterEntityType("excludeImage")
+	IncludeImage    = FilterEntityType("includeImage")
+	ExcludeStack    = FilterEntityType("excludeStack")
+	IncludeStack    = FilterEntityType("includeStack")
+	ExcludeDatabase = FilterEntityType("excludeDatabase")
+	IncludeDatabase = FilterEntityType("includeDatabase")
+	ExcludeAlert    = FilterEntityType("excludeAlert")
+	IncludeAlert    = FilterEntityType("includeAlert")
+	ExcludeStorage  = FilterEntityType("excludeStorage")
+	IncludeStorage  = FilterEntityType("includeStorage")
 )
 
 type FilterConfigProperty string
@@ -1,8 +1,10 @@
 package types
 
 import (
-	log "github.com/sirupsen/logrus"
+	"fmt"
 	"strings"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // FilterConfigV2 structure that stores the information provided by the exclude/include flag
@@ -10,11 +12,26 @@ type FilterConfigV2 struct {
 	Filters []FilterConfigV2Filter `yaml:"filters"`
 }
 
+// FilterConfigV2Filter matches the items of the entity types and clouds if any of the properties starts with any of the values,
+// or if every condition matches
 type FilterConfigV2Filter struct {
 	Types      []FilterEntityType     `yaml:"filterTypes"`
 	CloudTypes []CloudType            `yaml:"cloudTypes"`
 	Properties []FilterConfigProperty `yaml:"filterProperties"`
 	Values     []string               `yaml:"filterValues"`
+	Conditions []FilterCondition      `yaml:"conditions"`
+}
+
+// Validate checks the conditions of the filters, the regular expressions of the conditions are compiled once here
+func (filterConfig FilterConfigV2) Validate() error {
+	for i, filter := range filterConfig.Filters {
+		for j := range filter.Conditions {
+			if err := filter.Conditions[j].Validate(); err != nil {
+				return fmt.Errorf("filter %d: %s", i+1, err)
+			}
+		}
+	}
+	return nil
 }
 
 func (filterConfig FilterConfigV2) GetFilterValues(fType FilterEntityType, cloud CloudType, property FilterConfigProperty) []string {
@@ -38,3 +55,33 @@ func (filterConfig FilterConfigV2) GetFilterValues(fType FilterEntityType, cloud
 	}
 	return nil
 }
+
+// GetConditions returns the conditions of every filter of the entity type and cloud
+func (filterConfig FilterConfigV2) GetConditions(fType FilterEntityType, cloud CloudType) [][]FilterCondition {
+	cloudProperty := strings.ToLower(string(cloud))
+	var conditions [][]FilterCondition
+	for _, filter := range filterConfig.Filters {
+		if len(filter.Conditions) != 0 && containsEntityType(filter.Types, fType) && containsCloudType(filter.CloudTypes, cloudProperty) {
+			conditions = append(conditions, filter.Conditions)
+		}
+	}
+	return conditions
+}
+
+func containsEntityType(fTypes []FilterEntityType, fType FilterEntityType) bool {
+	for _, t := range fTypes {
+		if t == fType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCloudType(clouds []CloudType, cloud string) bool {
+	for _, c := range clouds {
+		if string(c) == cloud {
+			return true
+		}
+	}
+	return false
+}
@@ -60,6 +60,16 @@ func (img Image) GetType() string {
 	return "image"
 }
 
+// GetID returns the identifier of the image
+func (img Image) GetID() string {
+	return img.ID
+}
+
+// GetRegion returns the region of the image
+func (img Image) GetRegion() string {
+	return img.Region
+}
+
 func (img Image) GetTags() Tags {
 	return img.Tags
 }
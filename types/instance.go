@@ -44,6 +44,10 @@ type Instance struct {
 	Metadata     map[string]string `json:"Metadata"`
 	Region       string            `json:"Region"`
 	Ephemeral    bool              `json:"Ephemeral"`
+	Cost         *Cost             `json:"Cost,omitempty"`
+
+	// StoppedAt is the time the instance entered the stopped state according to the provider, nil if it's not stopped or unknown
+	StoppedAt *time.Time `json:"StoppedAt,omitempty"`
 }
 
 // Tags Key-value pairs of the tags on the instances
@@ -82,6 +86,16 @@ func (i Instance) GetType() string {
 	return "instance"
 }
 
+// GetID returns the identifier of the instance
+func (i Instance) GetID() string {
+	return i.ID
+}
+
+// GetRegion returns the region of the instance
+func (i Instance) GetRegion() string {
+	return i.Region
+}
+
 func (i Instance) GetTags() Tags {
 	return i.Tags
 }
@@ -19,6 +19,9 @@ const (
 	// ReadImages operation to return all the images from sdin
 	ReadImages = OpType("readImages")
 
+	// ReadItems operation to return the cloud items printed by the json action from stdin or a file
+	ReadItems = OpType("readItems")
+
 	// Stacks operation to return all stack (CF, ARM..)
 	Stacks = OpType("getStacks")
 
@@ -0,0 +1,44 @@
+package types
+
+// Plan contains the jobs to execute in a single run, sharing the initialized cloud providers
+type Plan struct {
+	Jobs []Job `yaml:"jobs"`
+}
+
+// Job is an operation which result is filtered by the filters in the given order and passed to the action.
+// If the filter expression is set the filters are combined by it, e.g. "(longrunning & ownerless) | stopped", and the filters
+// only provide the parameters of the filters referred by the expression.
+type Job struct {
+	Name             string      `yaml:"name"`
+	Operation        OpType      `yaml:"operation"`
+	Filters          []JobFilter `yaml:"filters"`
+	FilterExpression string      `yaml:"filterExpression"`
+	Action           ActionType  `yaml:"action"`
+	Clouds           []CloudType `yaml:"clouds"`
+}
+
+// JobFilter is a filter of a job with its optional parameters
+type JobFilter struct {
+	Type       FilterType        `yaml:"type"`
+	Parameters map[string]string `yaml:"parameters"`
+}
+
+// GetFilterTypes returns the types of the filters of the job in the order of execution
+func (j Job) GetFilterTypes() []FilterType {
+	filterTypes := make([]FilterType, 0, len(j.Filters))
+	for _, f := range j.Filters {
+		filterTypes = append(filterTypes, f.Type)
+	}
+	return filterTypes
+}
+
+// Schedule contains the jobs of the daemon mode with the cron expressions they are executed by
+type Schedule struct {
+	Jobs []ScheduledJob `yaml:"jobs"`
+}
+
+// ScheduledJob is a job which is executed periodically, e.g. "0 * * * *" or "@every 6h"
+type ScheduledJob struct {
+	Job  `yaml:",inline"`
+	Cron string `yaml:"cron"`
+}
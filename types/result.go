@@ -0,0 +1,156 @@
+package types
+
+import (
+	"fmt"
+)
+
+const (
+	// OutcomeSucceeded the action was successfully executed on the cloud item
+	OutcomeSucceeded = ActionOutcome("succeeded")
+
+	// OutcomeFailed the action failed on the cloud item
+	OutcomeFailed = ActionOutcome("failed")
+
+	// OutcomeDryRun the action was skipped on the cloud item because of the dry run
+	OutcomeDryRun = ActionOutcome("dry-run")
+
+	// OutcomeInvalid the action was not executed on the cloud item, because a filter could not evaluate it
+	OutcomeInvalid = ActionOutcome("invalid")
+)
+
+// ActionOutcome is the outcome of an action on a single cloud item
+type ActionOutcome string
+
+// ResourceError is an error of a cloud provider operation on a single resource.
+// If the ID is empty the error applies to every resource of the region.
+type ResourceError struct {
+	ID     string
+	Region string
+	Err    error
+}
+
+// NewResourceError wraps the error of the resource with the given ID
+func NewResourceError(ID, region string, err error) ResourceError {
+	return ResourceError{ID: ID, Region: region, Err: err}
+}
+
+func (e ResourceError) Error() string {
+	if len(e.ID) == 0 {
+		return fmt.Sprintf("region %s: %s", e.Region, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.ID, e.Err)
+}
+
+// Unwrap returns the original error
+func (e ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// Affects returns true if the error belongs to the given cloud item
+func (e ResourceError) Affects(item CloudItem) bool {
+	if len(e.ID) == 0 {
+		return len(e.Region) != 0 && e.Region == item.GetRegion()
+	}
+	return e.ID == item.GetID() && (len(e.Region) == 0 || e.Region == item.GetRegion())
+}
+
+// ActionResult is the result of an action on a single cloud item
+type ActionResult struct {
+	ID        string        `json:"Id"`
+	Name      string        `json:"Name"`
+	CloudType CloudType     `json:"CloudType"`
+	Region    string        `json:"Region"`
+	ItemType  string        `json:"ItemType"`
+	Action    ActionType    `json:"Action"`
+	Outcome   ActionOutcome `json:"Outcome"`
+	Error     string        `json:"Error,omitempty"`
+
+	// MonthlySavings is the estimated monthly cost of the cloud item that is saved by the action
+	MonthlySavings float64 `json:"MonthlySavings,omitempty"`
+}
+
+// ActionReport contains the results of an action execution per cloud item
+type ActionReport struct {
+	Action  ActionType     `json:"Action"`
+	Results []ActionResult `json:"Results"`
+}
+
+// NewActionReport creates an empty report of the action
+func NewActionReport(action ActionType) *ActionReport {
+	return &ActionReport{Action: action, Results: []ActionResult{}}
+}
+
+// Add records the outcome of the action on the cloud item, the error is optional
+func (r *ActionReport) Add(item CloudItem, outcome ActionOutcome, err error) {
+	result := ActionResult{
+		ID:        item.GetID(),
+		Name:      item.GetName(),
+		CloudType: item.GetCloudType(),
+		Region:    item.GetRegion(),
+		ItemType:  item.GetType(),
+		Action:    r.Action,
+		Outcome:   outcome,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.Results = append(r.Results, result)
+}
+
+// Merge appends the results of the other report
+func (r *ActionReport) Merge(other *ActionReport) {
+	if other != nil {
+		r.Results = append(r.Results, other.Results...)
+	}
+}
+
+// GetFailed returns the results of the cloud items the action failed on
+func (r *ActionReport) GetFailed() []ActionResult {
+	return r.getResults(OutcomeFailed)
+}
+
+// GetInvalid returns the results of the cloud items that could not be evaluated by a filter
+func (r *ActionReport) GetInvalid() []ActionResult {
+	return r.getResults(OutcomeInvalid)
+}
+
+func (r *ActionReport) getResults(outcome ActionOutcome) []ActionResult {
+	results := []ActionResult{}
+	for _, result := range r.Results {
+		if result.Outcome == outcome {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// Count returns the number of cloud items with the given outcome
+func (r *ActionReport) Count(outcome ActionOutcome) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// GetMonthlySavings returns the estimated monthly savings of the cloud items the action succeeded on.
+// On a dry run it's the savings that the action would have achieved.
+func (r *ActionReport) GetMonthlySavings() float64 {
+	savings := 0.0
+	if r == nil {
+		return savings
+	}
+	for _, result := range r.Results {
+		if result.Outcome == OutcomeSucceeded || result.Outcome == OutcomeDryRun {
+			savings += result.MonthlySavings
+		}
+	}
+	return savings
+}
+
+// HasFailure returns true if the action failed on any of the cloud items
+func (r *ActionReport) HasFailure() bool {
+	return r != nil && r.Count(OutcomeFailed) != 0
+}
@@ -65,6 +65,16 @@ func (s Stack) GetType() string {
 	return "stack"
 }
 
+// GetID returns the identifier of the stack
+func (s Stack) GetID() string {
+	return s.ID
+}
+
+// GetRegion returns the region of the stack
+func (s Stack) GetRegion() string {
+	return s.Region
+}
+
 func (s Stack) GetTags() Tags {
 	return s.Tags
 }
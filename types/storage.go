@@ -65,6 +65,16 @@ func (s Storage) GetType() string {
 	return "storage"
 }
 
+// GetID returns the identifier of the storage
+func (s Storage) GetID() string {
+	return s.ID
+}
+
+// GetRegion returns the region of the storage
+func (s Storage) GetRegion() string {
+	return s.Region
+}
+
 func (s Storage) GetTags() Tags {
 	return s.Tags
 }
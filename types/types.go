@@ -1,9 +1,26 @@
 package types
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// ItemTypeField is the field of the marshalled cloud items that contains the type of the item
+const ItemTypeField = "ItemType"
+
+var itemTypes = map[string]func() CloudItem{
+	Instance{}.GetType(): func() CloudItem { return &Instance{} },
+	Stack{}.GetType():    func() CloudItem { return &Stack{} },
+	Disk{}.GetType():     func() CloudItem { return &Disk{} },
+	Image{}.GetType():    func() CloudItem { return &Image{} },
+	Database{}.GetType(): func() CloudItem { return &Database{} },
+	Alert{}.GetType():    func() CloudItem { return &Alert{} },
+	Storage{}.GetType():  func() CloudItem { return &Storage{} },
+	Access{}.GetType():   func() CloudItem { return &Access{} },
+}
+
 const (
 	// Running state of the cloud item
 	Running = State("running")
@@ -42,17 +59,109 @@ type I64 struct {
 
 // CloudItem is a general cloud item that is returned by the operation and processed by the filters and actions
 type CloudItem interface {
+	GetID() string
 	GetName() string
 	GetOwner() string
 	GetCloudType() CloudType
+	GetRegion() string
 	GetCreated() time.Time
 	GetItem() interface{}
 	GetType() string
 	GetTags() Tags
 }
 
+// GetState returns the state of the cloud item, or an empty state if the item does not have one
+func GetState(item CloudItem) State {
+	switch t := item.GetItem().(type) {
+	case Instance:
+		return t.State
+	case Stack:
+		return t.State
+	case Database:
+		return t.State
+	case Disk:
+		return t.State
+	case Alert:
+		return t.State
+	default:
+		return ""
+	}
+}
+
+// GetItemKey identifies the cloud item by its cloud, type and ID
+func GetItemKey(item CloudItem) string {
+	return item.GetCloudType().String() + "/" + item.GetType() + "/" + item.GetID()
+}
+
+// MarshalItems marshals the cloud items into an indented JSON array, each item starts with its type in the ItemType field,
+// so UnmarshalItems can rebuild the same items
+func MarshalItems(items []CloudItem) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("[")
+	for i, item := range items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) < 2 || raw[0] != '{' {
+			return nil, fmt.Errorf("cloud item is not marshalled as an object: %s", item.GetName())
+		}
+		if i != 0 {
+			out.WriteString(",")
+		}
+		fmt.Fprintf(&out, `{"%s":%q`, ItemTypeField, item.GetType())
+		if len(raw) > 2 {
+			out.WriteString(",")
+		}
+		out.Write(raw[1:])
+	}
+	out.WriteString("]")
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// UnmarshalItems rebuilds the cloud items marshalled by MarshalItems, every item must have a known type and a cloud type
+func UnmarshalItems(raw []byte) ([]CloudItem, error) {
+	var rawItems []json.RawMessage
+	if err := json.Unmarshal(raw, &rawItems); err != nil {
+		return nil, err
+	}
+	items := make([]CloudItem, 0, len(rawItems))
+	for i, rawItem := range rawItems {
+		var header struct {
+			ItemType  string
+			CloudType CloudType
+		}
+		if err := json.Unmarshal(rawItem, &header); err != nil {
+			return nil, fmt.Errorf("invalid item at index %d, err: %s", i, err)
+		}
+		newItem, ok := itemTypes[header.ItemType]
+		if !ok {
+			return nil, fmt.Errorf("unknown %s of item at index %d: %q", ItemTypeField, i, header.ItemType)
+		}
+		if len(header.CloudType) == 0 {
+			return nil, fmt.Errorf("missing CloudType of item at index %d", i)
+		}
+		item := newItem()
+		if err := json.Unmarshal(rawItem, item); err != nil {
+			return nil, fmt.Errorf("invalid %s at index %d, err: %s", header.ItemType, i, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
 // Dispatcher interface used to send the messages with
 type Dispatcher interface {
 	GetName() string
 	Send(op OpType, filters []FilterType, items []CloudItem) error
 }
+
+// ReportDispatcher is a dispatcher that can also send the result report of an action
+type ReportDispatcher interface {
+	Dispatcher
+	SendReport(op OpType, filters []FilterType, report *ActionReport) error
+}
@@ -0,0 +1,10 @@
+package types
+
+// Utilization is the average resource usage of a cloud item over a period of time
+type Utilization struct {
+	// CPU is the average CPU utilization in percent
+	CPU float64 `json:"CPU"`
+
+	// Network is the average received and sent network traffic in bytes per second
+	Network float64 `json:"Network"`
+}
@@ -26,6 +26,38 @@ func IsAnyStartsWith(haystack map[string]string, needles ...string) bool {
 	return false
 }
 
+// IsAnyLabelMatch looks any tag match with given labels. A label is either a prefix of the tag keys, or key=value, key=prefix* or key!=value,
+// where key!=value matches the tags without the key too. The keys and values are compared case-insensitively if ignoreCase is set.
+func IsAnyLabelMatch(tags map[string]string, ignoreCase bool, labels ...string) bool {
+	normalize := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	normalized := make(map[string]string, len(tags))
+	for k, v := range tags {
+		normalized[normalize(k)] = normalize(v)
+	}
+	for _, label := range labels {
+		label = normalize(label)
+		if i := strings.Index(label, "!="); i > 0 {
+			if value, ok := normalized[label[:i]]; !ok || value != label[i+2:] {
+				return true
+			}
+		} else if i := strings.Index(label, "="); i > 0 {
+			value, ok := normalized[label[:i]]
+			expected := label[i+1:]
+			if ok && (value == expected || (strings.HasSuffix(expected, "*") && strings.HasPrefix(value, strings.TrimSuffix(expected, "*")))) {
+				return true
+			}
+		} else if IsAnyStartsWith(normalized, label) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsStartsWith looks input start with given needles
 func IsStartsWith(hay string, needles ...string) bool {
 	for _, n := range needles {
@@ -104,10 +136,54 @@ func LoadFilterConfigV2(location string) (*types.FilterConfigV2, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := configV2.Validate(); err != nil {
+		return nil, err
+	}
 	log.Debugf("[UTIL] Filter config V2 loaded:\n%s", raw)
 	return configV2, nil
 }
 
+// LoadPlan loads and unmarshalls run plan YAML
+func LoadPlan(location string) (*types.Plan, error) {
+	raw, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	plan := &types.Plan{}
+	err = yaml.UnmarshalStrict(raw, plan)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Jobs) == 0 {
+		return nil, fmt.Errorf("run plan %s does not contain any jobs", location)
+	}
+	log.Debugf("[UTIL] Run plan loaded:\n%s", raw)
+	return plan, nil
+}
+
+// LoadSchedule loads and unmarshalls the schedule YAML of the daemon mode
+func LoadSchedule(location string) (*types.Schedule, error) {
+	raw, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &types.Schedule{}
+	err = yaml.UnmarshalStrict(raw, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedule.Jobs) == 0 {
+		return nil, fmt.Errorf("schedule %s does not contain any jobs", location)
+	}
+	for i, job := range schedule.Jobs {
+		if len(job.Cron) == 0 {
+			return nil, fmt.Errorf("job %d of schedule %s does not have a cron expression", i+1, location)
+		}
+	}
+	log.Debugf("[UTIL] Schedule loaded:\n%s", raw)
+	return schedule, nil
+}
+
 // GetCloudAccountNames returns the name of the configured cloud accounts
 func GetCloudAccountNames() map[types.CloudType]string {
 	var accounts = make(map[types.CloudType]string)
@@ -117,6 +193,19 @@ func GetCloudAccountNames() map[types.CloudType]string {
 	return accounts
 }
 
+// GetCloudTypes returns the cloud types of the cloud items in the order of their first appearance
+func GetCloudTypes(items []types.CloudItem) []types.CloudType {
+	var clouds []types.CloudType
+	seen := map[types.CloudType]bool{}
+	for _, item := range items {
+		if !seen[item.GetCloudType()] {
+			seen[item.GetCloudType()] = true
+			clouds = append(clouds, item.GetCloudType())
+		}
+	}
+	return clouds
+}
+
 // SplitListToMap splits comma separated list to key:true map
 func SplitListToMap(list string) (resp map[string]bool) {
 	resp = map[string]bool{}
@@ -99,6 +99,16 @@ func TestLoadIgnoresV2(t *testing.T) {
 	assert.Equal(t, []string{"includeThisValue"}, filterConfig.GetFilterValues(types.IncludeInstance, types.GCP, types.Name))
 }
 
+func TestGetCloudTypes(t *testing.T) {
+	items := []types.CloudItem{
+		&types.Instance{CloudType: types.GCP},
+		&types.Disk{CloudType: types.AWS},
+		&types.Instance{CloudType: types.GCP},
+	}
+
+	assert.Equal(t, []types.CloudType{types.GCP, types.AWS}, GetCloudTypes(items))
+}
+
 func TestSplitListToMap(t *testing.T) {
 	assert.Equal(t, map[string]bool{"a": true, "b": true, "A": true, "B": true}, SplitListToMap("a, b"))
 }
@@ -106,3 +116,85 @@ func TestSplitListToMap(t *testing.T) {
 func TestSplitListToMapEmpty(t *testing.T) {
 	assert.Equal(t, map[string]bool{}, SplitListToMap(""))
 }
+
+func TestLoadPlan(t *testing.T) {
+	plan, err := LoadPlan("testdata/plan.yml")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(plan.Jobs))
+	assert.Equal(t, types.Instances, plan.Jobs[0].Operation)
+	assert.Equal(t, []types.FilterType{types.LongRunningFilter, types.OwnerlessFilter}, plan.Jobs[0].GetFilterTypes())
+	assert.Equal(t, map[string]string{"period": "6h"}, plan.Jobs[0].Filters[0].Parameters)
+	assert.Equal(t, types.StopAction, plan.Jobs[0].Action)
+	assert.Equal(t, []types.CloudType{"aws", "gcp"}, plan.Jobs[0].Clouds)
+	assert.Empty(t, plan.Jobs[1].Clouds)
+}
+
+func TestLoadSchedule(t *testing.T) {
+	schedule, err := LoadSchedule("testdata/schedule.yml")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(schedule.Jobs))
+	assert.Equal(t, "0 * * * *", schedule.Jobs[0].Cron)
+	assert.Equal(t, "stop-long-running-instances", schedule.Jobs[0].Name)
+	assert.Equal(t, types.Instances, schedule.Jobs[0].Operation)
+	assert.Equal(t, map[string]string{"period": "6h"}, schedule.Jobs[0].Filters[0].Parameters)
+	assert.Equal(t, "@every 24h", schedule.Jobs[1].Cron)
+	assert.Equal(t, types.TerminationAction, schedule.Jobs[1].Action)
+}
+
+func TestLoadConditionsV2(t *testing.T) {
+	filterConfig, err := LoadFilterConfigV2("testdata/filterConfigV2Conditions.yml")
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]types.FilterCondition{{
+		{Attribute: "instanceType", Operator: types.Equals, Value: "m5.24xlarge"},
+		{Attribute: "region", Operator: types.Equals, Value: "us-east-1"},
+		{Attribute: "tag:team", Operator: types.Equals, Value: "ml"},
+	}}, filterConfig.GetConditions(types.ExcludeInstance, types.AWS))
+	assert.Equal(t, 1, len(filterConfig.GetConditions(types.ExcludeDisk, types.GCP)))
+	assert.Nil(t, filterConfig.GetConditions(types.ExcludeInstance, types.GCP))
+	assert.Nil(t, filterConfig.GetConditions(types.IncludeInstance, types.AWS))
+}
+
+func TestLoadInvalidConditionV2(t *testing.T) {
+	_, err := LoadFilterConfigV2("testdata/filterConfigV2InvalidCondition.yml")
+
+	assert.EqualError(t, err, "filter 1: unknown operator of attribute instanceType: between")
+}
+
+func TestValidateFilterConfig(t *testing.T) {
+	for location, expected := range map[string]int{
+		"testdata/filterConfig.yml":             1,
+		"testdata/filterConfigV2.yml":           2,
+		"testdata/filterConfigV2Conditions.yml": 2,
+	} {
+		version, problems, err := ValidateFilterConfig(location)
+
+		assert.Nil(t, err)
+		assert.Empty(t, problems, location)
+		assert.Equal(t, expected, version, location)
+	}
+}
+
+func TestValidateInvalidFilterConfig(t *testing.T) {
+	version, problems, err := ValidateFilterConfig("testdata/filterConfigV2Invalid.yml")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, version)
+	var lines []int
+	for _, problem := range problems {
+		lines = append(lines, problem.Line)
+	}
+	assert.Equal(t, []int{4, 6, 10, 14, 21}, lines)
+	assert.Equal(t, "line 6: cloud type must be lowercase: AWS", problems[1].Error())
+}
+
+func TestValidateInvalidFilterConfigV1(t *testing.T) {
+	version, problems, err := ValidateFilterConfig("testdata/plan.yml")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, 1, len(problems))
+	assert.Equal(t, 2, problems[0].Line)
+}
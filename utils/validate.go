@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/hortonworks/cloud-haunter/types"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem of a configuration file at a line
+type ValidationError struct {
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+var (
+	filterConfigV1Properties = map[string][]string{
+		"excludeAccess":   {"names", "owners"},
+		"includeAccess":   {"names", "owners"},
+		"excludeInstance": {"labels", "names", "owners"},
+		"includeInstance": {"labels", "names", "owners"},
+	}
+	filterConfigClouds     = []string{"aws", "azure", "gcp"}
+	filterConfigV2Clouds   = []string{"aws", "azure", "dummy", "gcp"}
+	filterConfigV2Fields   = []string{"cloudTypes", "conditions", "filterProperties", "filterTypes", "filterValues"}
+	filterConditionFields  = []string{"attribute", "operator", "value", "values"}
+	filterConfigProperties = []string{string(types.Name), string(types.Owner), string(types.Label)}
+	filterEntityTypes      = []types.FilterEntityType{
+		types.ExcludeAccess, types.IncludeAccess, types.ExcludeInstance, types.IncludeInstance,
+		types.ExcludeDisk, types.IncludeDisk, types.ExcludeImage, types.IncludeImage,
+		types.ExcludeStack, types.IncludeStack, types.ExcludeDatabase, types.IncludeDatabase,
+		types.ExcludeAlert, types.IncludeAlert, types.ExcludeStorage, types.IncludeStorage,
+	}
+)
+
+// ValidateFilterConfig strictly checks the filter config file. The version is detected by the top level keys, a V2 config
+// has the filters key. An error is returned only if the file cannot be read or is not a valid YAML.
+func ValidateFilterConfig(location string) (version int, problems []ValidationError, err error) {
+	raw, err := ioutil.ReadFile(location)
+	if err != nil {
+		return 0, nil, err
+	}
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(raw, &document); err != nil {
+		return 0, nil, err
+	}
+	if len(document.Content) == 0 {
+		return 0, []ValidationError{{Line: 1, Message: "filter config is empty"}}, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return 0, []ValidationError{{Line: root.Line, Message: "filter config must be a mapping"}}, nil
+	}
+	v := &validator{}
+	if getMappingValue(root, "filters") != nil {
+		v.validateFilterConfigV2(root)
+		return 2, v.problems, nil
+	}
+	v.validateFilterConfigV1(root)
+	return 1, v.problems, nil
+}
+
+type validator struct {
+	problems []ValidationError
+}
+
+func (v *validator) addProblem(node *yamlv3.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, ValidationError{Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateFilterConfigV1(root *yamlv3.Node) {
+	v.forEachField(root, getSortedKeys(filterConfigV1Properties), func(entityType string, entity *yamlv3.Node) {
+		properties := filterConfigV1Properties[entityType]
+		v.forEachField(entity, filterConfigClouds, func(_ string, cloud *yamlv3.Node) {
+			v.forEachField(cloud, properties, func(property string, values *yamlv3.Node) {
+				v.getStrings(values, property)
+			})
+		})
+	})
+}
+
+func (v *validator) validateFilterConfigV2(root *yamlv3.Node) {
+	v.forEachField(root, []string{"filters"}, func(_ string, filters *yamlv3.Node) {
+		if filters.Kind != yamlv3.SequenceNode {
+			v.addProblem(filters, "filters must be a list")
+			return
+		}
+		for _, filter := range filters.Content {
+			v.validateFilterV2(filter)
+		}
+	})
+}
+
+func (v *validator) validateFilterV2(filter *yamlv3.Node) {
+	fields := map[string]*yamlv3.Node{}
+	v.forEachField(filter, filterConfigV2Fields, func(name string, value *yamlv3.Node) {
+		fields[name] = value
+	})
+	if filter.Kind != yamlv3.MappingNode {
+		return
+	}
+	if fields["filterTypes"] == nil {
+		v.addProblem(filter, "filterTypes are missing")
+	}
+	for _, node := range v.getStrings(fields["filterTypes"], "filterTypes") {
+		if !containsEntityType(filterEntityTypes, types.FilterEntityType(node.Value)) {
+			v.addProblem(node, "unknown filter type: %s, valid types: %s", node.Value, filterEntityTypes)
+		}
+	}
+	if fields["cloudTypes"] == nil {
+		v.addProblem(filter, "cloudTypes are missing")
+	}
+	for _, node := range v.getStrings(fields["cloudTypes"], "cloudTypes") {
+		if !IsAnyEquals(node.Value, filterConfigV2Clouds...) {
+			if IsAnyEquals(strings.ToLower(node.Value), filterConfigV2Clouds...) {
+				v.addProblem(node, "cloud type must be lowercase: %s", node.Value)
+			} else {
+				v.addProblem(node, "unknown cloud type: %s, valid types: %s", node.Value, filterConfigV2Clouds)
+			}
+		}
+	}
+	for _, node := range v.getStrings(fields["filterProperties"], "filterProperties") {
+		if !IsAnyEquals(node.Value, filterConfigProperties...) {
+			v.addProblem(node, "unknown filter property: %s, valid properties: %s", node.Value, filterConfigProperties)
+		}
+	}
+	values := v.getStrings(fields["filterValues"], "filterValues")
+	switch {
+	case fields["filterProperties"] != nil && len(values) == 0:
+		v.addProblem(fields["filterProperties"], "filterValues are missing, the properties do not match anything")
+	case fields["filterValues"] != nil && fields["filterProperties"] == nil:
+		v.addProblem(fields["filterValues"], "filterProperties are missing, the values are not used")
+	case fields["filterProperties"] == nil && fields["conditions"] == nil:
+		v.addProblem(filter, "neither filterProperties nor conditions are given, the filter does not match anything")
+	}
+	if conditions := fields["conditions"]; conditions != nil {
+		if conditions.Kind != yamlv3.SequenceNode {
+			v.addProblem(conditions, "conditions must be a list")
+			return
+		}
+		for _, conditionNode := range conditions.Content {
+			v.validateCondition(conditionNode)
+		}
+	}
+}
+
+func (v *validator) validateCondition(node *yamlv3.Node) {
+	valid := true
+	v.forEachField(node, filterConditionFields, func(name string, value *yamlv3.Node) {
+		if name == "values" {
+			if value.Kind != yamlv3.SequenceNode {
+				v.addProblem(value, "values must be a list")
+				valid = false
+			} else if len(v.getStrings(value, name)) != len(value.Content) {
+				valid = false
+			}
+		} else if value.Kind != yamlv3.ScalarNode {
+			v.addProblem(value, "%s must be a string", name)
+			valid = false
+		}
+	})
+	if !valid || node.Kind != yamlv3.MappingNode {
+		return
+	}
+	var condition types.FilterCondition
+	if err := node.Decode(&condition); err != nil {
+		v.addProblem(node, "invalid condition: %s", err)
+		return
+	}
+	if err := condition.Validate(); err != nil {
+		v.addProblem(node, "%s", err)
+	}
+}
+
+// The callback is called with the known fields, the unknown and duplicated ones are reported
+func (v *validator) forEachField(node *yamlv3.Node, known []string, callback func(string, *yamlv3.Node)) {
+	if node.Kind != yamlv3.MappingNode {
+		v.addProblem(node, "mapping is expected with the keys: %s", known)
+		return
+	}
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case !IsAnyEquals(key.Value, known...):
+			v.addProblem(key, "unknown key: %s, valid keys: %s", key.Value, known)
+		case seen[key.Value]:
+			v.addProblem(key, "duplicated key: %s", key.Value)
+		default:
+			seen[key.Value] = true
+			callback(key.Value, value)
+		}
+	}
+}
+
+// Only the non-empty string elements of the list are returned, the others are reported
+func (v *validator) getStrings(node *yamlv3.Node, name string) []*yamlv3.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind != yamlv3.SequenceNode {
+		v.addProblem(node, "%s must be a list", name)
+		return nil
+	}
+	var values []*yamlv3.Node
+	for _, element := range node.Content {
+		if element.Kind != yamlv3.ScalarNode || len(element.Value) == 0 {
+			v.addProblem(element, "elements of %s must be non-empty strings", name)
+			continue
+		}
+		values = append(values, element)
+	}
+	return values
+}
+
+func getMappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func getSortedKeys(m map[string][]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsEntityType(entityTypes []types.FilterEntityType, entityType types.FilterEntityType) bool {
+	for _, t := range entityTypes {
+		if t == entityType {
+			return true
+		}
+	}
+	return false
+}